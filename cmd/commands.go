@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mine-parser/internal/app"
+	"mine-parser/internal/config"
+	"mine-parser/internal/export"
+	"os"
+	"time"
+)
+
+// command — подкоманда CLI
+type command struct {
+	name    string
+	usage   string // аргументы для справки
+	summary string
+	run     func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "", "отслеживать лог и запустить бота", runMode(app.ModeServe)},
+		{"parse", "", "только отслеживать лог", runMode(app.ModeParse)},
+		{"bot", "", "только бот (данные пишет другой процесс)", runMode(app.ModeBot)},
+		{"import", "<файл>", "разово загрузить файл лога (.log или архив .log.gz)", runImport},
+		{"stats", "[-json] <игрок>", "статистика игрока", runStats},
+		{"export", "[-format csv|jsonl|parquet] [-from время] [-to время] [-player игрок] [-server имя] [-o файл] [sessions|commands|advancements]",
+			"выгрузить статистику игроков в JSON Lines или таблицу сессий, команд или достижений", runExport},
+		{"migrate", "up | down [N] | status | redo", "управление схемой БД", runMigrate},
+		{"config", "", "вывести действующую конфигурацию без секретов", runConfig},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// parseFlags разбирает флаги подкоманды и проверяет число позиционных аргументов
+func parseFlags(fs *flag.FlagSet, args []string, positional int) error {
+	return parseFlagsRange(fs, args, positional, positional)
+}
+
+// parseFlagsRange — parseFlags для команд с необязательными позиционными аргументами
+func parseFlagsRange(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError("%v", err)
+	}
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		if minArgs == maxArgs {
+			return usageError("ожидается аргументов: %d, передано: %d", minArgs, fs.NArg())
+		}
+		return usageError("ожидается аргументов: от %d до %d, передано: %d", minArgs, maxArgs, fs.NArg())
+	}
+	return nil
+}
+
+// runMode запускает долгоживущие компоненты до сигнала завершения
+func runMode(mode app.Mode) func(ctx context.Context, cfg *config.Config, args []string) error {
+	return func(ctx context.Context, cfg *config.Config, args []string) error {
+		if err := parseFlags(flag.NewFlagSet("", flag.ContinueOnError), args, 0); err != nil {
+			return err
+		}
+
+		log.Println("Запуск парсера логов Minecraft...")
+
+		// Сборка приложения: общий пул БД, репозитории и сервисы
+		application, err := app.New(config.NewStore(cfg), mode)
+		if err != nil {
+			return fmt.Errorf("не удалось запустить приложение: %w", err)
+		}
+		return application.Run(ctx)
+	}
+}
+
+func runImport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	if _, err := os.Stat(path); err != nil {
+		return notFoundError("файл лога недоступен: %v", err)
+	}
+
+	application, err := app.New(config.NewStore(cfg), app.ModeImport)
+	if err != nil {
+		return err
+	}
+	defer application.Close()
+	return application.Import(ctx, path)
+}
+
+func runStats(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "вывести в JSON")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+	username := fs.Arg(0)
+
+	application, err := app.New(config.NewStore(cfg), app.ModeQuery)
+	if err != nil {
+		return err
+	}
+	defer application.Close()
+
+	summary, err := application.PlayerSummary(ctx, username)
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return notFoundError("игрок %s не найден", username)
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, summary)
+	}
+	status := "не в сети"
+	if summary.Online {
+		status = "онлайн"
+	}
+	local := func(t time.Time) string { return t.In(cfg.App.Location).Format("02.01.2006 15:04") }
+	fmt.Printf("Игрок:          %s (%s)\n", summary.Username, summary.ID)
+	fmt.Printf("Статус:         %s\n", status)
+	fmt.Printf("Первый вход:    %s\n", local(summary.FirstSeen))
+	fmt.Printf("Последний вход: %s\n", local(summary.LastSeen))
+	fmt.Printf("Время в игре:   %.1fч\n", summary.PlayTimeHours)
+	fmt.Printf("Сессий:         %d\n", summary.Sessions)
+	fmt.Printf("Команд:         %d\n", summary.Commands)
+	fmt.Printf("Достижений:     %d\n", summary.Advancements)
+	return nil
+}
+
+// runExport без таблицы выгружает статистику игроков, как раньше; с таблицей — её записи
+func runExport(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "", "файл для выгрузки (по умолчанию stdout)")
+	formatName := fs.String("format", string(export.FormatJSONL), "формат таблицы: csv, jsonl или parquet")
+	from := fs.String("from", "", "начало интервала: RFC 3339 или ГГГГ-ММ-ДД")
+	to := fs.String("to", "", "конец интервала (не включая): RFC 3339 или ГГГГ-ММ-ДД")
+	player := fs.String("player", "", "UUID или ник игрока")
+	server := fs.String("server", "", "имя сервера")
+	if err := parseFlagsRange(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	var table export.Table
+	var format export.Format
+	var filter export.Filter
+	if fs.NArg() == 1 {
+		var err error
+		if table, err = export.ParseTable(fs.Arg(0)); err != nil {
+			return usageError("%v", err)
+		}
+		if format, err = export.ParseFormat(*formatName); err != nil {
+			return usageError("%v", err)
+		}
+		if filter.Period.From, err = parseExportTime(*from, cfg.App.Location); err != nil {
+			return usageError("-from: %v", err)
+		}
+		if filter.Period.To, err = parseExportTime(*to, cfg.App.Location); err != nil {
+			return usageError("-to: %v", err)
+		}
+		if !filter.Period.From.IsZero() && !filter.Period.To.IsZero() && !filter.Period.From.Before(filter.Period.To) {
+			return usageError("-from должен быть раньше -to")
+		}
+		filter.Server = *server
+	} else if *from != "" || *to != "" || *player != "" || *server != "" || *formatName != string(export.FormatJSONL) {
+		return usageError("фильтры и формат задаются только вместе с таблицей")
+	}
+
+	application, err := app.New(config.NewStore(cfg), app.ModeQuery)
+	if err != nil {
+		return err
+	}
+	defer application.Close()
+
+	if *player != "" {
+		found, err := application.FindPlayer(ctx, *player)
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			return notFoundError("игрок %s не найден", *player)
+		}
+		filter.PlayerID = found.ID
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	// Построчная запись в файл или канал слишком медленна без буфера
+	buffered := bufio.NewWriter(w)
+
+	if table == "" {
+		count, err := application.ExportPlayers(ctx, buffered)
+		if err != nil {
+			return err
+		}
+		log.Printf("Выгружено игроков: %d", count)
+		return buffered.Flush()
+	}
+
+	count, err := application.ExportTable(ctx, buffered, table, format, filter)
+	if err != nil {
+		return fmt.Errorf("выгрузка %s прервана после %d записей: %w", table, count, err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Выгружено записей %s: %d", table, count)
+	return nil
+}
+
+// parseExportTime читает время в RFC 3339 или дату, которая отсчитывается от полуночи в loc
+func parseExportTime(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ожидается время в формате RFC 3339 или дата ГГГГ-ММ-ДД: %q", value)
+	}
+	return t.UTC(), nil
+}
+
+func runConfig(ctx context.Context, cfg *config.Config, args []string) error {
+	if err := parseFlags(flag.NewFlagSet("config", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	return printConfig(cfg)
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printConfig выводит итоговую конфигурацию (файл, переменные окружения и значения по умолчанию)
+func printConfig(cfg *config.Config) error {
+	dump, err := cfg.Dump()
+	if err != nil {
+		return err
+	}
+	if cfg.File() != "" {
+		fmt.Printf("# Файл конфигурации: %s\n", cfg.File())
+	}
+	_, err = os.Stdout.Write(dump)
+	return err
+}
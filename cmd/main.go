@@ -1,29 +1,114 @@
 package main
 
 import (
-	"log"
-	"mine-parser/internal/app"
-	"sync"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/logging"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Коды завершения
+const (
+	exitOK       = 0
+	exitFailure  = 1 // ошибка во время работы
+	exitUsage    = 2 // неверные аргументы командной строки
+	exitConfig   = 3 // некорректная конфигурация
+	exitNotFound = 4 // запрошенные данные не найдены
 )
 
 func main() {
-	log.Println("Запуск парсера логов Minecraft...")
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	// Глобальные флаги указываются до имени команды
+	global := flag.NewFlagSet("mclog-parser", flag.ContinueOnError)
+	configFile := global.String("config", "", "путь к файлу конфигурации (по умолчанию CONFIG_FILE или config.yaml)")
+	logLevel := global.String("log-level", "", "уровень логирования: debug, info, warn, error (по умолчанию LOG_LEVEL или info)")
+	global.Usage = func() { printUsage(global) }
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
 
-	var wg sync.WaitGroup
+	name := "serve"
+	if global.NArg() > 0 {
+		name = global.Arg(0)
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q\n\n", name)
+		printUsage(global)
+		return exitUsage
+	}
+	var cmdArgs []string
+	if global.NArg() > 1 {
+		cmdArgs = global.Args()[1:]
+	}
 
-	// Запускаем парсер логов
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		app.Parser()
-	}()
+	// Загрузка конфигурации
+	cfg, err := config.Load(config.Options{File: *configFile, LogLevel: *logLevel})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка конфигурации: %v\n", err)
+		return exitConfig
+	}
+	logging.SetLevel(cfg.App.Level)
+
+	// Graceful shutdown по SIGINT/SIGTERM (SIGHUP перечитывает конфигурацию)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, cfg, cmdArgs); err != nil {
+		var cmdErr *commandError
+		if errors.As(err, &cmdErr) {
+			if cmdErr.code == exitUsage {
+				fmt.Fprintf(os.Stderr, "%v\nиспользование: mclog-parser %s %s\n", cmdErr.err, cmd.name, cmd.usage)
+			} else {
+				fmt.Fprintln(os.Stderr, cmdErr.err)
+			}
+			return cmdErr.code
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Printf("использование: mclog-parser %s %s\n%s\n", cmd.name, cmd.usage, cmd.summary)
+			return exitOK
+		}
+		logging.Errorf("Команда %s завершилась с ошибкой: %v", cmd.name, err)
+		return exitFailure
+	}
+	return exitOK
+}
 
-	// Запускаем Telegram бота
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		app.TelegramBot()
-	}()
+func printUsage(global *flag.FlagSet) {
+	out := global.Output()
+	fmt.Fprintln(out, "использование: mclog-parser [глобальные флаги] <команда> [аргументы]")
+	fmt.Fprintln(out, "\nКоманды:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nБез команды выполняется serve. Глобальные флаги:")
+	global.PrintDefaults()
+}
+
+// commandError — ошибка с кодом завершения
+type commandError struct {
+	code int
+	err  error
+}
+
+func (e *commandError) Error() string { return e.err.Error() }
+func (e *commandError) Unwrap() error { return e.err }
+
+func usageError(format string, args ...any) error {
+	return &commandError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
 
-	wg.Wait()
+func notFoundError(format string, args ...any) error {
+	return &commandError{code: exitNotFound, err: fmt.Errorf(format, args...)}
 }
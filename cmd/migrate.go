@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/migrations"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return usageError("не указано действие")
+	}
+
+	db, err := migrations.Open(cfg.Db.Dsn)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrations.NewMigrator(sqlDB, migrations.DialectOf(cfg.Db.Dsn))
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Применено миграций: %d\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return usageError("число шагов должно быть положительным: %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Откачено миграций: %d\n", reverted)
+
+	case "redo":
+		return migrator.Redo(ctx)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ВЕРСИЯ\tИМЯ\tСОСТОЯНИЕ\tПРИМЕНЕНА")
+		for _, status := range statuses {
+			state, appliedAt := "ожидает", "-"
+			if status.Applied {
+				state = "применена"
+				appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			if status.Missing {
+				state = "нет в сборке"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return usageError("неизвестное действие %q", args[0])
+	}
+	return nil
+}
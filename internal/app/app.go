@@ -0,0 +1,371 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/handlers"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/migrations"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/service"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+)
+
+// Mode определяет, какие компоненты запускает приложение
+type Mode int
+
+const (
+	ModeServe  Mode = iota // отслеживание лога и бот
+	ModeParse              // только отслеживание лога
+	ModeBot                // только бот; данные пишет другой процесс
+	ModeImport             // разовая загрузка файла лога
+	ModeQuery              // только чтение данных (stats, export)
+)
+
+func (m Mode) runsParser() bool { return m == ModeServe || m == ModeParse }
+func (m Mode) runsBot() bool    { return m == ModeServe || m == ModeBot }
+
+// presenceRefreshInterval — как часто бот без парсера перечитывает открытые сессии из БД
+const presenceRefreshInterval = 15 * time.Second
+
+// App — корень композиции: конфигурация, пул соединений с БД, репозитории
+// и сервисы создаются один раз и разделяются парсером и ботом
+type App struct {
+	cfg  *config.Store // действующая конфигурация, обновляется по SIGHUP
+	mode Mode
+	db   *gorm.DB
+
+	// Репозитории
+	playerRepo       repo.PlayerRepository
+	sessionRepo      repo.SessionRepository
+	commandRepo      repo.CommandRepository
+	advancementRepo  repo.AdvancementRepository
+	notificationRepo repo.NotificationRepository
+	outboxRepo       repo.OutboxRepository
+	discordRepo      repo.DiscordRepository
+	serverRunRepo    repo.ServerRunRepository
+	webhookRepo      repo.WebhookRepository
+	exportRepo       repo.ExportRepository
+	linkRepo         repo.TelegramLinkRepository
+
+	// Сервисы
+	diagnostics     service.ParseDiagnostics
+	presence        service.PresenceTracker
+	playerSvc       service.PlayerService
+	commandSvc      service.CommandService
+	advancementSvc  service.AdvancementService
+	statsSvc        service.StatsService
+	serverStatusSvc service.ServerStatusService
+	webhookSvc      service.WebhookService
+	notificationSvc service.NotificationService
+	discordSvc      service.DiscordNotificationService
+	exportSvc       service.ExportService
+	leaderboardSvc  service.LeaderboardService
+	pipeline        service.WritePipeline      // nil, если приложение не пишет события
+	outbox          service.NotificationOutbox // nil, если уведомления не ставятся в очередь
+	notifications   *NotificationSender        // nil, если процесс не доставляет уведомления
+	webhooks        *WebhookSender             // nil, если приложение не публикует события
+	bus             *events.Bus
+	feed            *events.Feed // nil, пока не запущен HTTP-сервер
+
+	// Состояние фоновых циклов для проверок здоровья
+	tail *tailState
+	poll *pollState
+
+	// Telegram (nil, если бот не настроен)
+	bot              *tgbotapi.BotAPI
+	telegramHandlers *handlers.TelegramHandlers
+}
+
+// New собирает приложение по конфигурации; mode определяет, какие компоненты создаются
+func New(store *config.Store, mode Mode) (*App, error) {
+	cfg := store.Get()
+
+	// 1. Инициализация БД
+	dbConn, err := migrations.InitDB(cfg.Db.Dsn, cfg.Db.AutoMigrate)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{cfg: store, mode: mode, db: dbConn, tail: &tailState{}, poll: &pollState{}}
+	if err := repo.Instrument(dbConn, func() repo.QueryOptions {
+		current := store.Get()
+		return repo.QueryOptions{Timeout: current.Db.QueryTimeout, SlowThreshold: current.Db.SlowThreshold}
+	}); err != nil {
+		return nil, err
+	}
+
+	// 2. Репозитории
+	a.playerRepo = repo.NewPlayerRepository(dbConn)
+	a.sessionRepo = repo.NewSessionRepository(dbConn)
+	a.commandRepo = repo.NewCommandRepository(dbConn)
+	a.advancementRepo = repo.NewAdvancementRepository(dbConn)
+	a.notificationRepo = repo.NewNotificationRepository(dbConn)
+	a.outboxRepo = repo.NewOutboxRepository(dbConn)
+	a.discordRepo = repo.NewDiscordRepository(dbConn)
+	a.serverRunRepo = repo.NewServerRunRepository(dbConn)
+	a.webhookRepo = repo.NewWebhookRepository(dbConn)
+	a.exportRepo = repo.NewExportRepository(dbConn)
+	a.linkRepo = repo.NewTelegramLinkRepository(dbConn)
+
+	// 3. Сервисы
+	a.diagnostics = service.NewParseDiagnostics(5, 1000)
+	a.presence = service.NewPresenceTracker()
+	a.playerSvc = service.NewPlayerService(a.playerRepo, a.sessionRepo, a.commandRepo, a.advancementRepo, a.presence)
+	a.commandSvc = service.NewCommandService(a.commandRepo)
+	a.advancementSvc = service.NewAdvancementService(a.advancementRepo)
+	a.statsSvc = service.NewStatsService(a.playerRepo, a.sessionRepo, a.commandRepo, a.advancementRepo)
+	a.serverStatusSvc = service.NewServerStatusService(a.presence, a.serverRunRepo)
+	a.webhookSvc = service.NewWebhookService(a.webhookRepo, a.cfg)
+	a.notificationSvc = service.NewNotificationService(a.notificationRepo, a.outboxRepo)
+	a.discordSvc = service.NewDiscordNotificationService(a.discordRepo, a.cfg)
+	a.exportSvc = service.NewExportService(a.exportRepo)
+	a.leaderboardSvc = service.NewLeaderboardService(a.statsSvc, a.linkRepo)
+	a.bus = events.NewBus()
+
+	// Восстанавливаем, кто онлайн, по открытым сессиям
+	if err := a.presence.Rebuild(context.Background(), a.sessionRepo, a.playerRepo); err != nil {
+		logging.Errorf("Не удалось восстановить список игроков онлайн: %v", err)
+	}
+
+	// 4. Telegram бот (необязателен при отслеживании лога, обязателен в режиме бота)
+	if mode.runsBot() {
+		a.initTelegram()
+		if mode == ModeBot && a.bot == nil {
+			a.Close()
+			return nil, errors.New("бот не запущен: проверьте TG_TOKEN")
+		}
+	}
+
+	// 5. Конвейер записи: уведомления ставятся в очередь вместе с событиями,
+	// после коммита обновляется присутствие и события публикуются в шину.
+	// Уведомления в Telegram доставляет процесс с ботом, поэтому очередь нужна
+	// и без бота в этом процессе; уведомления в Discord и вебхуки доставляет этот процесс.
+	// При импорте старого лога уведомления и вебхуки не отправляются.
+	switch {
+	case mode.runsParser():
+		a.outbox = service.NewNotificationOutbox(a.presence, a.cfg)
+		a.pipeline = a.newPipeline(service.LivePipelineOptions(cfg))
+		a.initWebhooks()
+	case mode == ModeImport:
+		a.pipeline = a.newPipeline(service.BackfillPipelineOptions(cfg))
+	}
+	a.initNotifications()
+
+	return a, nil
+}
+
+func (a *App) newPipeline(opts service.PipelineOptions) service.WritePipeline {
+	return service.NewWritePipeline(
+		repo.NewTransactor(a.db),
+		a.diagnostics,
+		opts,
+		a.outbox,
+		a.onEventsCommitted,
+	)
+}
+
+// Run запускает компоненты и блокируется до отмены ctx или ошибки парсера.
+// Затем компоненты останавливаются по очереди: HTTP-сервер, парсер, конвейер записи,
+// бот, отправка уведомлений и вебхуков и только после этого закрывается пул соединений с БД.
+func (a *App) Run(ctx context.Context) error {
+	httpServer, err := a.startHTTP()
+	if err != nil {
+		a.Close()
+		return fmt.Errorf("не удалось запустить HTTP-сервер: %w", err)
+	}
+
+	parserCtx, stopParser := context.WithCancel(context.Background())
+	defer stopParser()
+	botCtx, stopBot := context.WithCancel(context.Background())
+	defer stopBot()
+
+	// Перезагрузка конфигурации по SIGHUP до начала остановки
+	go a.watchReload(ctx)
+
+	parserErr := make(chan error, 1)
+	parserDone := make(chan struct{})
+	if a.mode.runsParser() {
+		go func() {
+			defer close(parserDone)
+			parserErr <- a.runParser(parserCtx)
+		}()
+	} else {
+		close(parserDone)
+		// Без парсера присутствие обновляет другой процесс: перечитываем его из БД
+		go a.refreshPresence(botCtx)
+	}
+
+	botDone := make(chan struct{})
+	if a.notifications != nil {
+		a.notifications.Start()
+	}
+	if a.webhooks != nil {
+		a.webhooks.Start()
+	}
+	if a.bot != nil {
+		go func() {
+			defer close(botDone)
+			a.runBot(botCtx)
+		}()
+	} else {
+		close(botDone)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Println("Получен сигнал завершения, останавливаем приложение...")
+	case err := <-parserErr:
+		if err != nil {
+			runErr = fmt.Errorf("ошибка при чтении лога: %w", err)
+		}
+	}
+
+	// 0. HTTP-сервер: проверки состояния больше не принимаются
+	if httpServer != nil {
+		httpServer.Shutdown()
+	}
+
+	// 1. Парсер: больше не читаем лог
+	stopParser()
+	<-parserDone
+
+	// 2. Конвейер: записываем оставшиеся в буфере события
+	a.closePipeline()
+
+	// 3. Бот: прекращаем получать обновления и дожидаемся обработчиков
+	stopBot()
+	<-botDone
+
+	// 4. Уведомления, вебхуки и остальные подписчики шины (неотправленное остаётся в очереди в БД)
+	if a.notifications != nil {
+		a.notifications.Stop()
+	}
+	if a.webhooks != nil {
+		a.webhooks.Stop()
+	}
+	a.bus.Close()
+
+	// 5. Пул соединений с БД
+	a.closeDB()
+
+	log.Println("Приложение завершено.")
+	return runErr
+}
+
+// Close освобождает ресурсы приложения, которое не запускалось через Run
+func (a *App) Close() {
+	a.closePipeline()
+	a.bus.Close()
+	a.closeDB()
+}
+
+func (a *App) closePipeline() {
+	if a.pipeline == nil {
+		return
+	}
+	if err := a.pipeline.Close(); err != nil {
+		logging.Errorf("Ошибка при записи оставшихся событий: %v", err)
+	}
+}
+
+func (a *App) closeDB() {
+	if sqlDB, err := a.db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logging.Errorf("Ошибка при закрытии соединения с БД: %v", err)
+		}
+	}
+}
+
+// refreshPresence периодически восстанавливает список игроков онлайн по открытым сессиям
+func (a *App) refreshPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.presence.Rebuild(ctx, a.sessionRepo, a.playerRepo); err != nil && ctx.Err() == nil {
+				logging.Errorf("Не удалось обновить список игроков онлайн: %v", err)
+			}
+		}
+	}
+}
+
+// onEventsCommitted обновляет присутствие и публикует в шину события,
+// сохранённые конвейером записи
+func (a *App) onEventsCommitted(logEvents []service.LogEvent) {
+	a.presence.Apply(logEvents)
+	for _, event := range logEvents {
+		if domainEvent := toDomainEvent(event); domainEvent != nil {
+			a.bus.Publish(domainEvent)
+		}
+	}
+}
+
+// toDomainEvent преобразует событие лога в доменное событие шины
+func toDomainEvent(event service.LogEvent) events.Event {
+	switch event.Rule {
+	case service.RuleJoin:
+		return events.PlayerLogin{
+			Server:    event.Server,
+			PlayerID:  event.PlayerID,
+			Username:  event.Username,
+			SessionID: event.SessionID,
+			Timestamp: event.Timestamp,
+		}
+	case service.RuleLeave:
+		return events.PlayerLogout{
+			Server:    event.Server,
+			PlayerID:  event.PlayerID,
+			Username:  event.Username,
+			Timestamp: event.Timestamp,
+		}
+	case service.RuleCommand:
+		return events.CommandIssued{
+			Server:    event.Server,
+			PlayerID:  event.PlayerID,
+			Username:  event.Username,
+			Command:   event.Command,
+			Timestamp: event.Timestamp,
+		}
+	case service.RuleAdvancement:
+		return events.AdvancementEarned{
+			Server:      event.Server,
+			PlayerID:    event.PlayerID,
+			Username:    event.Username,
+			Advancement: event.Advancement,
+			Timestamp:   event.Timestamp,
+		}
+	case service.RuleChat:
+		return events.ChatMessage{
+			Server:    event.Server,
+			PlayerID:  event.PlayerID,
+			Username:  event.Username,
+			Message:   event.Message,
+			Timestamp: event.Timestamp,
+		}
+	case service.RuleDeath:
+		return events.PlayerDied{
+			Server:    event.Server,
+			PlayerID:  event.PlayerID,
+			Username:  event.Username,
+			Message:   event.Message,
+			Timestamp: event.Timestamp,
+		}
+	case service.RuleServerStart:
+		return events.ServerStarted{Server: event.Server, Timestamp: event.Timestamp}
+	case service.RuleServerStop:
+		return events.ServerStopping{Server: event.Server, Timestamp: event.Timestamp}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mine-parser/internal/export"
+	"mine-parser/internal/models"
+	"mine-parser/internal/service"
+	"time"
+)
+
+// PlayerSummary — сводная статистика игрока для команд stats и export
+type PlayerSummary struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	Online        bool      `json:"online"`
+	PlayTimeHours float64   `json:"play_time_hours"`
+	Sessions      int       `json:"sessions"`
+	Commands      int64     `json:"commands"`
+	Advancements  int       `json:"advancements"`
+}
+
+// Import разово загружает файл лога. Повторная загрузка того же файла
+// не создаёт дублей: конвейер пропускает уже записанные события.
+func (a *App) Import(ctx context.Context, path string) error {
+	parser := service.NewLogParserService(ctx, a.cfg.Get(), a.playerSvc, a.pipeline, a.diagnostics)
+	return parser.ProcessLogFile(ctx, path)
+}
+
+// PlayerSummary возвращает статистику игрока по нику; nil, если игрок не найден
+func (a *App) PlayerSummary(ctx context.Context, username string) (*PlayerSummary, error) {
+	player, err := a.playerSvc.GetPlayerByUsername(ctx, username)
+	if err != nil || player == nil {
+		return nil, err
+	}
+	return a.summarize(ctx, *player)
+}
+
+// ExportPlayers пишет статистику всех игроков в w, по одному JSON-объекту на строку
+func (a *App) ExportPlayers(ctx context.Context, w io.Writer) (int, error) {
+	players, err := a.playerSvc.ListAllPlayers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(w)
+	for i, player := range players {
+		summary, err := a.summarize(ctx, player)
+		if err != nil {
+			return i, fmt.Errorf("статистика игрока %s: %w", player.Username, err)
+		}
+		if err := encoder.Encode(summary); err != nil {
+			return i, err
+		}
+	}
+	return len(players), nil
+}
+
+// FindPlayer ищет игрока по UUID или нику; nil, если игрок не найден
+func (a *App) FindPlayer(ctx context.Context, ref string) (*models.Player, error) {
+	return a.playerSvc.FindPlayer(ctx, ref)
+}
+
+// ExportTable выгружает сессии, команды или достижения в w и возвращает число записей
+func (a *App) ExportTable(ctx context.Context, w io.Writer, table export.Table, format export.Format, filter export.Filter) (int64, error) {
+	return a.exportSvc.Export(ctx, w, table, format, filter)
+}
+
+func (a *App) summarize(ctx context.Context, player models.Player) (*PlayerSummary, error) {
+	stats, err := a.playerSvc.GetPlayerStats(ctx, player.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &PlayerSummary{
+		ID:            player.ID,
+		Username:      player.Username,
+		FirstSeen:     player.FirstSeen,
+		LastSeen:      player.LastSeen,
+		Online:        a.playerSvc.IsPlayerOnline(player.ID),
+		PlayTimeHours: stats.TotalPlayTime.Hours(),
+		Sessions:      stats.SessionCount,
+		Commands:      stats.CommandsUsed,
+		Advancements:  len(stats.Advancements),
+	}, nil
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"mine-parser/internal/health"
+	"mine-parser/internal/models"
+	"time"
+)
+
+// newHealthRegistry регистрирует проверки компонентов, запущенных в текущем режиме.
+// Пороги читаются при каждой проверке, поэтому меняются по SIGHUP без перезапуска.
+func (a *App) newHealthRegistry() *health.Registry {
+	registry := health.NewRegistry(func() time.Duration { return a.cfg.Get().Health.CheckTimeout })
+
+	registry.Register(health.Check{Name: "database", Run: a.checkDatabase})
+	if a.mode.runsParser() {
+		registry.Register(health.Check{Name: "tailer", Liveness: true, Run: a.checkTailer})
+		registry.Register(health.Check{Name: "events", Run: a.checkEvents})
+	}
+	if a.bot != nil {
+		registry.Register(health.Check{Name: "telegram", Liveness: true, Run: a.checkTelegram})
+	}
+	if a.outbox != nil || a.notifications != nil {
+		registry.Register(health.Check{Name: "notification_queue", Run: a.checkNotificationQueue})
+	}
+	return registry
+}
+
+func (a *App) checkDatabase(ctx context.Context) health.Result {
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return health.Failed(nil, "%v", err)
+	}
+
+	started := time.Now()
+	err = sqlDB.PingContext(ctx)
+	latency := time.Since(started)
+	details := map[string]any{"latency_ms": latency.Milliseconds()}
+	if err != nil {
+		return health.Failed(details, "БД недоступна: %v", err)
+	}
+
+	if warn := a.cfg.Get().Health.DbLatencyWarn; warn > 0 && latency > warn {
+		return health.Degraded(details, "БД отвечает дольше %s", warn)
+	}
+	return health.OK(details)
+}
+
+func (a *App) checkTailer(context.Context) health.Result {
+	cfg := a.cfg.Get().Health
+	state := a.tail.snapshot()
+
+	behind := max(state.size-state.offset, 0)
+	details := map[string]any{
+		"path":         state.path,
+		"offset":       state.offset,
+		"size":         state.size,
+		"bytes_behind": behind,
+	}
+	if !state.lastProgress.IsZero() {
+		details["last_progress"] = state.lastProgress.UTC()
+	}
+	if state.lastError != "" {
+		details["last_error"] = state.lastError
+	}
+
+	switch {
+	case !state.running:
+		return health.Failed(details, "отслеживание лога не запущено")
+	case cfg.TailLagFail > 0 && behind >= cfg.TailLagFail:
+		return health.Failed(details, "не прочитано %d байт лога", behind)
+	case cfg.TailStallFail > 0 && behind > 0 && time.Since(state.lastProgress) > cfg.TailStallFail:
+		return health.Failed(details, "чтение лога не продвигается дольше %s", cfg.TailStallFail)
+	case cfg.TailLagWarn > 0 && behind >= cfg.TailLagWarn:
+		return health.Degraded(details, "не прочитано %d байт лога", behind)
+	case state.lastError != "":
+		return health.Degraded(details, "%s", state.lastError)
+	}
+	return health.OK(details)
+}
+
+func (a *App) checkEvents(context.Context) health.Result {
+	warn := a.cfg.Get().Health.EventStaleWarn
+	lastMatched := a.diagnostics.Summary().LastMatchedAt
+	if lastMatched.IsZero() {
+		// После запуска событий ещё могло не быть: тихий сервер — не ошибка
+		return health.OK(map[string]any{"last_event": nil})
+	}
+
+	details := map[string]any{"last_event": lastMatched.UTC()}
+	if warn > 0 && time.Since(lastMatched) > warn {
+		return health.Degraded(details, "нет распознанных событий дольше %s", warn)
+	}
+	return health.OK(details)
+}
+
+func (a *App) checkTelegram(context.Context) health.Result {
+	cfg := a.cfg.Get().Health
+	state := a.poll.snapshot()
+
+	details := map[string]any{"failures": state.failures}
+	if !state.lastSuccess.IsZero() {
+		details["last_success"] = state.lastSuccess.UTC()
+	}
+	if state.lastError != "" {
+		details["last_error"] = state.lastError
+	}
+	if !state.running {
+		return health.Failed(details, "опрос Telegram не запущен")
+	}
+
+	// До первого успешного опроса отсчитываем время с запуска
+	since := state.lastSuccess
+	if since.IsZero() {
+		since = state.startedAt
+	}
+	stale := time.Since(since)
+	switch {
+	case cfg.PollStaleFail > 0 && stale > cfg.PollStaleFail:
+		return health.Failed(details, "нет успешного опроса Telegram дольше %s", cfg.PollStaleFail)
+	case cfg.PollStaleWarn > 0 && stale > cfg.PollStaleWarn:
+		return health.Degraded(details, "нет успешного опроса Telegram дольше %s", cfg.PollStaleWarn)
+	}
+	return health.OK(details)
+}
+
+func (a *App) checkNotificationQueue(ctx context.Context) health.Result {
+	cfg := a.cfg.Get().Health
+	counts, err := a.outboxRepo.CountByStatus(ctx)
+	if err != nil {
+		return health.Failed(nil, "не удалось получить размер очереди: %v", err)
+	}
+
+	pending := counts[models.OutboxPending]
+	details := map[string]any{"pending": pending}
+	switch {
+	case cfg.QueueFail > 0 && pending >= cfg.QueueFail:
+		return health.Failed(details, "в очереди %d уведомлений", pending)
+	case cfg.QueueWarn > 0 && pending >= cfg.QueueWarn:
+		return health.Degraded(details, "в очереди %d уведомлений", pending)
+	}
+	return health.OK(details)
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"mine-parser/internal/badge"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/metrics"
+	"mine-parser/internal/web"
+)
+
+// startHTTP запускает HTTP-сервер с /healthz, /readyz, /metrics, REST API, потоком событий,
+// значками состояния и веб-интерфейсом; nil, если порт не задан
+func (a *App) startHTTP() (*web.Server, error) {
+	port := a.cfg.Get().App.Port
+	if port == "" {
+		return nil, nil
+	}
+
+	registry := a.newHealthRegistry()
+	codes := func() web.StatusCodes {
+		cfg := a.cfg.Get().Health
+		return web.StatusCodes{Degraded: cfg.DegradedStatusCode, Failed: cfg.FailedStatusCode}
+	}
+
+	server := web.NewServer(":" + port)
+	server.Handle("GET /healthz", web.HealthHandler(registry, true, codes))
+	server.Handle("GET /readyz", web.HealthHandler(registry, false, codes))
+
+	metricsHandler, err := metrics.Handler(newAppCollector(a))
+	if err != nil {
+		return nil, err
+	}
+	server.Handle("GET /metrics", metricsHandler)
+
+	feed, err := events.NewFeed(a.bus, a.cfg.Get().API.StreamReplaySize)
+	if err != nil {
+		return nil, err
+	}
+	a.feed = feed
+
+	api := web.NewAPI(a.playerSvc, a.commandSvc, a.advancementSvc, a.statsSvc, a.exportSvc, feed, func() web.APIOptions {
+		cfg := a.cfg.Get()
+		options := web.APIOptions{
+			Tokens:               cfg.API.Tokens,
+			Public:               cfg.API.Public,
+			DefaultPageSize:      cfg.API.DefaultPageSize,
+			MaxPageSize:          cfg.API.MaxPageSize,
+			StreamMaxConnections: cfg.API.StreamMaxConnections,
+			Location:             cfg.App.Location,
+		}
+		options.Servers = serverInfos(cfg.Servers)
+		return options
+	})
+	api.Register(server)
+	web.NewBadges(a.serverStatusSvc, func() web.BadgeOptions {
+		cfg := a.cfg.Get()
+		return web.BadgeOptions{
+			Enabled: cfg.Badge.Enabled,
+			Style: badge.Style{
+				Label:          cfg.Badge.Label,
+				LabelColor:     cfg.Badge.LabelColor,
+				OnlineColor:    cfg.Badge.OnlineColor,
+				OfflineColor:   cfg.Badge.OfflineColor,
+				Height:         cfg.Badge.Height,
+				CardWidth:      cfg.Badge.CardWidth,
+				CardBackground: cfg.Badge.CardBackground,
+				CardText:       cfg.Badge.CardText,
+				MaxNames:       cfg.Badge.MaxPlayerNames,
+			},
+			ShowPlayers:   cfg.Badge.ShowPlayers,
+			Hidden:        cfg.Badge.HiddenPlayers,
+			MaxAge:        cfg.Badge.CacheMaxAge,
+			Servers:       serverInfos(cfg.Servers),
+			DefaultServer: cfg.App.ServerName,
+		}
+	}).Register(server)
+	server.Handle("/", web.UIHandler(func() bool { return a.cfg.Get().UI.Enabled }))
+	if cfg := a.cfg.Get().API; len(cfg.Tokens) == 0 && !cfg.Public {
+		logging.Warnf("Токены API не заданы (API_TOKENS), запросы к %s будут отклонены", web.APIPrefix)
+	}
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func serverInfos(servers []config.ServerConfig) []web.ServerInfo {
+	infos := make([]web.ServerInfo, 0, len(servers))
+	for _, s := range servers {
+		infos = append(infos, web.ServerInfo{Name: s.Name, Address: s.Address, Version: s.Version, MapURL: s.MapURL, MaxPlayers: s.MaxPlayers})
+	}
+	return infos
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"mine-parser/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// appCollector читает метрики из состояния компонентов в момент опроса:
+// диагностика парсера, присутствие игроков, отслеживание лога и шина событий
+// уже ведут свои счётчики, и дублировать их не нужно
+type appCollector struct {
+	app *App
+
+	playersOnline  *prometheus.Desc
+	linesRead      *prometheus.Desc
+	linesUnmatched *prometheus.Desc
+	eventsMatched  *prometheus.Desc
+	parseErrors    *prometheus.Desc
+	duplicates     *prometheus.Desc
+	tailerLag      *prometheus.Desc
+	busQueued      *prometheus.Desc
+	busOnDisk      *prometheus.Desc
+	busDelivered   *prometheus.Desc
+	busDropped     *prometheus.Desc
+	streams        *prometheus.Desc
+}
+
+func newAppCollector(a *App) *appCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", name), help, labels, nil)
+	}
+	return &appCollector{
+		app: a,
+
+		playersOnline:  desc("players_online", "Игроков на сервере сейчас.", "server"),
+		linesRead:      desc("log_lines_total", "Прочитано строк лога."),
+		linesUnmatched: desc("log_lines_unmatched_total", "Строк лога, не распознанных ни одним правилом."),
+		eventsMatched:  desc("events_recognized_total", "Распознано событий по правилам парсера.", "type"),
+		parseErrors:    desc("parse_errors_total", "Ошибок разбора по правилам парсера.", "rule"),
+		duplicates:     desc("events_duplicate_total", "Событий, пропущенных как уже обработанные.", "type"),
+		tailerLag:      desc("tailer_lag_bytes", "Байт лога, ещё не прочитанных парсером."),
+		busQueued:      desc("bus_queue_depth", "Событий в буфере подписчика шины.", "subscriber"),
+		busOnDisk:      desc("bus_spilled_events", "Событий в файле переполнения подписчика шины.", "subscriber"),
+		busDelivered:   desc("bus_delivered_events_total", "Событий, доставленных в буфер подписчика шины.", "subscriber"),
+		busDropped:     desc("bus_dropped_events_total", "Событий, потерянных подписчиком шины.", "subscriber"),
+		streams:        desc("event_stream_clients", "Клиентов, подключённых к потоку событий."),
+	}
+}
+
+func (c *appCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *appCollector) Collect(ch chan<- prometheus.Metric) {
+	a := c.app
+
+	// Серверы из конфигурации показываются и без игроков, чтобы на графике был ноль, а не разрыв
+	online := make(map[string]int)
+	cfg := a.cfg.Get()
+	online[cfg.App.ServerName] = 0
+	for _, server := range cfg.Servers {
+		online[server.Name] = 0
+	}
+	for _, presence := range a.presence.Online() {
+		online[presence.Server]++
+	}
+	for server, count := range online {
+		ch <- prometheus.MustNewConstMetric(c.playersOnline, prometheus.GaugeValue, float64(count), server)
+	}
+
+	if a.mode.runsParser() {
+		summary := a.diagnostics.Summary()
+		ch <- prometheus.MustNewConstMetric(c.linesRead, prometheus.CounterValue, float64(summary.TotalLines))
+		ch <- prometheus.MustNewConstMetric(c.linesUnmatched, prometheus.CounterValue, float64(summary.UnmatchedLines))
+		for _, rule := range a.diagnostics.RuleStats() {
+			ch <- prometheus.MustNewConstMetric(c.eventsMatched, prometheus.CounterValue, float64(rule.Matched), rule.Rule)
+			ch <- prometheus.MustNewConstMetric(c.parseErrors, prometheus.CounterValue, float64(rule.Errors), rule.Rule)
+			ch <- prometheus.MustNewConstMetric(c.duplicates, prometheus.CounterValue, float64(rule.Duplicates), rule.Rule)
+		}
+
+		tail := a.tail.snapshot()
+		ch <- prometheus.MustNewConstMetric(c.tailerLag, prometheus.GaugeValue, float64(max(tail.size-tail.offset, 0)))
+	}
+
+	for _, sub := range a.bus.Metrics() {
+		ch <- prometheus.MustNewConstMetric(c.busQueued, prometheus.GaugeValue, float64(sub.Queued), sub.Name)
+		ch <- prometheus.MustNewConstMetric(c.busOnDisk, prometheus.GaugeValue, float64(sub.OnDisk), sub.Name)
+		ch <- prometheus.MustNewConstMetric(c.busDelivered, prometheus.CounterValue, float64(sub.Delivered), sub.Name)
+		ch <- prometheus.MustNewConstMetric(c.busDropped, prometheus.CounterValue, float64(sub.Dropped), sub.Name)
+	}
+
+	if a.feed != nil {
+		ch <- prometheus.MustNewConstMetric(c.streams, prometheus.GaugeValue, float64(a.feed.Listeners()))
+	}
+}
@@ -1,139 +1,216 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"mine-parser/internal/service"
+	"maps"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/models"
+	"mine-parser/internal/notify"
+	"mine-parser/internal/repo"
+	"slices"
 	"sync"
-
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"time"
 )
 
-// PlayerLoginEvent событие входа игрока на сервер
-type PlayerLoginEvent struct {
-	PlayerID  string
-	Username  string
-	Timestamp int64
-}
-
-// Глобальный канал для событий входа игроков
-var playerLoginEvents chan PlayerLoginEvent
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxBatchSize    = 50
+	outboxBaseBackoff  = 2 * time.Second
+	outboxMaxBackoff   = 10 * time.Minute
+	// Запросы к очереди не отменяются при остановке: результат уже выполненной отправки должен быть записан
+	outboxQueryTimeout = 30 * time.Second
+)
 
-// InitPlayerLoginEvents инициализирует канал событий
-func InitPlayerLoginEvents() chan PlayerLoginEvent {
-	if playerLoginEvents == nil {
-		playerLoginEvents = make(chan PlayerLoginEvent, 100) // Буферизованный канал
+// initNotifications создаёт отправку уведомлений для каналов, которые доставляет этот процесс:
+// Telegram — если запущен бот, Discord — если процесс ставит уведомления в очередь
+func (a *App) initNotifications() {
+	notifiers := make(map[string]notify.Notifier)
+	wakeTypes := []events.Type{events.TypePlayerLogin}
+	if a.bot != nil {
+		notifiers[models.ChannelTelegram] = notify.NewTelegram(a.bot)
 	}
-	return playerLoginEvents
-}
-
-// SendPlayerLoginEvent отправляет событие входа игрока (безопасно для использования из parser)
-func SendPlayerLoginEvent(playerID, username string) {
-	if playerLoginEvents != nil {
-		select {
-		case playerLoginEvents <- PlayerLoginEvent{
-			PlayerID:  playerID,
-			Username:  username,
-			Timestamp: 0, // Можно добавить timestamp если нужно
-		}:
-		default:
-			// Если канал переполнен, просто игнорируем (не блокируем parser)
-			log.Printf("Канал событий переполнен, событие пропущено")
-		}
+	if a.outbox != nil {
+		notifiers[models.ChannelDiscord] = notify.NewDiscord(a.cfg)
+		wakeTypes = config.DiscordEventTypes
+	}
+	if len(notifiers) == 0 {
+		return
 	}
-}
 
-// init устанавливает глобальную функцию отправки событий
-func init() {
-	service.SetGlobalLoginEventSender(SendPlayerLoginEvent)
+	// Уведомления хранятся в очереди в БД; событие из шины лишь сигнал, что в очереди
+	// появились новые сообщения, поэтому достаточно буфера на одно событие
+	wake, err := a.bus.Subscribe("notification-outbox", events.SubscriberOptions{
+		Buffer: 1,
+		Policy: events.DropOldest,
+		Types:  wakeTypes,
+	})
+	if err != nil {
+		logging.Errorf("Не удалось подписаться на события, уведомления отключены: %v", err)
+		return
+	}
+	a.notifications = NewNotificationSender(notifiers, a.outboxRepo, a.cfg, wake)
 }
 
-// NotificationSender отправляет уведомления о входе игроков
+// NotificationSender доставляет уведомления из очереди исходящих сообщений.
+// Сообщения попадают в очередь вместе с событием, поэтому переживают перезапуск
+// и недоступность получателя; неудачные отправки повторяются с экспоненциальной задержкой.
+// Отправитель доставляет сообщения только тех каналов, для которых у него есть Notifier:
+// Telegram — процесс с ботом, Discord — процесс, который разбирает лог.
 type NotificationSender struct {
-	bot             *tgbotapi.BotAPI
-	notificationSvc service.NotificationService
-	eventChan       chan PlayerLoginEvent
-	wg              sync.WaitGroup
-	stopChan        chan struct{}
+	notifiers  map[string]notify.Notifier // по каналу доставки
+	channels   []string
+	outboxRepo repo.OutboxRepository
+	cfg        *config.Store
+	wake       *events.Subscription // события уведомлений будят отправку сразу после записи
+	wg         sync.WaitGroup
+	stopChan   chan struct{}
+	stopOnce   sync.Once
 }
 
-// StartNotificationSender запускает сервис отправки уведомлений
-func StartNotificationSender(
-	bot *tgbotapi.BotAPI,
-	notificationSvc service.NotificationService,
-) {
-	eventChan := InitPlayerLoginEvents()
-
-	sender := &NotificationSender{
-		bot:             bot,
-		notificationSvc: notificationSvc,
-		eventChan:       eventChan,
-		stopChan:        make(chan struct{}),
+// NewNotificationSender создаёт сервис доставки уведомлений
+func NewNotificationSender(
+	notifiers map[string]notify.Notifier,
+	outboxRepo repo.OutboxRepository,
+	cfg *config.Store,
+	wake *events.Subscription,
+) *NotificationSender {
+	return &NotificationSender{
+		notifiers:  notifiers,
+		channels:   slices.Sorted(maps.Keys(notifiers)),
+		outboxRepo: outboxRepo,
+		cfg:        cfg,
+		wake:       wake,
+		stopChan:   make(chan struct{}),
 	}
+}
+
+// Start запускает доставку
+func (ns *NotificationSender) Start() {
+	ns.wg.Add(1)
+	go ns.run()
+}
 
-	sender.wg.Add(1)
-	go sender.processEvents()
+// Stop останавливает доставку после текущего сообщения. Неотправленное остаётся в очереди.
+func (ns *NotificationSender) Stop() {
+	ns.stopOnce.Do(func() {
+		close(ns.stopChan)
+		ns.wake.Unsubscribe()
+		ns.wg.Wait()
+	})
 }
 
-// processEvents обрабатывает события входа игроков
-func (ns *NotificationSender) processEvents() {
+func (ns *NotificationSender) run() {
 	defer ns.wg.Done()
 
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	// Сначала отправляем то, что осталось с прошлого запуска
+	ns.deliverDue()
 	for {
 		select {
-		case event := <-ns.eventChan:
-			ns.handlePlayerLogin(event)
 		case <-ns.stopChan:
 			return
+		case <-ns.wake.C():
+		case <-ticker.C:
 		}
+		ns.deliverDue()
 	}
 }
 
-// handlePlayerLogin обрабатывает событие входа игрока
-func (ns *NotificationSender) handlePlayerLogin(event PlayerLoginEvent) {
-	// Получаем всех активных подписчиков
-	subscribers, err := ns.notificationSvc.GetAllSubscribers()
-	if err != nil {
-		log.Printf("Ошибка при получении подписчиков: %v", err)
-		return
-	}
+// deliverDue отправляет все сообщения, срок попытки которых наступил
+func (ns *NotificationSender) deliverDue() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), outboxQueryTimeout)
+		messages, err := ns.outboxRepo.ListDue(ctx, ns.channels, time.Now().UTC(), outboxBatchSize)
+		cancel()
+		if err != nil {
+			logging.Errorf("Ошибка при чтении очереди уведомлений: %v", err)
+			return
+		}
 
-	if len(subscribers) == 0 {
-		return
+		for _, message := range messages {
+			select {
+			case <-ns.stopChan:
+				return
+			default:
+			}
+			ns.deliver(message)
+		}
+
+		if len(messages) < outboxBatchSize {
+			return
+		}
 	}
+}
 
-	// Формируем сообщение
-	message := fmt.Sprintf("🟢 Игрок %s зашел на сервер", event.Username)
+// deliver выполняет одну попытку отправки и записывает её результат
+func (ns *NotificationSender) deliver(message models.OutboxMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxQueryTimeout)
+	defer cancel()
 
-	// Отправляем уведомления асинхронно каждому подписчику
-	for _, subscriber := range subscribers {
-		go func(chatID int64) {
-			// Проверяем, нужно ли отправлять уведомление (не в черном списке)
-			shouldNotify, err := ns.notificationSvc.ShouldNotify(chatID, event.PlayerID)
-			if err != nil {
-				log.Printf("Ошибка при проверке черного списка для чата %d: %v", chatID, err)
-				return
-			}
+	now := time.Now().UTC()
+	if now.After(message.ExpiresAt) {
+		if err := ns.outboxRepo.MarkFinal(ctx, message.ID, models.OutboxExpired, message.LastError); err != nil {
+			logging.Errorf("Ошибка при обновлении уведомления %d: %v", message.ID, err)
+		}
+		return
+	}
 
-			if !shouldNotify {
-				return // Игрок в черном списке или уведомления выключены
-			}
+	// Попытка фиксируется до отправки: если процесс упадёт во время отправки,
+	// сообщение будет повторено не раньше, чем через интервал отсрочки
+	attempt := message.Attempts + 1
+	if err := ns.outboxRepo.BeginAttempt(ctx, message.ID, now.Add(outboxBackoff(attempt))); err != nil {
+		logging.Errorf("Ошибка при обновлении уведомления %d: %v", message.ID, err)
+		return
+	}
 
-			msg := tgbotapi.NewMessage(chatID, message)
-			if _, err := ns.bot.Send(msg); err != nil {
-				log.Printf("Ошибка при отправке уведомления в чат %d: %v", chatID, err)
-			}
-		}(subscriber.ChatID)
+	sendErr := ns.notifiers[message.Channel].Send(ctx, message)
+	var err error
+	switch {
+	case sendErr == nil:
+		err = ns.outboxRepo.MarkSent(ctx, message.ID, time.Now().UTC())
+	case notify.IsPermanent(sendErr):
+		logging.Warnf("Уведомление %s не может быть доставлено: %v", recipient(message), sendErr)
+		err = ns.outboxRepo.MarkFinal(ctx, message.ID, models.OutboxFailed, sendErr.Error())
+	case attempt >= ns.cfg.Get().Tg.NotifyMaxAttempts:
+		logging.Warnf("Уведомление %s не доставлено за %d попыток: %v", recipient(message), attempt, sendErr)
+		err = ns.outboxRepo.MarkFinal(ctx, message.ID, models.OutboxFailed, sendErr.Error())
+	default:
+		delay := max(outboxBackoff(attempt), notify.RetryAfter(sendErr))
+		logging.Errorf("Ошибка при отправке уведомления %s (попытка %d, повтор через %s): %v",
+			recipient(message), attempt, delay, sendErr)
+		err = ns.outboxRepo.MarkRetry(ctx, message.ID, time.Now().UTC().Add(delay), sendErr.Error())
+	}
+	if err != nil {
+		logging.Errorf("Ошибка при обновлении уведомления %d: %v", message.ID, err)
 	}
 }
 
-// Stop останавливает сервис отправки уведомлений
-func (ns *NotificationSender) Stop() {
-	close(ns.stopChan)
-	ns.wg.Wait()
+// outboxBackoff — задержка перед попыткой attempt+1: 2с, 4с, 8с… но не больше outboxMaxBackoff
+func outboxBackoff(attempt int) time.Duration {
+	return exponentialBackoff(outboxBaseBackoff, outboxMaxBackoff, attempt)
+}
+
+// exponentialBackoff удваивает base с каждой попыткой после первой, но не больше limit
+func exponentialBackoff(base, limit time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
 }
 
-// GetNotificationSender возвращает экземпляр отправителя (для использования в tg.go)
-func GetNotificationSender() *NotificationSender {
-	return nil // Не используется, но может понадобиться для graceful shutdown
+// recipient описывает получателя уведомления для журнала
+func recipient(message models.OutboxMessage) string {
+	if message.Channel == models.ChannelDiscord {
+		return fmt.Sprintf("в канал Discord %s", message.Target)
+	}
+	return fmt.Sprintf("в чат %d", message.ChatID)
 }
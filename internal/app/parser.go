@@ -1,74 +1,38 @@
 package app
 
 import (
-	"bufio"
 	"context"
+	"errors"
+	"io"
 	"log"
-	"mine-parser/internal/config"
-	"mine-parser/internal/migrations"
-	"mine-parser/internal/repo"
+	"mine-parser/internal/logging"
 	"mine-parser/internal/service"
 	"os"
-	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
-func Parser() {
-	// 1. Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalln("Failed to load config:", err)
-	}
+// runParser отслеживает лог до отмены ctx
+func (a *App) runParser(ctx context.Context) error {
+	cfg := a.cfg.Get()
 	if cfg.App.ParsePath == "" {
-		log.Fatal("parse_path не задан в конфигурации")
-	}
-
-	// 2. Инициализация БД
-	dbConn := migrations.InitDB(cfg.Db.Dsn)
-
-	// 3. Репозитории
-	playerRepo := repo.NewPlayerRepository(dbConn)
-	sessionRepo := repo.NewSessionRepository(dbConn)
-	commandRepo := repo.NewCommandRepository(dbConn)
-	advancementRepo := repo.NewAdvancementRepository(dbConn)
-
-	// 4. Сервисы
-	playerSvc := service.NewPlayerService(playerRepo, sessionRepo, commandRepo, advancementRepo)
-	commandSvc := service.NewCommandService(commandRepo, sessionRepo)
-	advancementSvc := service.NewAdvancementService(advancementRepo)
-
-	parser := service.NewLogParserService(cfg, playerSvc, commandSvc, advancementSvc)
-
-	// 5. Настройка graceful shutdown
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	// 6. Запуск парсера
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- startTailing(ctx, cfg.App.ParsePath, parser)
-	}()
-
-	// 7. Ожидание завершения
-	select {
-	case <-ctx.Done():
-		log.Println("Получен сигнал завершения, останавливаем парсинг...")
-	case err := <-errCh:
-		if err != nil {
-			log.Fatalf("Ошибка при чтении лога: %v", err)
-		}
+		return errors.New("parse_path не задан в конфигурации")
 	}
 
-	// Дополнительная задержка для завершения обработки
-	time.Sleep(500 * time.Millisecond)
-	log.Println("Приложение завершено.")
+	parser := service.NewLogParserService(ctx, cfg, a.playerSvc, a.pipeline, a.diagnostics)
+	err := startTailing(ctx, cfg.App.ParsePath, cfg.App.Location, parser, a.tail)
+	a.tail.stop(err)
+	return err
 }
 
-func startTailing(ctx context.Context, filePath string, parser service.LogParserService) error {
+// startTailing читает новые строки лога; даты строк восстанавливаются в часовом поясе loc
+func startTailing(ctx context.Context, filePath string, loc *time.Location, parser service.LogParserService, state *tailState) error {
 	var file *os.File
 	var lastPos int64
 	var currentInode uint64
+	var sourceID string
+	var clock *service.LogClock
 
 	openFile := func() error {
 		if file != nil {
@@ -86,11 +50,31 @@ func startTailing(ctx context.Context, filePath string, parser service.LogParser
 			return err
 		}
 
-		// При первом открытии читаем с конца
-		if currentInode == 0 {
-			lastPos = stat.Size()
+		// Часы и идентичность лога считаются по самому файлу, как при загрузке командой import:
+		// день последней строки — дата изменения файла, от неё отсчитываются переходы через полночь.
+		// От момента, когда замечена ротация, они не зависят.
+		size := stat.Size()
+		var firstLine string
+		clock, err = service.ScanLogClock(io.NewSectionReader(file, 0, size), stat.ModTime().In(loc))
+		if err == nil {
+			firstLine, err = service.ReadFirstLine(io.NewSectionReader(file, 0, size))
+		}
+		if err != nil {
+			file.Close()
+			return err
+		}
+		sourceID = ""
+		if firstLine != "" {
+			sourceID = service.SourceIdentity(firstLine, clock.Start())
+		}
+
+		if currentInode == 0 && firstLine != "" {
+			lastPos = size // При первом открытии читаем с конца: часы уже стоят на последней строке
 		} else {
-			lastPos = 0 // Новый файл после ротации
+			// Новый файл после ротации или первая строка не дописана — читаем файл с начала;
+			// без первой строки идентичность вычисляется, когда она будет прочитана
+			lastPos = 0
+			clock.Rewind()
 		}
 
 		currentInode = stat.Sys().(*syscall.Stat_t).Ino
@@ -104,6 +88,7 @@ func startTailing(ctx context.Context, filePath string, parser service.LogParser
 	defer file.Close()
 
 	log.Printf("Начинаю отслеживание лога: %s", filePath)
+	state.start(filePath, lastPos)
 
 	ticker := time.NewTicker(200 * time.Millisecond)
 	defer ticker.Stop()
@@ -117,7 +102,8 @@ func startTailing(ctx context.Context, filePath string, parser service.LogParser
 			// Проверяем ротацию по inode
 			newStat, err := os.Stat(filePath)
 			if err != nil {
-				log.Printf("Ошибка проверки файла: %v", err)
+				logging.Errorf("Ошибка проверки файла: %v", err)
+				state.fail(err)
 				continue
 			}
 
@@ -125,27 +111,83 @@ func startTailing(ctx context.Context, filePath string, parser service.LogParser
 			if newInode != currentInode {
 				log.Println("Обнаружена ротация файла, переоткрываю...")
 				if err := openFile(); err != nil {
-					log.Printf("Ошибка переоткрытия файла: %v", err)
+					logging.Errorf("Ошибка переоткрытия файла: %v", err)
+					state.fail(err)
 					continue
 				}
+				state.observe(lastPos, newStat.Size())
 				continue
 			}
 
-			// Читаем новые данные
+			// Читаем новые данные (только завершённые строки, смещения нужны для отпечатков событий)
 			if newStat.Size() > lastPos {
 				file.Seek(lastPos, 0)
-				scanner := bufio.NewScanner(file)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if err := parser.ProcessLogLine(line); err != nil {
-						log.Printf("Ошибка обработки строки: %v\n  Строка: %s", err, line)
+				lastPos, err = service.ReadLines(file, lastPos, false, func(line string, offset int64) {
+					if offset == 0 {
+						sourceID = service.SourceIdentity(line, clock.Start())
 					}
+					origin := service.LineOrigin{SourceID: sourceID, Offset: offset, Clock: clock}
+					if err := parser.ProcessLogLine(ctx, line, origin); err != nil {
+						logging.Errorf("Ошибка обработки строки: %v\n  Строка: %s", err, line)
+					}
+				})
+				if err != nil {
+					logging.Errorf("Ошибка чтения файла: %v", err)
+					state.fail(err)
 				}
-				if err := scanner.Err(); err != nil {
-					log.Printf("Ошибка чтения файла: %v", err)
-				}
-				lastPos, _ = file.Seek(0, 1)
 			}
+			state.observe(lastPos, newStat.Size())
 		}
 	}
 }
+
+// tailState — состояние отслеживания лога для проверок здоровья
+type tailState struct {
+	mu           sync.Mutex
+	path         string
+	running      bool
+	offset       int64     // до какого места прочитан файл
+	size         int64     // размер файла при последней проверке
+	lastProgress time.Time // когда чтение последний раз продвинулось или было в конце файла
+	lastError    string
+}
+
+func (t *tailState) start(path string, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.path, t.running, t.offset, t.size = path, true, offset, offset
+	t.lastProgress, t.lastError = time.Now(), ""
+}
+
+func (t *tailState) observe(offset, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if offset != t.offset || offset >= size {
+		t.lastProgress = time.Now()
+	}
+	t.offset, t.size, t.lastError = offset, size, ""
+}
+
+func (t *tailState) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastError = err.Error()
+}
+
+func (t *tailState) stop(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+	if err != nil {
+		t.lastError = err.Error()
+	}
+}
+
+func (t *tailState) snapshot() tailState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return tailState{
+		path: t.path, running: t.running, offset: t.offset, size: t.size,
+		lastProgress: t.lastProgress, lastError: t.lastError,
+	}
+}
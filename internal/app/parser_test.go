@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"mine-parser/internal/service"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tailedLine — строка, переданная парсеру при отслеживании лога
+type tailedLine struct {
+	line     string
+	sourceID string
+	at       time.Time
+}
+
+// recordingParser запоминает строки вместо разбора; время строки восстанавливает
+// часами источника, как настоящий парсер
+type recordingParser struct {
+	lines chan tailedLine
+}
+
+func (p *recordingParser) ProcessLogFile(context.Context, string) error { return nil }
+
+func (p *recordingParser) ProcessLogLine(_ context.Context, line string, origin service.LineOrigin) error {
+	at, err := origin.Clock.Resolve(line[1:9])
+	if err != nil {
+		return err
+	}
+	p.lines <- tailedLine{line: line, sourceID: origin.SourceID, at: at}
+	return nil
+}
+
+// TestTailingRotationUsesFileDate — после ротации дата строк и идентичность лога берутся
+// из самого файла, а не из момента, когда ротация замечена: иначе отпечатки живого чтения
+// не совпали бы с отпечатками загрузки того же файла из архива
+func TestTailingRotationUsesFileDate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "latest.log")
+	if err := os.WriteFile(path, []byte("[12:00:00] [Server thread/INFO]: Stopping server\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := &recordingParser{lines: make(chan tailedLine, 10)}
+	state := &tailState{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- startTailing(ctx, path, time.UTC, parser, state) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	for deadline := time.Now().Add(5 * time.Second); !state.snapshot().running; {
+		if time.Now().After(deadline) {
+			t.Fatal("отслеживание не запустилось")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Сервер перезапущен перед полуночью 10 апреля; последняя запись — уже 11 апреля
+	lines := []string{
+		"[23:59:50] [Server thread/INFO]: Starting minecraft server version 1.21.4",
+		`[00:00:05] [Server thread/INFO]: Done (15.000s)! For help, type "help"`,
+	}
+	next := filepath.Join(dir, "next.log")
+	if err := os.WriteFile(next, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2026, 4, 11, 0, 0, 5, 0, time.UTC)
+	if err := os.Chtimes(next, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, filepath.Join(dir, "2026-04-10-1.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+
+	firstDay := time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)
+	want := []tailedLine{
+		{line: lines[0], sourceID: service.SourceIdentity(lines[0], firstDay), at: time.Date(2026, 4, 10, 23, 59, 50, 0, time.UTC)},
+		{line: lines[1], sourceID: service.SourceIdentity(lines[0], firstDay), at: modTime},
+	}
+	for i, w := range want {
+		select {
+		case got := <-parser.lines:
+			if got.line != w.line || got.sourceID != w.sourceID || !got.at.Equal(w.at) {
+				t.Errorf("строка %d: %+v, want %+v", i+1, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("строка %d не прочитана после ротации", i+1)
+		}
+	}
+}
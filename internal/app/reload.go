@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"log"
+	"mine-parser/internal/logging"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchReload перечитывает конфигурацию по SIGHUP до отмены ctx. Компоненты берут
+// значения из хранилища при каждом использовании, поэтому новые тексты, часовой пояс,
+// список серверов, администраторы и таймауты действуют сразу.
+func (a *App) watchReload(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			cfg, err := a.cfg.Reload()
+			if err != nil {
+				logging.Errorf("Конфигурация не перезагружена, продолжаем с прежней: %v", err)
+				continue
+			}
+			logging.SetLevel(cfg.App.Level)
+			log.Println("Конфигурация перезагружена")
+		}
+	}
+}
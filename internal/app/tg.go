@@ -1,73 +1,187 @@
 package app
 
 import (
+	"context"
 	"log"
-	"mine-parser/internal/config"
 	"mine-parser/internal/handlers"
-	"mine-parser/internal/migrations"
-	"mine-parser/internal/repo"
-	"mine-parser/internal/service"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/metrics"
+	"net/http"
+	"path"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-func TelegramBot() {
-	// 1. Загрузка конфигурации
-	cfg, err := config.Load()
-	if err != nil {
-		log.Printf("Failed to load config for Telegram bot: %v", err)
-		return
-	}
-
+// initTelegram создаёт бота, хендлеры и отправку уведомлений. Без TG_TOKEN бот не запускается.
+func (a *App) initTelegram() {
+	cfg := a.cfg.Get()
 	if cfg.Tg.Token == "" {
-		log.Println("TG_TOKEN не задан, Telegram бот не запущен")
+		logging.Warnf("TG_TOKEN не задан, Telegram бот не запущен")
 		return
 	}
 
-	// 2. Инициализация БД
-	dbConn := migrations.ConnectDB(cfg.Db.Dsn)
-
-	// 3. Репозитории
-	playerRepo := repo.NewPlayerRepository(dbConn)
-	sessionRepo := repo.NewSessionRepository(dbConn)
-	commandRepo := repo.NewCommandRepository(dbConn)
-	advancementRepo := repo.NewAdvancementRepository(dbConn)
-	notificationRepo := repo.NewNotificationRepository(dbConn)
-
-	// 4. Сервисы
-	playerSvc := service.NewPlayerService(playerRepo, sessionRepo, commandRepo, advancementRepo)
-	commandSvc := service.NewCommandService(commandRepo, sessionRepo)
-	advancementSvc := service.NewAdvancementService(advancementRepo)
-	notificationSvc := service.NewNotificationService(notificationRepo)
-
-	// 5. Создание бота
-	bot, err := tgbotapi.NewBotAPI(cfg.Tg.Token)
+	client := &pollingClient{client: &http.Client{}}
+	bot, err := tgbotapi.NewBotAPIWithClient(cfg.Tg.Token, tgbotapi.APIEndpoint, client)
 	if err != nil {
-		log.Printf("Не удалось создать бота: %v", err)
+		logging.Errorf("Не удалось создать бота: %v", err)
 		return
 	}
 
-	bot.Debug = false
+	bot.Debug = logging.Enabled(logging.LevelDebug)
 	log.Printf("Авторизован как %s", bot.Self.UserName)
 
-	// 6. Запуск сервиса отправки уведомлений
-	StartNotificationSender(bot, notificationSvc)
+	a.bot = bot
+	a.telegramHandlers = handlers.NewTelegramHandlers(bot, a.cfg, a.playerSvc, a.commandSvc, a.advancementSvc, a.statsSvc, a.leaderboardSvc, a.notificationSvc, a.discordSvc, a.webhookSvc, a.diagnostics, a.bus)
+}
 
-	// 7. Создание хендлеров
-	telegramHandlers := handlers.NewTelegramHandlers(bot, playerSvc, commandSvc, advancementSvc, notificationSvc)
+// runBot получает обновления до отмены ctx и дожидается завершения запущенных обработчиков
+func (a *App) runBot(ctx context.Context) {
+	client := a.bot.Client.(*pollingClient)
+	client.setContext(ctx)
+	a.telegramHandlers.RegisterCommands()
+
+	var handlersWg sync.WaitGroup
+	defer handlersWg.Wait()
 
-	// 8. Настройка обновлений
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
-	updates := bot.GetUpdatesChan(u)
+	a.poll.start()
+	defer a.poll.stop()
 
-	// 9. Обработка обновлений
-	for update := range updates {
-		if update.Message != nil {
-			go telegramHandlers.HandleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			go telegramHandlers.HandleCallback(update.CallbackQuery)
+	for ctx.Err() == nil {
+		updates, err := a.bot.GetUpdates(u)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			a.poll.fail(err)
+			logging.Errorf("Не удалось получить обновления: %v, повтор через 3 секунды", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(3 * time.Second):
+			}
+			continue
 		}
+		a.poll.succeed()
+
+		// Обработка обновлений
+		for _, update := range updates {
+			if update.UpdateID >= u.Offset {
+				u.Offset = update.UpdateID + 1
+			}
+
+			handlersWg.Add(1)
+			go func(update tgbotapi.Update) {
+				defer handlersWg.Done()
+				// Обработчик ограничен по времени и прерывается при остановке бота
+				handlerCtx, cancel := context.WithTimeout(ctx, a.cfg.Get().Tg.HandlerTimeout)
+				defer cancel()
+				if update.Message != nil {
+					a.telegramHandlers.HandleMessage(handlerCtx, update.Message)
+				} else if update.CallbackQuery != nil {
+					a.telegramHandlers.HandleCallback(handlerCtx, update.CallbackQuery)
+				} else if update.InlineQuery != nil {
+					a.telegramHandlers.HandleInlineQuery(handlerCtx, update.InlineQuery)
+				}
+			}(update)
+		}
+	}
+
+	log.Println("Получение обновлений Telegram остановлено")
+}
+
+// pollingClient прерывает долгий опрос getUpdates при остановке бота,
+// не затрагивая остальные запросы (например, отправку уведомлений при завершении)
+type pollingClient struct {
+	client *http.Client
+	mu     sync.RWMutex
+	ctx    context.Context
+}
+
+func (c *pollingClient) setContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
+}
+
+func (c *pollingClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.RLock()
+	ctx := c.ctx
+	c.mu.RUnlock()
+
+	method := path.Base(req.URL.Path)
+	if ctx != nil && method == "getUpdates" {
+		req = req.WithContext(ctx)
+	}
+	resp, err := c.client.Do(req)
+	if method != "getUpdates" {
+		metrics.TelegramRequests.WithLabelValues(method, telegramResult(resp, err)).Inc()
+	}
+	return resp, err
+}
+
+// telegramResult относит ответ Bot API к классу для метрик: ok или вид ошибки
+func telegramResult(resp *http.Response, err error) string {
+	switch {
+	case err != nil:
+		return "network"
+	case resp.StatusCode < http.StatusMultipleChoices:
+		return "ok"
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return "forbidden"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "server_error"
+	case resp.StatusCode == http.StatusBadRequest:
+		return "bad_request"
+	}
+	return "other"
+}
+
+// pollState — состояние получения обновлений Telegram для проверок здоровья
+type pollState struct {
+	mu          sync.Mutex
+	running     bool
+	startedAt   time.Time
+	lastSuccess time.Time
+	lastError   string
+	failures    int // ошибок подряд
+}
+
+func (p *pollState) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running, p.startedAt = true, time.Now()
+}
+
+func (p *pollState) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+}
+
+func (p *pollState) succeed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSuccess, p.lastError, p.failures = time.Now(), "", 0
+}
+
+func (p *pollState) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastError = err.Error()
+	p.failures++
+}
+
+func (p *pollState) snapshot() pollState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return pollState{
+		running: p.running, startedAt: p.startedAt, lastSuccess: p.lastSuccess,
+		lastError: p.lastError, failures: p.failures,
 	}
 }
@@ -0,0 +1,362 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/webhook"
+	"sync"
+	"time"
+)
+
+const (
+	webhookSubscriber    = "webhooks"
+	webhookBusBuffer     = 1000
+	webhookPollInterval  = 5 * time.Second
+	webhookBatchSize     = 50
+	webhookBaseBackoff   = 5 * time.Second
+	webhookMaxBackoff    = time.Hour
+	webhookPruneInterval = time.Hour
+	// Запросы к очереди не отменяются при остановке: результат уже выполненной отправки должен быть записан
+	webhookQueryTimeout = 30 * time.Second
+)
+
+// initWebhooks подписывает отправку вебхуков на шину. События публикует конвейер записи,
+// поэтому вебхуки работают в процессе с парсером. Подписка создаётся и без вебхуков
+// в конфигурации: их можно добавить по SIGHUP.
+func (a *App) initWebhooks() {
+	sub, err := a.bus.Subscribe(webhookSubscriber, events.SubscriberOptions{
+		Buffer:   webhookBusBuffer,
+		Policy:   events.SpillToDisk,
+		SpillDir: a.cfg.Get().App.EventSpoolDir,
+	})
+	if err != nil {
+		logging.Errorf("Не удалось подписаться на события, вебхуки отключены: %v", err)
+		return
+	}
+	a.webhooks = NewWebhookSender(webhook.NewClient(), a.webhookRepo, a.cfg, sub)
+}
+
+// WebhookSender доставляет события шины вебхукам из конфигурации. События сначала
+// записываются в очередь доставки в БД (при всплеске — в файл переполнения шины),
+// затем отправляются с повторами и экспоненциальной задержкой. Очередь служит и журналом
+// доставки. Вебхук, не принявший webhooks.disable_after попыток подряд, отключается.
+type WebhookSender struct {
+	client      *webhook.Client
+	webhookRepo repo.WebhookRepository
+	cfg         *config.Store
+	events      *events.Subscription
+	wake        chan struct{} // новые доставки в очереди
+
+	ctx      context.Context // отменяется при остановке, прерывая текущий запрос
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWebhookSender создаёт сервис доставки вебхуков
+func NewWebhookSender(
+	client *webhook.Client,
+	webhookRepo repo.WebhookRepository,
+	cfg *config.Store,
+	sub *events.Subscription,
+) *WebhookSender {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookSender{
+		client:      client,
+		webhookRepo: webhookRepo,
+		cfg:         cfg,
+		events:      sub,
+		wake:        make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start запускает приём событий и доставку
+func (ws *WebhookSender) Start() {
+	ws.wg.Add(2)
+	go ws.collect()
+	go ws.run()
+}
+
+// Stop записывает в очередь события из буфера подписки и останавливает доставку.
+// Неотправленное остаётся в очереди в БД, события из файла переполнения — на диске.
+func (ws *WebhookSender) Stop() {
+	ws.stopOnce.Do(func() {
+		ws.events.Unsubscribe()
+		close(ws.stopChan)
+		ws.cancel()
+		ws.wg.Wait()
+	})
+}
+
+// collect ставит события в очередь доставки, пока подписка не закрыта
+func (ws *WebhookSender) collect() {
+	defer ws.wg.Done()
+	for event := range ws.events.C() {
+		ws.enqueue(event)
+	}
+}
+
+// enqueue создаёт доставки события для подходящих включённых вебхуков
+func (ws *WebhookSender) enqueue(event events.Event) {
+	subject := events.SubjectOf(event)
+	var targets []string
+	for _, endpoint := range ws.cfg.Get().Webhooks.Endpoints {
+		if endpoint.Matches(string(event.EventType()), subject.Server) {
+			targets = append(targets, endpoint.Name)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookQueryTimeout)
+	defer cancel()
+	disabled, err := ws.disabledWebhooks(ctx)
+	if err != nil {
+		logging.Errorf("Ошибка при чтении состояния вебхуков: %v", err)
+		return
+	}
+	body, err := webhook.Marshal(event)
+	if err != nil {
+		logging.Errorf("Не удалось подготовить событие %s для вебхуков: %v", event.EventType(), err)
+		return
+	}
+
+	now := time.Now().UTC()
+	var deliveries []models.WebhookDelivery
+	for _, name := range targets {
+		if disabled[name] {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			Webhook:       name,
+			EventType:     string(event.EventType()),
+			Payload:       string(body),
+			Status:        models.WebhookPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := ws.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		logging.Errorf("Не удалось поставить событие %s в очередь вебхуков: %v", event.EventType(), err)
+		return
+	}
+	if len(deliveries) > 0 {
+		select {
+		case ws.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (ws *WebhookSender) run() {
+	defer ws.wg.Done()
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		if time.Since(lastPrune) >= webhookPruneInterval {
+			ws.prune()
+			lastPrune = time.Now()
+		}
+		ws.deliverDue()
+
+		select {
+		case <-ws.stopChan:
+			return
+		case <-ws.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue выполняет все доставки, срок попытки которых наступил
+func (ws *WebhookSender) deliverDue() {
+	// Недоступному получателю в этом проходе больше не пишем: его доставки
+	// уже отложены в БД, а каждый запрос к нему может ждать полный таймаут
+	unreachable := make(map[string]bool)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookQueryTimeout)
+		deliveries, err := ws.webhookRepo.ListDue(ctx, time.Now().UTC(), webhookBatchSize)
+		var disabled map[string]bool
+		if err == nil {
+			disabled, err = ws.disabledWebhooks(ctx)
+		}
+		cancel()
+		if err != nil {
+			logging.Errorf("Ошибка при чтении очереди вебхуков: %v", err)
+			return
+		}
+
+		for _, delivery := range deliveries {
+			select {
+			case <-ws.stopChan:
+				return
+			default:
+			}
+			if !unreachable[delivery.Webhook] && ws.deliver(delivery, disabled) {
+				unreachable[delivery.Webhook] = true
+			}
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliver выполняет одну попытку доставки и записывает её результат; true — попытка
+// будет повторена. disabled пополняется, если после неудачи вебхук отключён.
+func (ws *WebhookSender) deliver(delivery models.WebhookDelivery, disabled map[string]bool) (retry bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookQueryTimeout)
+	defer cancel()
+
+	cfg := ws.cfg.Get().Webhooks
+	endpoint, ok := cfg.Endpoint(delivery.Webhook)
+	var reason string
+	switch {
+	case !ok:
+		reason = "вебхук удалён из конфигурации"
+	case disabled[delivery.Webhook]:
+		reason = "вебхук отключён"
+	}
+	if reason != "" {
+		if err := ws.webhookRepo.MarkFailed(ctx, delivery.ID, repo.DeliveryAttempt{Error: reason}); err != nil {
+			logging.Errorf("Ошибка при обновлении доставки %d: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	// Попытка фиксируется до отправки: если процесс упадёт во время запроса,
+	// доставка будет повторена не раньше, чем через интервал отсрочки
+	now := time.Now().UTC()
+	attempt := delivery.Attempts + 1
+	if err := ws.webhookRepo.BeginAttempt(ctx, delivery.ID, now.Add(webhookBackoff(attempt))); err != nil {
+		logging.Errorf("Ошибка при обновлении доставки %d: %v", delivery.ID, err)
+		return
+	}
+
+	sendCtx, cancelSend := context.WithTimeout(ws.ctx, cfg.Timeout)
+	result := ws.client.Send(sendCtx, webhook.Request{
+		Webhook:    endpoint.Name,
+		URL:        endpoint.URL,
+		Secret:     endpoint.Secret,
+		DeliveryID: delivery.ID,
+		Event:      delivery.EventType,
+		Body:       []byte(delivery.Payload),
+	})
+	cancelSend()
+
+	record := repo.DeliveryAttempt{StatusCode: result.StatusCode, Duration: result.Duration}
+	if result.Err == nil {
+		if err := ws.webhookRepo.MarkDelivered(ctx, delivery.ID, time.Now().UTC(), record); err != nil {
+			logging.Errorf("Ошибка при обновлении доставки %d: %v", delivery.ID, err)
+		}
+		if err := ws.webhookRepo.RecordSuccess(ctx, endpoint.Name); err != nil {
+			logging.Errorf("Ошибка при обновлении состояния вебхука %s: %v", endpoint.Name, err)
+		}
+		return
+	}
+
+	record.Error = result.Err.Error()
+	if ws.ctx.Err() != nil {
+		// Запрос прерван остановкой приложения: это не ошибка получателя, повторим при запуске
+		if err := ws.webhookRepo.MarkRetry(ctx, delivery.ID, now, record); err != nil {
+			logging.Errorf("Ошибка при обновлении доставки %d: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	var err error
+	switch {
+	case !result.Retryable():
+		logging.Warnf("Вебхук %s отклонил доставку %d: %v", endpoint.Name, delivery.ID, result.Err)
+		err = ws.webhookRepo.MarkFailed(ctx, delivery.ID, record)
+	case attempt >= cfg.MaxAttempts:
+		logging.Warnf("Доставка %d вебхуку %s не выполнена за %d попыток: %v", delivery.ID, endpoint.Name, attempt, result.Err)
+		err = ws.webhookRepo.MarkFailed(ctx, delivery.ID, record)
+	default:
+		delay := max(webhookBackoff(attempt), result.RetryAfter)
+		next := time.Now().UTC().Add(delay)
+		logging.Errorf("Ошибка при доставке %d вебхуку %s (попытка %d, повтор через %s): %v",
+			delivery.ID, endpoint.Name, attempt, delay, result.Err)
+		err = ws.webhookRepo.MarkRetry(ctx, delivery.ID, next, record)
+		// Остальные доставки недоступному получателю ждут того же срока,
+		// чтобы не тратить на него время ожидания в каждом проходе очереди
+		if err == nil {
+			err = ws.webhookRepo.Postpone(ctx, endpoint.Name, next)
+		}
+		retry = true
+	}
+	if err != nil {
+		logging.Errorf("Ошибка при обновлении доставки %d: %v", delivery.ID, err)
+	}
+
+	failures, err := ws.webhookRepo.RecordFailure(ctx, endpoint.Name)
+	if err != nil {
+		logging.Errorf("Ошибка при обновлении состояния вебхука %s: %v", endpoint.Name, err)
+		return
+	}
+	if cfg.DisableAfter > 0 && failures >= cfg.DisableAfter {
+		ws.disable(ctx, endpoint.Name, fmt.Sprintf("неудачных попыток подряд: %d, последняя: %s", failures, record.Error))
+		disabled[endpoint.Name] = true
+	}
+	return
+}
+
+// disable отключает вебхук и завершает его ожидающие доставки
+func (ws *WebhookSender) disable(ctx context.Context, name, reason string) {
+	if err := ws.webhookRepo.Disable(ctx, name, reason, time.Now().UTC()); err != nil {
+		logging.Errorf("Не удалось отключить вебхук %s: %v", name, err)
+		return
+	}
+	dropped, err := ws.webhookRepo.FailPending(ctx, name, "вебхук отключён")
+	if err != nil {
+		logging.Errorf("Ошибка при очистке очереди вебхука %s: %v", name, err)
+	}
+	logging.Warnf("Вебхук %s отключён (%s), отменено доставок: %d", name, reason, dropped)
+}
+
+func (ws *WebhookSender) disabledWebhooks(ctx context.Context) (map[string]bool, error) {
+	states, err := ws.webhookRepo.ListStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	disabled := make(map[string]bool)
+	for _, state := range states {
+		if state.DisabledAt != nil {
+			disabled[state.Webhook] = true
+		}
+	}
+	return disabled, nil
+}
+
+// prune удаляет из журнала завершённые доставки старше webhooks.log_retention
+func (ws *WebhookSender) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookQueryTimeout)
+	defer cancel()
+	before := time.Now().UTC().Add(-ws.cfg.Get().Webhooks.LogRetention)
+	deleted, err := ws.webhookRepo.DeleteFinishedBefore(ctx, before)
+	if err != nil {
+		logging.Errorf("Ошибка при очистке журнала вебхуков: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logging.Debugf("Из журнала вебхуков удалено доставок: %d", deleted)
+	}
+}
+
+// webhookBackoff — задержка перед попыткой attempt+1: 5с, 10с, 20с… но не больше webhookMaxBackoff
+func webhookBackoff(attempt int) time.Duration {
+	return exponentialBackoff(webhookBaseBackoff, webhookMaxBackoff, attempt)
+}
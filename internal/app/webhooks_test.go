@@ -0,0 +1,273 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/migrations"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/webhook"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "test-secret-0123456789"
+
+// testResponse — ответ тестового получателя на очередной запрос
+type testResponse struct {
+	status     int
+	retryAfter int // секунд в заголовке Retry-After; 0 — без заголовка
+}
+
+// testReceiver — получатель вебхука: проверяет подпись и отвечает по сценарию;
+// после конца сценария повторяет последний ответ
+type testReceiver struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []testResponse
+	requests  []http.Header
+	bodies    [][]byte
+}
+
+func (rc *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("чтение тела запроса: %v", err)
+	}
+	if err := webhook.Verify(testWebhookSecret, r.Header, body, time.Minute, time.Now()); err != nil {
+		rc.t.Errorf("подпись запроса: %v", err)
+	}
+
+	rc.mu.Lock()
+	response := rc.responses[min(len(rc.requests), len(rc.responses)-1)]
+	rc.requests = append(rc.requests, r.Header.Clone())
+	rc.bodies = append(rc.bodies, body)
+	rc.mu.Unlock()
+
+	if response.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(response.retryAfter))
+	}
+	w.WriteHeader(response.status)
+	if response.status >= 300 {
+		io.WriteString(w, http.StatusText(response.status))
+	}
+}
+
+func (rc *testReceiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// newTestSender создаёт отправку в тестовый сервер поверх новой БД SQLite; шина не нужна:
+// тест ставит события в очередь и выполняет проходы очереди сам
+func newTestSender(t *testing.T, disableAfter int, responses ...testResponse) (*WebhookSender, *testReceiver, *gorm.DB) {
+	t.Helper()
+	receiver := &testReceiver{t: t, responses: responses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	db, err := migrations.InitDB(config.SQLiteScheme+filepath.Join(t.TempDir(), "webhooks.db"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { sqlDB.Close() })
+	}
+
+	cfg := &config.Config{Webhooks: config.WebhooksConfig{
+		Timeout:      5 * time.Second,
+		MaxAttempts:  10,
+		DisableAfter: disableAfter,
+		Endpoints:    []config.WebhookConfig{{Name: "test", URL: server.URL, Secret: testWebhookSecret}},
+	}}
+	sender := NewWebhookSender(webhook.NewClient(), repo.NewWebhookRepository(db), config.NewStore(cfg), nil)
+	t.Cleanup(sender.cancel)
+	return sender, receiver, db
+}
+
+func testLogin(username string) events.Event {
+	return events.PlayerLogin{
+		Server:    "main",
+		PlayerID:  "00000000-0000-0000-0000-000000000001",
+		Username:  username,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// makeDue переносит отложенные доставки на текущий момент, как будто срок повтора прошёл
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	err := db.Model(&models.WebhookDelivery{}).Where("status = ?", models.WebhookPending).
+		Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listDeliveries(t *testing.T, db *gorm.DB) []models.WebhookDelivery {
+	t.Helper()
+	var deliveries []models.WebhookDelivery
+	if err := db.Order("id").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func webhookState(t *testing.T, db *gorm.DB) models.WebhookState {
+	t.Helper()
+	var states []models.WebhookState
+	if err := db.Where("webhook = ?", "test").Find(&states).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(states) == 0 {
+		return models.WebhookState{}
+	}
+	return states[0]
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	sender, receiver, db := newTestSender(t, 50,
+		testResponse{status: http.StatusServiceUnavailable, retryAfter: 30},
+		testResponse{status: http.StatusInternalServerError},
+		testResponse{status: http.StatusBadGateway},
+		testResponse{status: http.StatusOK},
+	)
+	sender.enqueue(testLogin("Steve"))
+
+	// Задержка — не меньше экспоненциальной отсрочки и не меньше Retry-After
+	retries := []struct {
+		status int
+		delay  time.Duration
+	}{
+		{http.StatusServiceUnavailable, 30 * time.Second},
+		{http.StatusInternalServerError, webhookBackoff(2)},
+		{http.StatusBadGateway, webhookBackoff(3)},
+	}
+	for i, retry := range retries {
+		before := time.Now().UTC()
+		sender.deliverDue()
+		after := time.Now().UTC()
+
+		// Повтор не выполняется раньше срока
+		sender.deliverDue()
+		if got := receiver.count(); got != i+1 {
+			t.Fatalf("попытка %d: запросов %d, want %d", i+1, got, i+1)
+		}
+
+		deliveries := listDeliveries(t, db)
+		if len(deliveries) != 1 {
+			t.Fatalf("доставок %d, want 1", len(deliveries))
+		}
+		d := deliveries[0]
+		if d.Status != models.WebhookPending || d.Attempts != i+1 || d.StatusCode != retry.status || d.LastError == "" {
+			t.Errorf("попытка %d: доставка %+v, want pending с кодом %d", i+1, d, retry.status)
+		}
+		if d.NextAttemptAt.Before(before.Add(retry.delay)) || d.NextAttemptAt.After(after.Add(retry.delay)) {
+			t.Errorf("попытка %d: следующая через %s, want %s", i+1, d.NextAttemptAt.Sub(before), retry.delay)
+		}
+		if state := webhookState(t, db); state.ConsecutiveFailures != i+1 {
+			t.Errorf("попытка %d: неудач подряд %d, want %d", i+1, state.ConsecutiveFailures, i+1)
+		}
+		makeDue(t, db)
+	}
+
+	sender.deliverDue()
+	d := listDeliveries(t, db)[0]
+	if d.Status != models.WebhookDelivered || d.DeliveredAt == nil || d.Attempts != 4 || d.StatusCode != http.StatusOK {
+		t.Errorf("доставка %+v, want delivered с 4 попытками", d)
+	}
+	if state := webhookState(t, db); state.ConsecutiveFailures != 0 {
+		t.Errorf("неудач подряд после доставки %d, want 0", state.ConsecutiveFailures)
+	}
+
+	// В теле только публичные поля события
+	var payload struct {
+		Type events.Type    `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for field := range payload.Data {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	if want := []string{"player_id", "server", "timestamp", "username"}; payload.Type != events.TypePlayerLogin || !slices.Equal(fields, want) {
+		t.Errorf("тело %s, want %s с полями %v", receiver.bodies[0], events.TypePlayerLogin, want)
+	}
+
+	// Повторы — тот же запрос доставки
+	for i, header := range receiver.requests {
+		if got := header.Get(webhook.HeaderDelivery); got != strconv.FormatUint(uint64(d.ID), 10) {
+			t.Errorf("запрос %d: %s = %q, want %d", i+1, webhook.HeaderDelivery, got, d.ID)
+		}
+		if got := header.Get(webhook.HeaderEvent); got != string(events.TypePlayerLogin) {
+			t.Errorf("запрос %d: %s = %q", i+1, webhook.HeaderEvent, got)
+		}
+	}
+}
+
+func TestWebhookRejectedWithoutRetry(t *testing.T) {
+	sender, receiver, db := newTestSender(t, 50, testResponse{status: http.StatusBadRequest})
+	sender.enqueue(testLogin("Steve"))
+
+	sender.deliverDue()
+	makeDue(t, db)
+	sender.deliverDue()
+
+	if got := receiver.count(); got != 1 {
+		t.Errorf("запросов %d, want 1", got)
+	}
+	d := listDeliveries(t, db)[0]
+	if d.Status != models.WebhookFailed || d.StatusCode != http.StatusBadRequest || d.LastError != "HTTP 400: Bad Request" {
+		t.Errorf("доставка %+v, want failed с кодом 400", d)
+	}
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	const disableAfter = 3
+	sender, receiver, db := newTestSender(t, disableAfter, testResponse{status: http.StatusInternalServerError})
+	sender.enqueue(testLogin("Steve"))
+	sender.enqueue(testLogin("Alex"))
+
+	for pass := 1; pass <= disableAfter; pass++ {
+		sender.deliverDue()
+		// Недоступному получателю — одна попытка за проход
+		if got := receiver.count(); got != pass {
+			t.Fatalf("проход %d: запросов %d, want %d", pass, got, pass)
+		}
+		makeDue(t, db)
+	}
+
+	state := webhookState(t, db)
+	if state.DisabledAt == nil || state.ConsecutiveFailures != disableAfter || state.DisabledReason == "" {
+		t.Fatalf("состояние %+v, want отключён после %d неудач", state, disableAfter)
+	}
+	for _, d := range listDeliveries(t, db) {
+		if d.Status != models.WebhookFailed || d.LastError == "" {
+			t.Errorf("доставка %d: %s %q, want failed с ошибкой", d.ID, d.Status, d.LastError)
+		}
+	}
+
+	// Отключённому вебхуку новые события не ставятся в очередь и не отправляются
+	sender.enqueue(testLogin("Herobrine"))
+	sender.deliverDue()
+	if got := len(listDeliveries(t, db)); got != 2 {
+		t.Errorf("доставок %d, want 2", got)
+	}
+	if got := receiver.count(); got != disableAfter {
+		t.Errorf("запросов %d, want %d", got, disableAfter)
+	}
+}
@@ -0,0 +1,209 @@
+// Package badge рисует значок и карточку состояния сервера для форумов и README:
+// SVG-значок в стиле shields.io и PNG-карточку с игроками, версией и временем работы
+package badge
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Status — состояние сервера, которое показывают значок и карточка
+type Status struct {
+	Server     string
+	Running    bool
+	Online     int
+	MaxPlayers int      // 0 — вместимость неизвестна, показывается только число игроков
+	Players    []string // ники для показа; пусто, если их скрывают
+	Version    string
+	Uptime     time.Duration // 0 — время запуска неизвестно
+}
+
+// Style — оформление; цвета задаются в виде #rgb или #rrggbb
+type Style struct {
+	Label        string // подпись левой части значка
+	LabelColor   string
+	OnlineColor  string
+	OfflineColor string
+	Height       int // высота значка в пикселях
+
+	CardWidth      int
+	CardBackground string
+	CardText       string
+	MaxNames       int // сколько ников показывает карточка, остальные — числом
+}
+
+// Допустимые размеры: меньше текст не читается, больше — не нужно для виджета
+const (
+	MinHeight    = 14
+	MaxHeight    = 80
+	MinCardWidth = 240
+	MaxCardWidth = 1000
+)
+
+var colorRe = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ValidColor проверяет, что цвет записан в виде #rgb или #rrggbb
+func ValidColor(value string) bool {
+	return colorRe.MatchString(value)
+}
+
+// normalizeColor возвращает цвет с ведущим #; неверный цвет заменяется fallback
+func normalizeColor(value, fallback string) string {
+	if !ValidColor(value) {
+		value = fallback
+	}
+	return "#" + strings.TrimPrefix(value, "#")
+}
+
+func parseColor(value string) color.RGBA {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	n, _ := strconv.ParseUint(hex, 16, 32)
+	return color.RGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 0xff}
+}
+
+// statusColor — цвет правой части значка и индикатора карточки
+func (s Style) statusColor(status Status) string {
+	if status.Running {
+		return normalizeColor(s.OnlineColor, "#4c1")
+	}
+	return normalizeColor(s.OfflineColor, "#9f9f9f")
+}
+
+// OnlineText — «3/20 онлайн», «3 онлайн» или «офлайн»
+func (st Status) OnlineText() string {
+	if !st.Running {
+		return "офлайн"
+	}
+	if st.MaxPlayers > 0 {
+		return fmt.Sprintf("%d/%d онлайн", st.Online, st.MaxPlayers)
+	}
+	return fmt.Sprintf("%d онлайн", st.Online)
+}
+
+// UptimeText — время работы с точностью до минуты, пусто, если оно неизвестно
+func (st Status) UptimeText() string {
+	if !st.Running || st.Uptime <= 0 {
+		return ""
+	}
+	minutes := int(st.Uptime / time.Minute)
+	days, hours, minutes := minutes/(24*60), minutes/60%24, minutes%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d д %d ч", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%d ч %d мин", hours, minutes)
+	default:
+		return fmt.Sprintf("%d мин", minutes)
+	}
+}
+
+// Шрифты Go встроены в бинарник и содержат кириллицу, поэтому картинки не зависят от системы
+var fonts = sync.OnceValues(func() (map[bool]*opentype.Font, error) {
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return map[bool]*opentype.Font{false: regular, true: bold}, nil
+})
+
+// newFace создаёт начертание; оно не потокобезопасно, поэтому своё у каждой отрисовки
+func newFace(size float64, bold bool) (font.Face, error) {
+	parsed, err := fonts()
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(parsed[bold], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+func measure(face font.Face, text string) float64 {
+	return fixed26ToFloat(font.MeasureString(face, text))
+}
+
+func fixed26ToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
+
+// Значок рисуется в координатах высоты 20 и масштабируется атрибутами width/height
+const (
+	badgeBaseHeight = 20
+	badgeFontSize   = 11
+	badgePadding    = 6
+)
+
+// SVG рисует значок «<подпись> | 3/20 онлайн»; в подсказке перечислены игроки
+func SVG(status Status, style Style) ([]byte, error) {
+	face, err := newFace(badgeFontSize, false)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	label := style.Label
+	if label == "" {
+		label = status.Server
+	}
+	value := status.OnlineText()
+	labelWidth := measure(face, label) + 2*badgePadding
+	valueWidth := measure(face, value) + 2*badgePadding
+	width := labelWidth + valueWidth
+
+	height := style.Height
+	if height == 0 {
+		height = badgeBaseHeight
+	}
+	height = min(max(height, MinHeight), MaxHeight)
+	scale := float64(height) / badgeBaseHeight
+
+	title := label + ": " + value
+	if len(status.Players) > 0 {
+		title += " — " + strings.Join(status.Players, ", ")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%d" viewBox="0 0 %.1f %d" role="img" aria-label="%s">`,
+		width*scale, height, width, badgeBaseHeight, escape(label+": "+value))
+	fmt.Fprintf(&buf, `<title>%s</title>`, escape(title))
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%.1f" height="%d" rx="3" fill="#fff"/></clipPath>`, width, badgeBaseHeight)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%.1f" height="%d" fill="%s"/><rect x="%.1f" width="%.1f" height="%d" fill="%s"/><rect width="%.1f" height="%d" fill="url(#s)"/></g>`,
+		labelWidth, badgeBaseHeight, normalizeColor(style.LabelColor, "#555"),
+		labelWidth, valueWidth, badgeBaseHeight, style.statusColor(status),
+		width, badgeBaseHeight)
+	fmt.Fprintf(&buf, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="%d">`, badgeFontSize)
+	for _, part := range []struct {
+		x    float64
+		text string
+	}{{labelWidth / 2, label}, {labelWidth + valueWidth/2, value}} {
+		// Тень на пиксель ниже, как у shields.io
+		fmt.Fprintf(&buf, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`,
+			part.x, escape(part.text), part.x, escape(part.text))
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes(), nil
+}
+
+func escape(s string) string {
+	var buf strings.Builder
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
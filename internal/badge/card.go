@@ -0,0 +1,186 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Размеры карточки в пикселях
+const (
+	cardPadding   = 16
+	cardAccent    = 6 // полоса цвета состояния у левого края
+	cardTitleSize = 20
+	cardTextSize  = 14
+	cardLineGap   = 8
+	cardMaxLines  = 3 // строк со списком игроков
+)
+
+// PNG рисует карточку: имя сервера, состояние, число игроков, версию, время работы и ники
+func PNG(status Status, style Style) ([]byte, error) {
+	titleFace, err := newFace(cardTitleSize, true)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	textFace, err := newFace(cardTextSize, false)
+	if err != nil {
+		return nil, err
+	}
+	defer textFace.Close()
+
+	width := style.CardWidth
+	if width == 0 {
+		width = 400
+	}
+	width = min(max(width, MinCardWidth), MaxCardWidth)
+	background := parseColor(normalizeColor(style.CardBackground, "#1f2227"))
+	foreground := parseColor(normalizeColor(style.CardText, "#e6e8eb"))
+	muted := blend(foreground, background, 0.6)
+	accent := parseColor(style.statusColor(status))
+
+	left := cardAccent + cardPadding
+	textWidth := float64(width - left - cardPadding)
+
+	// Строки карточки собираются заранее: от их числа зависит высота
+	type line struct {
+		text  string
+		face  font.Face
+		color color.Color
+		dot   bool
+	}
+	lines := []line{
+		{fit(titleFace, status.Server, textWidth), titleFace, foreground, false},
+		{status.OnlineText(), textFace, foreground, true},
+	}
+	var details []string
+	if status.Version != "" {
+		details = append(details, "версия "+status.Version)
+	}
+	if uptime := status.UptimeText(); uptime != "" {
+		details = append(details, "работает "+uptime)
+	}
+	if len(details) > 0 {
+		lines = append(lines, line{fit(textFace, strings.Join(details, " · "), textWidth), textFace, muted, false})
+	}
+	for _, names := range wrapNames(textFace, status.Players, style.MaxNames, textWidth) {
+		lines = append(lines, line{names, textFace, foreground, false})
+	}
+
+	height := cardPadding
+	for _, l := range lines {
+		height += lineHeight(l.face) + cardLineGap
+	}
+	height += cardPadding - cardLineGap
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, cardAccent, height), image.NewUniform(accent), image.Point{}, draw.Src)
+
+	y := cardPadding
+	for _, l := range lines {
+		metrics := l.face.Metrics()
+		baseline := y + metrics.Ascent.Ceil()
+		x := left
+		if l.dot {
+			radius := metrics.Ascent.Ceil() / 3
+			fillCircle(img, x+radius, baseline-metrics.Ascent.Ceil()/2+1, radius, accent)
+			x += 2*radius + 6
+		}
+		drawer := font.Drawer{Dst: img, Src: image.NewUniform(l.color), Face: l.face, Dot: fixed.P(x, baseline)}
+		drawer.DrawString(l.text)
+		y += lineHeight(l.face) + cardLineGap
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func lineHeight(face font.Face) int {
+	metrics := face.Metrics()
+	return (metrics.Ascent + metrics.Descent).Ceil()
+}
+
+// wrapNames раскладывает ники по строкам шириной width, не больше cardMaxLines строк.
+// Ники сверх maxNames и не поместившиеся показываются числом: «и ещё 5».
+func wrapNames(face font.Face, names []string, maxNames int, width float64) []string {
+	shown := names
+	if maxNames > 0 && len(shown) > maxNames {
+		shown = shown[:maxNames]
+	}
+
+	var rows [][]string
+	placed := 0
+	for _, name := range shown {
+		last := len(rows) - 1
+		if last >= 0 && measure(face, strings.Join(append(rows[last], name), ", ")) <= width {
+			rows[last] = append(rows[last], name)
+		} else if len(rows) < cardMaxLines {
+			rows = append(rows, []string{name})
+		} else {
+			break
+		}
+		placed++
+	}
+
+	// Остаток дописывается в последнюю строку, при нехватке места — вместо последних ников
+	if rest := len(names) - placed; rest > 0 && len(rows) > 0 {
+		last := len(rows) - 1
+		for {
+			more := fmt.Sprintf("и ещё %d", rest)
+			if measure(face, strings.Join(append(rows[last], more), ", ")) <= width || len(rows[last]) == 1 {
+				rows[last] = append(rows[last], more)
+				break
+			}
+			rows[last] = rows[last][:len(rows[last])-1]
+			rest++
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strings.Join(row, ", ")
+		if i < len(rows)-1 {
+			lines[i] += ","
+		}
+		lines[i] = fit(face, lines[i], width)
+	}
+	return lines
+}
+
+// fit укорачивает текст с многоточием, чтобы он поместился в width
+func fit(face font.Face, text string, width float64) string {
+	if measure(face, text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && measure(face, string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+func blend(a, b color.RGBA, ratio float64) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8(float64(x)*ratio + float64(y)*(1-ratio)) }
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 0xff}
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r int, c color.RGBA) {
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if x*x+y*y <= r*r {
+				img.SetRGBA(cx+x, cy+y, c)
+			}
+		}
+	}
+}
@@ -1,91 +1,761 @@
 package config
 
 import (
-	"errors"
-	// "fmt"
+	"fmt"
 	"log"
+	"mine-parser/internal/badge"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"net/url"
 	"os"
-	// "path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Config — итоговая конфигурация: значения по умолчанию, поверх них файл конфигурации,
+// поверх файла — переменные окружения
 type Config struct {
-	App AppConfig
-	Db  DbConfig
-	Tg  TelegramCongig
+	App      AppConfig      `yaml:"app"`
+	Db       DbConfig       `yaml:"db"`
+	Tg       TelegramCongig `yaml:"telegram"`
+	Health   HealthConfig   `yaml:"health"`
+	API      APIConfig      `yaml:"api"`
+	UI       UIConfig       `yaml:"ui"`
+	Badge    BadgeConfig    `yaml:"badge"`
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	Discord  DiscordConfig  `yaml:"discord"`
+	Servers  []ServerConfig `yaml:"servers"`
+	Texts    BotTexts       `yaml:"texts"`
+
+	file    string  // путь к файлу, из которого загружена конфигурация; пусто, если файла нет
+	options Options // параметры загрузки, с которыми конфигурация перечитывается
+}
+
+// Options — параметры командной строки, влияющие на загрузку конфигурации
+type Options struct {
+	File     string // путь к файлу конфигурации; пусто — CONFIG_FILE или config.yaml, если он есть
+	LogLevel string // уровень логирования; непустое значение важнее файла и LOG_LEVEL
 }
 
+// SQLiteScheme — префикс DATABASE_URL для хранения данных в файле SQLite вместо Postgres
+const SQLiteScheme = "sqlite://"
+
+// DefaultFile — файл конфигурации, который читается, если CONFIG_FILE не задан
+const DefaultFile = "config.yaml"
+
 type DbConfig struct {
-	Dsn string
+	Dsn           string        `yaml:"dsn"`
+	AutoMigrate   bool          `yaml:"auto_migrate"`         // применять миграции при запуске
+	QueryTimeout  time.Duration `yaml:"query_timeout"`        // максимальное время одного запроса
+	SlowThreshold time.Duration `yaml:"slow_query_threshold"` // запросы дольше порога пишутся в лог
 }
 
 type AppConfig struct {
-	Port      string
-	ParsePath string
+	Port string `yaml:"port"` // порт HTTP-сервера; пусто — сервер не запускается
+	// Минимальный уровень сообщений в логе: debug, info, warn или error
+	LogLevel   string        `yaml:"log_level"`
+	Level      logging.Level `yaml:"-"` // загружается из LogLevel при проверке
+	ParsePath  string        `yaml:"log_path"`
+	ServerName string        `yaml:"server_name"` // имя сервера, чей лог разбирается
+	// Часовой пояс, в котором время показывается пользователям (в БД хранится реальное время)
+	Timezone string         `yaml:"timezone"`
+	Location *time.Location `yaml:"-"` // загружается из Timezone при проверке
+	// Параметры конвейера записи событий
+	IngestBatchSize     int           `yaml:"ingest_batch_size"`
+	IngestFlushInterval time.Duration `yaml:"ingest_flush_interval"`
+	BackfillBatchSize   int           `yaml:"backfill_batch_size"`
+	IngestWriteTimeout  time.Duration `yaml:"ingest_write_timeout"` // максимальное время записи одного батча
+	// Каталог для событий, не поместившихся в буферы подписчиков шины
+	EventSpoolDir string `yaml:"event_spool_dir"`
 }
 
 type TelegramCongig struct {
-	Token string
+	Token    string  `yaml:"token"`
+	AdminIDs []int64 `yaml:"admin_ids"` // пользователи Telegram с доступом к служебным экранам
+	// Доставка уведомлений из очереди исходящих сообщений
+	NotifyTTL         time.Duration `yaml:"notify_ttl"` // после этого срока неотправленное уведомление считается устаревшим
+	NotifyMaxAttempts int           `yaml:"notify_max_attempts"`
+	HandlerTimeout    time.Duration `yaml:"handler_timeout"` // максимальное время обработки одного обновления
+}
+
+// HealthConfig — пороги проверок /healthz и /readyz. Превышение порога warn
+// даёт состояние degraded, порога fail — failed; нулевой порог отключает проверку.
+type HealthConfig struct {
+	CheckTimeout   time.Duration `yaml:"check_timeout"`   // максимальное время одной проверки
+	DbLatencyWarn  time.Duration `yaml:"db_latency_warn"` // время ответа БД на ping
+	TailLagWarn    int64         `yaml:"tail_lag_warn"`   // байт не прочитано до конца лога
+	TailLagFail    int64         `yaml:"tail_lag_fail"`
+	TailStallFail  time.Duration `yaml:"tail_stall_fail"`  // лог не дочитан и чтение не продвигается
+	EventStaleWarn time.Duration `yaml:"event_stale_warn"` // давно не было распознанных событий
+	PollStaleWarn  time.Duration `yaml:"poll_stale_warn"`  // давно не было успешного опроса Telegram
+	PollStaleFail  time.Duration `yaml:"poll_stale_fail"`
+	QueueWarn      int64         `yaml:"queue_warn"` // уведомлений ожидает отправки
+	QueueFail      int64         `yaml:"queue_fail"`
+	// HTTP-коды ответов; при состоянии ok всегда 200
+	DegradedStatusCode int `yaml:"degraded_status_code"`
+	FailedStatusCode   int `yaml:"failed_status_code"`
+}
+
+// APIConfig — REST API /api/v1 на порту HTTP-сервера
+type APIConfig struct {
+	// Токены доступа (заголовок Authorization: Bearer <токен>); без токенов API отвечает 401
+	Tokens []string `yaml:"tokens"`
+	// Чтение без токена — для веб-интерфейса, открытого всем игрокам
+	Public          bool `yaml:"public"`
+	DefaultPageSize int  `yaml:"default_page_size"` // записей на странице, если limit не указан
+	MaxPageSize     int  `yaml:"max_page_size"`
+	// Поток событий /api/v1/events/stream
+	StreamMaxConnections int `yaml:"stream_max_connections"` // одновременных подключений
+	StreamReplaySize     int `yaml:"stream_replay_size"`     // событий хранится для возобновления по Last-Event-ID
+}
+
+// UIConfig — веб-интерфейс, встроенный в бинарник; работает поверх REST API
+type UIConfig struct {
+	Enabled bool `yaml:"enabled"` // отдавать интерфейс на корне HTTP-сервера
 }
 
-func Load() (*Config, error) {
+// BadgeConfig — значок /badge.svg и карточка /card.png с состоянием сервера для форумов и README.
+// Цвета задаются в виде #rgb или #rrggbb; пустой цвет — стандартный.
+type BadgeConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Label          string `yaml:"label"` // подпись значка; пусто — имя сервера
+	LabelColor     string `yaml:"label_color"`
+	OnlineColor    string `yaml:"online_color"`
+	OfflineColor   string `yaml:"offline_color"`
+	Height         int    `yaml:"height"`     // высота значка в пикселях
+	CardWidth      int    `yaml:"card_width"` // ширина карточки в пикселях
+	CardBackground string `yaml:"card_background"`
+	CardText       string `yaml:"card_text"`
+	// Ники онлайн в подсказке значка и на карточке
+	ShowPlayers    bool `yaml:"show_players"`
+	MaxPlayerNames int  `yaml:"max_player_names"` // остальные показываются числом; 0 — без ограничения
+	// Игроки (ники или UUID), которых не показывают и не считают: администраторы, боты
+	HiddenPlayers []string      `yaml:"hidden_players"`
+	CacheMaxAge   time.Duration `yaml:"cache_max_age"` // сколько форумы и CDN могут не перепроверять картинку
+}
+
+// WebhooksConfig — исходящие вебхуки: события в JSON, подписанные HMAC-SHA256 секретом получателя
+type WebhooksConfig struct {
+	Timeout     time.Duration `yaml:"timeout"`      // ожидание ответа на один запрос
+	MaxAttempts int           `yaml:"max_attempts"` // попыток доставки одного события
+	// Неудачных попыток подряд, после которых вебхук отключается до включения администратором; 0 — не отключать
+	DisableAfter int             `yaml:"disable_after"`
+	LogRetention time.Duration   `yaml:"log_retention"` // сколько хранить завершённые доставки в журнале
+	Endpoints    []WebhookConfig `yaml:"endpoints"`
+}
+
+// WebhookConfig — получатель событий
+type WebhookConfig struct {
+	Name    string   `yaml:"name"` // имя в журнале доставки и заголовке X-Mclog-Webhook
+	URL     string   `yaml:"url"`
+	Secret  string   `yaml:"secret"`  // ключ подписи X-Mclog-Signature
+	Events  []string `yaml:"events"`  // типы событий; пусто — все
+	Servers []string `yaml:"servers"` // серверы; пусто — все
+}
+
+// Matches сообщает, нужно ли отправлять вебхуку событие типа eventType с сервера server
+func (w WebhookConfig) Matches(eventType, server string) bool {
+	return (len(w.Events) == 0 || slices.Contains(w.Events, eventType)) &&
+		(len(w.Servers) == 0 || slices.Contains(w.Servers, server))
+}
+
+// Endpoint возвращает вебхук по имени
+func (w WebhooksConfig) Endpoint(name string) (WebhookConfig, bool) {
+	for _, endpoint := range w.Endpoints {
+		if endpoint.Name == name {
+			return endpoint, true
+		}
+	}
+	return WebhookConfig{}, false
+}
+
+var webhookNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// minWebhookSecretLength — минимальная длина секрета подписи вебхука
+const minWebhookSecretLength = 16
+
+// DiscordConfig — уведомления в каналы Discord через входящие вебхуки
+type DiscordConfig struct {
+	Username string `yaml:"username"` // имя отправителя; пусто — имя из настроек вебхука в Discord
+	// Шаблон адреса аватара игрока в уведомлении, поля .PlayerID и .Username
+	AvatarURL string                 `yaml:"avatar_url"`
+	Timeout   time.Duration          `yaml:"timeout"` // ожидание ответа Discord на одно сообщение
+	Channels  []DiscordChannelConfig `yaml:"channels"`
+}
+
+// DiscordChannelConfig — канал Discord. Подписку, события и черный список канала
+// администратор меняет в боте; здесь — настройки до первого изменения.
+type DiscordChannelConfig struct {
+	Name       string   `yaml:"name"`        // имя в боте и очереди уведомлений
+	WebhookURL string   `yaml:"webhook_url"` // https://discord.com/api/webhooks/<id>/<токен>
+	Events     []string `yaml:"events"`      // типы событий; пусто — только вход игроков
+}
+
+// DiscordEventTypes — события, о которых можно уведомлять канал Discord
+var DiscordEventTypes = []events.Type{
+	events.TypePlayerLogin,
+	events.TypePlayerLogout,
+	events.TypePlayerDied,
+	events.TypeAdvancementEarned,
+	events.TypeChatMessage,
+	events.TypeServerStarted,
+	events.TypeServerStopping,
+}
+
+// DefaultEvents возвращает типы событий канала до изменения администратором
+func (c DiscordChannelConfig) DefaultEvents() []string {
+	if len(c.Events) == 0 {
+		return []string{string(events.TypePlayerLogin)}
+	}
+	return c.Events
+}
+
+// Channel возвращает канал Discord по имени
+func (d DiscordConfig) Channel(name string) (DiscordChannelConfig, bool) {
+	for _, channel := range d.Channels {
+		if channel.Name == name {
+			return channel, true
+		}
+	}
+	return DiscordChannelConfig{}, false
+}
+
+// Имя канала входит в данные кнопок бота, ограниченные 64 байтами, поэтому оно короче имени вебхука
+var discordChannelNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,15}$`)
+
+// minAPITokenLength — минимальная длина токена API, чтобы его нельзя было подобрать
+const minAPITokenLength = 16
+
+// ServerConfig — сервер Minecraft, о котором рассказывает бот
+type ServerConfig struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"` // адрес для подключения из клиента
+	Version string `yaml:"version"` // версия клиента Minecraft
+	MapURL  string `yaml:"map_url"` // веб-карта мира; пусто, если карты нет
+	// Вместимость сервера (max-players из server.properties); 0 — не показывать
+	MaxPlayers int `yaml:"max_players"`
+}
+
+// IsAdmin проверяет, есть ли пользователь в списке администраторов
+func (c TelegramCongig) IsAdmin(userID int64) bool {
+	for _, id := range c.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryServer возвращает сервер, чей лог разбирается, или первый из списка
+func (c *Config) PrimaryServer() ServerConfig {
+	for _, server := range c.Servers {
+		if server.Name == c.App.ServerName {
+			return server
+		}
+	}
+	if len(c.Servers) > 0 {
+		return c.Servers[0]
+	}
+	return ServerConfig{Name: c.App.ServerName}
+}
+
+// File возвращает путь к файлу конфигурации или пустую строку, если он не использовался
+func (c *Config) File() string {
+	return c.file
+}
+
+// Load читает .env, файл конфигурации (из options, CONFIG_FILE или config.yaml, если он есть)
+// и переменные окружения
+func Load(options Options) (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Файл .env не найден — используем переменные окружения")
 	}
+	return load(options)
+}
+
+func load(options Options) (*Config, error) {
+	config := defaults()
+	config.options = options
+
+	path, required := options.File, options.File != ""
+	if !required {
+		path, required = os.LookupEnv("CONFIG_FILE")
+	}
+	if !required {
+		path = DefaultFile
+	}
+	if path != "" {
+		found, err := config.readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !found && required {
+			return nil, fmt.Errorf("файл конфигурации %s не найден", path)
+		}
+		if found {
+			config.file = path
+		}
+	}
+
+	var problems problemList
+	config.applyEnv(&problems)
+	if options.LogLevel != "" {
+		config.App.LogLevel = options.LogLevel
+	}
+	config.validate(&problems)
+	if err := problems.err(config.file); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
 
-	config := &Config{
+func defaults() *Config {
+	return &Config{
 		App: AppConfig{
-			Port:      getEnv("PORT", "8081"),
-			ParsePath: getEnv("LOG_PATH", ""),
+			Port:       "8081",
+			LogLevel:   "info",
+			ServerName: "main",
+			Timezone:   "Europe/Moscow",
+
+			IngestBatchSize:     100,
+			IngestFlushInterval: time.Second,
+			BackfillBatchSize:   2000,
+			IngestWriteTimeout:  time.Minute,
+
+			EventSpoolDir: "spool",
 		},
 		Db: DbConfig{
-			Dsn: getEnv("DATABASE_URL", ""),
+			AutoMigrate:   true,
+			QueryTimeout:  5 * time.Second,
+			SlowThreshold: 200 * time.Millisecond,
 		},
 		Tg: TelegramCongig{
-			Token: getEnv("TG_TOKEN", ""),
+			NotifyTTL:         30 * time.Minute,
+			NotifyMaxAttempts: 8,
+			HandlerTimeout:    15 * time.Second,
 		},
-	}
+		Health: HealthConfig{
+			CheckTimeout:   2 * time.Second,
+			DbLatencyWarn:  250 * time.Millisecond,
+			TailLagWarn:    1 << 20,
+			TailLagFail:    64 << 20,
+			TailStallFail:  2 * time.Minute,
+			EventStaleWarn: 6 * time.Hour,
+			PollStaleWarn:  3 * time.Minute,
+			PollStaleFail:  10 * time.Minute,
+			QueueWarn:      50,
+			QueueFail:      500,
 
-	if err := config.validate(); err != nil {
-		return nil, err
+			DegradedStatusCode: 299,
+			FailedStatusCode:   503,
+		},
+		API: APIConfig{
+			DefaultPageSize:      50,
+			MaxPageSize:          500,
+			StreamMaxConnections: 100,
+			StreamReplaySize:     1000,
+		},
+		UI: UIConfig{
+			Enabled: true,
+		},
+		Badge: BadgeConfig{
+			Enabled:        true,
+			Height:         20,
+			CardWidth:      400,
+			ShowPlayers:    true,
+			MaxPlayerNames: 10,
+			CacheMaxAge:    time.Minute,
+		},
+		Servers: []ServerConfig{{
+			Name:    "main",
+			Address: "89.169.161.207",
+			Version: "1.21.4",
+			MapURL:  "https://minecraft.shapedby.ru",
+
+			MaxPlayers: 20,
+		}},
+		Webhooks: WebhooksConfig{
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			DisableAfter: 50,
+			LogRetention: 14 * 24 * time.Hour,
+		},
+		Discord: DiscordConfig{
+			AvatarURL: "https://mc-heads.net/avatar/{{.PlayerID}}/64",
+			Timeout:   10 * time.Second,
+		},
+		Texts: defaultTexts(),
 	}
+}
 
-	return config, nil
+// applyEnv переопределяет значения переменными окружения, заданными явно
+func (c *Config) applyEnv(problems *problemList) {
+	env := envReader{problems: problems}
+
+	env.string(&c.App.Port, "PORT")
+	env.string(&c.App.LogLevel, "LOG_LEVEL")
+	env.string(&c.App.ParsePath, "LOG_PATH")
+	env.string(&c.App.ServerName, "SERVER_NAME")
+	env.string(&c.App.Timezone, "TIMEZONE")
+	env.int(&c.App.IngestBatchSize, "INGEST_BATCH_SIZE")
+	env.duration(&c.App.IngestFlushInterval, "INGEST_FLUSH_INTERVAL")
+	env.int(&c.App.BackfillBatchSize, "BACKFILL_BATCH_SIZE")
+	env.duration(&c.App.IngestWriteTimeout, "INGEST_WRITE_TIMEOUT")
+	env.string(&c.App.EventSpoolDir, "EVENT_SPOOL_DIR")
+
+	env.string(&c.Db.Dsn, "DATABASE_URL")
+	env.bool(&c.Db.AutoMigrate, "DB_AUTO_MIGRATE")
+	env.duration(&c.Db.QueryTimeout, "DB_QUERY_TIMEOUT")
+	env.duration(&c.Db.SlowThreshold, "DB_SLOW_QUERY_THRESHOLD")
+
+	env.string(&c.Tg.Token, "TG_TOKEN")
+	env.int64List(&c.Tg.AdminIDs, "TG_ADMIN_IDS")
+	env.duration(&c.Tg.NotifyTTL, "TG_NOTIFY_TTL")
+	env.int(&c.Tg.NotifyMaxAttempts, "TG_NOTIFY_MAX_ATTEMPTS")
+	env.duration(&c.Tg.HandlerTimeout, "TG_HANDLER_TIMEOUT")
+
+	env.stringList(&c.API.Tokens, "API_TOKENS")
+	env.bool(&c.API.Public, "API_PUBLIC")
+
+	env.bool(&c.UI.Enabled, "UI_ENABLED")
+
+	env.bool(&c.Badge.Enabled, "BADGE_ENABLED")
+	env.stringList(&c.Badge.HiddenPlayers, "BADGE_HIDDEN_PLAYERS")
 }
 
-func (c *Config) validate() error {
+// validate проверяет значения и собирает все найденные ошибки
+func (c *Config) validate(problems *problemList) {
 	if c.Db.Dsn == "" {
-		return errors.New("DSN is required")
+		problems.add("db.dsn (DATABASE_URL)", "обязательный параметр")
+	}
+	if strings.HasPrefix(c.Db.Dsn, SQLiteScheme) && strings.TrimPrefix(c.Db.Dsn, SQLiteScheme) == "" {
+		problems.add("db.dsn (DATABASE_URL)", "для SQLite нужен путь к файлу: sqlite://path.db")
 	}
-	return nil
+	if c.App.ServerName == "" {
+		problems.add("app.server_name (SERVER_NAME)", "не может быть пустым")
+	}
+
+	level, err := logging.ParseLevel(c.App.LogLevel)
+	if err != nil {
+		problems.add("app.log_level (LOG_LEVEL)", err.Error())
+	}
+	c.App.Level = level
+
+	location, err := time.LoadLocation(c.App.Timezone)
+	if err != nil {
+		problems.add("app.timezone (TIMEZONE)", fmt.Sprintf("неизвестный часовой пояс %q", c.App.Timezone))
+		location = time.UTC
+	}
+	c.App.Location = location
+
+	positive := []struct {
+		field string
+		value int64
+	}{
+		{"app.ingest_batch_size (INGEST_BATCH_SIZE)", int64(c.App.IngestBatchSize)},
+		{"app.ingest_flush_interval (INGEST_FLUSH_INTERVAL)", int64(c.App.IngestFlushInterval)},
+		{"app.backfill_batch_size (BACKFILL_BATCH_SIZE)", int64(c.App.BackfillBatchSize)},
+		{"app.ingest_write_timeout (INGEST_WRITE_TIMEOUT)", int64(c.App.IngestWriteTimeout)},
+		{"telegram.notify_ttl (TG_NOTIFY_TTL)", int64(c.Tg.NotifyTTL)},
+		{"telegram.notify_max_attempts (TG_NOTIFY_MAX_ATTEMPTS)", int64(c.Tg.NotifyMaxAttempts)},
+		{"telegram.handler_timeout (TG_HANDLER_TIMEOUT)", int64(c.Tg.HandlerTimeout)},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			problems.add(p.field, "должно быть больше нуля")
+		}
+	}
+	if c.Db.QueryTimeout < 0 {
+		problems.add("db.query_timeout (DB_QUERY_TIMEOUT)", "не может быть отрицательным")
+	}
+	if c.Db.SlowThreshold < 0 {
+		problems.add("db.slow_query_threshold (DB_SLOW_QUERY_THRESHOLD)", "не может быть отрицательным")
+	}
+
+	c.Health.validate(problems)
+	c.API.validate(problems)
+	c.Badge.validate(problems)
+	c.Webhooks.validate(problems)
+	c.Discord.validate(problems)
+
+	names := make(map[string]bool, len(c.Servers))
+	for i, server := range c.Servers {
+		field := fmt.Sprintf("servers[%d]", i)
+		if server.Name == "" {
+			problems.add(field+".name", "не может быть пустым")
+		} else if names[server.Name] {
+			problems.add(field+".name", fmt.Sprintf("сервер %q указан дважды", server.Name))
+		}
+		names[server.Name] = true
+		if server.Address == "" {
+			problems.add(field+".address", "не может быть пустым")
+		}
+		if server.MapURL != "" && !strings.HasPrefix(server.MapURL, "http://") && !strings.HasPrefix(server.MapURL, "https://") {
+			problems.add(field+".map_url", "должен начинаться с http:// или https://")
+		}
+		if server.MaxPlayers < 0 {
+			problems.add(field+".max_players", "не может быть отрицательным")
+		}
+	}
+
+	c.Texts.validate(c, problems)
+}
+
+// problemList собирает ошибки конфигурации, чтобы показать их все сразу
+type problemList []string
+
+func (p *problemList) add(field, message string) {
+	*p = append(*p, field+": "+message)
+}
+
+func (p problemList) err(file string) error {
+	if len(p) == 0 {
+		return nil
+	}
+	source := "конфигурация"
+	if file != "" {
+		source = "конфигурация (" + file + ")"
+	}
+	return &ValidationError{Source: source, Problems: p}
+}
+
+// ValidationError перечисляет все ошибки конфигурации
+type ValidationError struct {
+	Source   string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("некорректная %s:\n  - %s", e.Source, strings.Join(e.Problems, "\n  - "))
 }
 
-func getEnv(key, fallback string) string {
+// envReader читает переменные окружения в поля конфигурации.
+// Незаданные переменные поля не меняют, некорректные — попадают в список ошибок.
+type envReader struct {
+	problems *problemList
+}
+
+func (r envReader) string(target *string, key string) {
 	if value, ok := os.LookupEnv(key); ok {
-		return value
+		*target = value
+	}
+}
+
+func (r envReader) int(target *int, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.problems.add(key, fmt.Sprintf("некорректное число %q", value))
+		return
+	}
+	*target = parsed
+}
+
+func (r envReader) duration(target *time.Duration, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		r.problems.add(key, fmt.Sprintf("некорректная длительность %q", value))
+		return
+	}
+	*target = parsed
+}
+
+func (r envReader) bool(target *bool, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.problems.add(key, fmt.Sprintf("некорректное логическое значение %q", value))
+		return
+	}
+	*target = parsed
+}
+
+// stringList читает список строк через запятую
+func (r envReader) stringList(target *[]string, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	var result []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	*target = result
+}
+
+// int64List читает список чисел через запятую
+func (r envReader) int64List(target *[]int64, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	var result []int64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			r.problems.add(key, fmt.Sprintf("некорректное значение %q", part))
+			continue
+		}
+		result = append(result, parsed)
+	}
+	*target = result
+}
+
+func (h HealthConfig) validate(problems *problemList) {
+	if h.CheckTimeout <= 0 {
+		problems.add("health.check_timeout", "должно быть больше нуля")
+	}
+	if h.TailLagFail > 0 && h.TailLagWarn > h.TailLagFail {
+		problems.add("health.tail_lag_warn", "больше tail_lag_fail")
+	}
+	if h.PollStaleFail > 0 && h.PollStaleWarn > h.PollStaleFail {
+		problems.add("health.poll_stale_warn", "больше poll_stale_fail")
+	}
+	if h.QueueFail > 0 && h.QueueWarn > h.QueueFail {
+		problems.add("health.queue_warn", "больше queue_fail")
+	}
+	if h.DegradedStatusCode < 200 || h.DegradedStatusCode > 599 {
+		problems.add("health.degraded_status_code", "должен быть HTTP-кодом от 200 до 599")
+	}
+	if h.FailedStatusCode < 200 || h.FailedStatusCode > 599 {
+		problems.add("health.failed_status_code", "должен быть HTTP-кодом от 200 до 599")
+	}
+}
+
+func (a APIConfig) validate(problems *problemList) {
+	for i, token := range a.Tokens {
+		if len(token) < minAPITokenLength {
+			problems.add(fmt.Sprintf("api.tokens[%d] (API_TOKENS)", i), fmt.Sprintf("токен короче %d символов", minAPITokenLength))
+		}
+	}
+	if a.DefaultPageSize <= 0 {
+		problems.add("api.default_page_size", "должно быть больше нуля")
+	}
+	if a.MaxPageSize < a.DefaultPageSize {
+		problems.add("api.max_page_size", "меньше default_page_size")
+	}
+	if a.StreamMaxConnections <= 0 {
+		problems.add("api.stream_max_connections", "должно быть больше нуля")
+	}
+	if a.StreamReplaySize <= 0 {
+		problems.add("api.stream_replay_size", "должно быть больше нуля")
+	}
+}
+
+func (b BadgeConfig) validate(problems *problemList) {
+	colors := []struct {
+		field string
+		value string
+	}{
+		{"badge.label_color", b.LabelColor},
+		{"badge.online_color", b.OnlineColor},
+		{"badge.offline_color", b.OfflineColor},
+		{"badge.card_background", b.CardBackground},
+		{"badge.card_text", b.CardText},
+	}
+	for _, c := range colors {
+		if c.value != "" && !badge.ValidColor(c.value) {
+			problems.add(c.field, fmt.Sprintf("цвет %q должен быть в виде #rgb или #rrggbb", c.value))
+		}
+	}
+	if b.Height < badge.MinHeight || b.Height > badge.MaxHeight {
+		problems.add("badge.height", fmt.Sprintf("должно быть от %d до %d", badge.MinHeight, badge.MaxHeight))
+	}
+	if b.CardWidth < badge.MinCardWidth || b.CardWidth > badge.MaxCardWidth {
+		problems.add("badge.card_width", fmt.Sprintf("должно быть от %d до %d", badge.MinCardWidth, badge.MaxCardWidth))
+	}
+	if b.MaxPlayerNames < 0 {
+		problems.add("badge.max_player_names", "не может быть отрицательным")
+	}
+	if b.CacheMaxAge < 0 {
+		problems.add("badge.cache_max_age", "не может быть отрицательным")
 	}
-	return fallback
 }
 
-// func dir(envFile string) string {
-// 	currentDir, err := os.Getwd()
-// 	if err != nil {
-// 		panic(err)
-// 	}
+func (d DiscordConfig) validate(problems *problemList) {
+	if d.Timeout <= 0 {
+		problems.add("discord.timeout", "должно быть больше нуля")
+	}
+	if d.AvatarURL != "" {
+		if _, err := Render(d.AvatarURL, AvatarView{PlayerID: "069a79f4-44e9-4726-a5be-fca90e38aaf5", Username: "Notch"}); err != nil {
+			problems.add("discord.avatar_url", fmt.Sprintf("ошибка в шаблоне: %v", err))
+		}
+	}
 
-// 	for {
-// 		goModPath := filepath.Join(currentDir, "go.mod")
-// 		if _, err := os.Stat(goModPath); err == nil {
-// 			break
-// 		}
+	names := make(map[string]bool, len(d.Channels))
+	for i, channel := range d.Channels {
+		field := fmt.Sprintf("discord.channels[%d]", i)
+		if !discordChannelNameRe.MatchString(channel.Name) {
+			problems.add(field+".name", "до 16 символов: строчные латинские буквы, цифры, _ и -")
+		} else if names[channel.Name] {
+			problems.add(field+".name", fmt.Sprintf("канал %q указан дважды", channel.Name))
+		}
+		names[channel.Name] = true
+		if u, err := url.Parse(channel.WebhookURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			problems.add(field+".webhook_url", "нужен адрес https:// вебхука канала")
+		}
+		for _, eventType := range channel.Events {
+			if !slices.Contains(DiscordEventTypes, events.Type(eventType)) {
+				problems.add(field+".events", fmt.Sprintf("тип события %q не поддерживается", eventType))
+			}
+		}
+	}
+}
 
-// 		parent := filepath.Dir(currentDir)
-// 		if parent == currentDir {
-// 			panic(fmt.Errorf("go.mod not found"))
-// 		}
-// 		currentDir = parent
-// 	}
+func (w WebhooksConfig) validate(problems *problemList) {
+	if w.Timeout <= 0 {
+		problems.add("webhooks.timeout", "должно быть больше нуля")
+	}
+	if w.MaxAttempts <= 0 {
+		problems.add("webhooks.max_attempts", "должно быть больше нуля")
+	}
+	if w.DisableAfter < 0 {
+		problems.add("webhooks.disable_after", "не может быть отрицательным")
+	}
+	if w.LogRetention <= 0 {
+		problems.add("webhooks.log_retention", "должно быть больше нуля")
+	}
 
-// 	return filepath.Join(currentDir, envFile)
-// }
+	names := make(map[string]bool, len(w.Endpoints))
+	for i, endpoint := range w.Endpoints {
+		field := fmt.Sprintf("webhooks.endpoints[%d]", i)
+		if !webhookNameRe.MatchString(endpoint.Name) {
+			problems.add(field+".name", "до 32 символов: строчные латинские буквы, цифры, _ и -")
+		} else if names[endpoint.Name] {
+			problems.add(field+".name", fmt.Sprintf("вебхук %q указан дважды", endpoint.Name))
+		}
+		names[endpoint.Name] = true
+		if u, err := url.Parse(endpoint.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems.add(field+".url", "нужен адрес http:// или https://")
+		}
+		if len(endpoint.Secret) < minWebhookSecretLength {
+			problems.add(field+".secret", fmt.Sprintf("секрет короче %d символов", minWebhookSecretLength))
+		}
+		for _, eventType := range endpoint.Events {
+			if !slices.Contains(events.AllTypes, events.Type(eventType)) {
+				problems.add(field+".events", fmt.Sprintf("неизвестный тип события %q", eventType))
+			}
+		}
+	}
+}
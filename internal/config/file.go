@@ -0,0 +1,31 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile накладывает значения из YAML-файла на текущие. Отсутствующий файл не ошибка:
+// found сообщает, был ли он прочитан. Неизвестные ключи считаются ошибкой,
+// чтобы опечатка в имени параметра не оставалась незамеченной.
+func (c *Config) readFile(path string) (found bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return true, fmt.Errorf("ошибка в файле конфигурации %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"mine-parser/internal/logging"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Store хранит действующую конфигурацию. Компоненты читают её через Get при каждом
+// использовании, поэтому перезагрузка применяется без перезапуска.
+type Store struct {
+	current atomic.Pointer[Config]
+	mu      sync.Mutex // перезагрузки выполняются по одной
+}
+
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get возвращает текущую конфигурацию. Возвращённое значение не меняется:
+// перезагрузка подменяет конфигурацию целиком.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload заново читает файл конфигурации и переменные окружения. При ошибке
+// действующая конфигурация остаётся прежней. Параметры, которые применяются
+// только при запуске, сохраняют прежние значения; их изменения перечисляются в логе.
+func (s *Store) Reload() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := load(s.Get().options)
+	if err != nil {
+		return nil, err
+	}
+	if ignored := keepStartupOnly(next, s.Get()); len(ignored) > 0 {
+		logging.Warnf("Изменения вступят в силу после перезапуска: %s", strings.Join(ignored, ", "))
+	}
+	s.current.Store(next)
+	return next, nil
+}
+
+// keepStartupOnly возвращает в next значения параметров, которые нельзя применить
+// на ходу, и перечисляет изменившиеся
+func keepStartupOnly(next, current *Config) []string {
+	var changed []string
+	keep(&changed, "app.port", &next.App.Port, current.App.Port)
+	keep(&changed, "app.log_path", &next.App.ParsePath, current.App.ParsePath)
+	keep(&changed, "app.server_name", &next.App.ServerName, current.App.ServerName)
+	keep(&changed, "app.ingest_batch_size", &next.App.IngestBatchSize, current.App.IngestBatchSize)
+	keep(&changed, "app.ingest_flush_interval", &next.App.IngestFlushInterval, current.App.IngestFlushInterval)
+	keep(&changed, "app.backfill_batch_size", &next.App.BackfillBatchSize, current.App.BackfillBatchSize)
+	keep(&changed, "app.ingest_write_timeout", &next.App.IngestWriteTimeout, current.App.IngestWriteTimeout)
+	keep(&changed, "app.event_spool_dir", &next.App.EventSpoolDir, current.App.EventSpoolDir)
+	keep(&changed, "db.dsn", &next.Db.Dsn, current.Db.Dsn)
+	keep(&changed, "db.auto_migrate", &next.Db.AutoMigrate, current.Db.AutoMigrate)
+	keep(&changed, "telegram.token", &next.Tg.Token, current.Tg.Token)
+	keep(&changed, "api.stream_replay_size", &next.API.StreamReplaySize, current.API.StreamReplaySize)
+	return changed
+}
+
+func keep[T comparable](changed *[]string, name string, next *T, current T) {
+	if *next != current {
+		*changed = append(*changed, name)
+		*next = current
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// BotTexts — тексты бота и уведомлений. Каждый текст — шаблон text/template:
+// экраны бота получают ServersView, уведомление о входе — JoinNotificationView.
+type BotTexts struct {
+	MainMenu         string `yaml:"main_menu"`
+	ConnectionGuide  string `yaml:"connection_guide"`
+	WorldMap         string `yaml:"world_map"`
+	JoinNotification string `yaml:"join_notification"`
+}
+
+// ServersView — данные для шаблонов экранов бота
+type ServersView struct {
+	Primary ServerConfig   // сервер, чей лог разбирается
+	Servers []ServerConfig // все серверы из конфигурации
+}
+
+// JoinNotificationView — данные для шаблона уведомления о входе игрока
+type JoinNotificationView struct {
+	Username string
+	Server   string
+	Online   int // игроков онлайн с учётом вошедшего
+}
+
+// AvatarView — данные для шаблона адреса аватара игрока
+type AvatarView struct {
+	PlayerID string
+	Username string
+}
+
+func defaultTexts() BotTexts {
+	return BotTexts{
+		MainMenu: "📊 Статистика сервера Minecraft\n\nВыберите раздел:",
+		ConnectionGuide: `📖 Инструкция по подключению к серверу
+
+Добро пожаловать на наш сервер Minecraft!
+
+Для подключения к серверу выполните следующие шаги:
+
+1️⃣ Скачайте и установите Minecraft версии {{.Primary.Version}}
+
+2️⃣ Запустите игру и выберите "Сетевая игра" (Multiplayer)
+
+3️⃣ Нажмите "Добавить сервер" (Add Server)
+
+4️⃣ Введите IP-адрес сервера:
+{{- range .Servers}}
+   🌐 {{.Address}}{{if gt (len $.Servers) 1}} — {{.Name}}{{end}}
+{{- end}}
+
+5️⃣ Нажмите "Готово" и подключитесь к серверу
+
+Удачной игры! 🎮`,
+		WorldMap: `🗺 Карта мира
+
+В этом разделе вы можете посмотреть карту мира, которая обновляется в реальном времени и показывает положение всех игроков на сервере.`,
+		JoinNotification: "🟢 Игрок {{.Username}} зашел на сервер\nОнлайн: {{.Online}}",
+	}
+}
+
+// ServersView собирает данные для шаблонов экранов бота
+func (c *Config) ServersView() ServersView {
+	return ServersView{Primary: c.PrimaryServer(), Servers: c.Servers}
+}
+
+// Render подставляет данные в шаблон текста. Шаблоны проверяются при загрузке конфигурации,
+// поэтому ошибка здесь означает ошибку в коде и возвращается как есть.
+func Render(text string, data any) (string, error) {
+	tmpl, err := parseText("text", text)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+func parseText(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// validate разбирает шаблоны и выполняет их на данных из конфигурации,
+// чтобы ошибки в именах полей обнаружились при загрузке, а не при отправке сообщения
+func (t BotTexts) validate(c *Config, problems *problemList) {
+	view := c.ServersView()
+	checks := []struct {
+		field string
+		text  string
+		data  any
+	}{
+		{"texts.main_menu", t.MainMenu, view},
+		{"texts.connection_guide", t.ConnectionGuide, view},
+		{"texts.world_map", t.WorldMap, view},
+		{"texts.join_notification", t.JoinNotification, JoinNotificationView{Username: "Steve", Server: view.Primary.Name, Online: 1}},
+	}
+	for _, check := range checks {
+		if strings.TrimSpace(check.text) == "" {
+			problems.add(check.field, "текст не может быть пустым")
+			continue
+		}
+		tmpl, err := parseText(check.field, check.text)
+		if err == nil {
+			err = tmpl.Execute(io.Discard, check.data)
+		}
+		if err != nil {
+			problems.add(check.field, fmt.Sprintf("ошибка в шаблоне: %v", err))
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"mine-parser/internal/logging"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy определяет, что делать с событием, когда буфер подписчика заполнен
+type OverflowPolicy int
+
+const (
+	// Block — издатель ждёт, пока подписчик освободит место в буфере
+	Block OverflowPolicy = iota
+	// DropOldest — из буфера выбрасывается самое старое событие
+	DropOldest
+	// SpillToDisk — события, не поместившиеся в буфер, пишутся в файл и дочитываются по мере обработки
+	SpillToDisk
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case SpillToDisk:
+		return "spill"
+	default:
+		return fmt.Sprintf("policy(%d)", int(p))
+	}
+}
+
+const defaultBufferSize = 100
+
+// SubscriberOptions — параметры подписки
+type SubscriberOptions struct {
+	Buffer   int            // размер буфера в памяти
+	Policy   OverflowPolicy // поведение при переполнении буфера
+	Types    []Type         // типы событий; пусто — все события
+	SpillDir string         // каталог для файла переполнения (для SpillToDisk)
+}
+
+// SubscriberMetrics — счётчики одного подписчика
+type SubscriberMetrics struct {
+	Name      string
+	Policy    OverflowPolicy
+	Delivered int64 // события, поставленные в буфер подписчика
+	Dropped   int64 // события, потерянные из-за переполнения или отписки
+	Spilled   int64 // события, записанные в файл переполнения
+	Queued    int   // событий в буфере сейчас
+	OnDisk    int64 // событий в файле переполнения сейчас
+}
+
+// Bus — шина доменных событий внутри процесса.
+// Каждый подписчик получает события через собственный буфер,
+// поэтому медленный подписчик не влияет на остальных (кроме политики Block).
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[string]*Subscription
+	closed bool
+}
+
+// NewBus создаёт пустую шину
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]*Subscription)}
+}
+
+// Subscribe регистрирует подписчика с уникальным именем
+func (b *Bus) Subscribe(name string, opts SubscriberOptions) (*Subscription, error) {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultBufferSize
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, fmt.Errorf("шина событий закрыта")
+	}
+	if _, ok := b.subs[name]; ok {
+		return nil, fmt.Errorf("подписчик %q уже зарегистрирован", name)
+	}
+
+	sub := &Subscription{
+		bus:  b,
+		name: name,
+		opts: opts,
+		ch:   make(chan Event, opts.Buffer),
+		done: make(chan struct{}),
+	}
+	if len(opts.Types) > 0 {
+		sub.types = make(map[Type]bool, len(opts.Types))
+		for _, t := range opts.Types {
+			sub.types[t] = true
+		}
+	}
+
+	if opts.Policy == SpillToDisk {
+		spill, err := openSpillQueue(opts.SpillDir, name)
+		if err != nil {
+			return nil, err
+		}
+		sub.spill = spill
+		if pending := spill.pendingCount(); pending > 0 {
+			log.Printf("Подписчик %s: найдено %d событий в файле переполнения с прошлого запуска", name, pending)
+		}
+		sub.pumpDone = make(chan struct{})
+		go sub.pump()
+	}
+
+	b.subs[name] = sub
+	return sub, nil
+}
+
+// Publish рассылает событие всем подписчикам, которым интересен его тип
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if sub.wants(event.EventType()) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// Metrics возвращает счётчики всех подписчиков, отсортированные по имени
+func (b *Bus) Metrics() []SubscriberMetrics {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := make([]SubscriberMetrics, 0, len(b.subs))
+	for _, sub := range b.subs {
+		result = append(result, sub.Metrics())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Close отписывает всех подписчиков. События, оставшиеся в буферах, можно дочитать из C().
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// Subscription — подписка на события шины
+type Subscription struct {
+	bus   *Bus
+	name  string
+	opts  SubscriberOptions
+	types map[Type]bool
+	ch    chan Event
+
+	mu    sync.Mutex  // упорядочивает запись в буфер и файл переполнения
+	spill *spillQueue // nil для политик без диска
+
+	done     chan struct{}
+	pumpDone chan struct{}
+	once     sync.Once
+
+	delivered atomic.Int64
+	dropped   atomic.Int64
+	spilled   atomic.Int64
+}
+
+// C возвращает канал событий. Канал закрывается после отписки.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Name возвращает имя подписчика
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+// Unsubscribe снимает подписку. События из файла переполнения остаются на диске до следующего запуска.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		// Сначала освобождаем издателей, ожидающих места в буфере
+		close(s.done)
+		if s.pumpDone != nil {
+			<-s.pumpDone
+		}
+
+		s.bus.mu.Lock()
+		if s.bus.subs[s.name] == s {
+			delete(s.bus.subs, s.name)
+		}
+		s.bus.mu.Unlock()
+
+		// Под блокировкой шины никто больше не публикует в этот канал
+		close(s.ch)
+		if s.spill != nil {
+			if err := s.spill.close(); err != nil {
+				logging.Errorf("Подписчик %s: ошибка при закрытии файла переполнения: %v", s.name, err)
+			}
+		}
+	})
+}
+
+// Metrics возвращает текущие счётчики подписчика
+func (s *Subscription) Metrics() SubscriberMetrics {
+	m := SubscriberMetrics{
+		Name:      s.name,
+		Policy:    s.opts.Policy,
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Spilled:   s.spilled.Load(),
+		Queued:    len(s.ch),
+	}
+	if s.spill != nil {
+		m.OnDisk = int64(s.spill.pendingCount())
+	}
+	return m
+}
+
+func (s *Subscription) wants(t Type) bool {
+	return s.types == nil || s.types[t]
+}
+
+// deliver ставит событие в буфер согласно политике переполнения (вызывается под RLock шины)
+func (s *Subscription) deliver(event Event) {
+	switch s.opts.Policy {
+	case DropOldest:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for {
+			select {
+			case s.ch <- event:
+				s.delivered.Add(1)
+				return
+			default:
+			}
+			// Буфер полон: выбрасываем самое старое событие и пробуем снова
+			select {
+			case <-s.ch:
+				s.delivered.Add(-1)
+				s.dropped.Add(1)
+			default:
+			}
+		}
+
+	case SpillToDisk:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Пока на диске есть события, новые пишем туда же, чтобы сохранить порядок
+		if s.spill.pendingCount() == 0 {
+			select {
+			case s.ch <- event:
+				s.delivered.Add(1)
+				return
+			default:
+			}
+		}
+		if err := s.spill.push(event); err != nil {
+			logging.Errorf("Подписчик %s: не удалось записать событие на диск: %v", s.name, err)
+			s.dropped.Add(1)
+			return
+		}
+		s.spilled.Add(1)
+
+	default: // Block
+		select {
+		case s.ch <- event:
+			s.delivered.Add(1)
+		case <-s.done:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// pump переносит события из файла переполнения в буфер по мере его освобождения
+func (s *Subscription) pump() {
+	defer close(s.pumpDone)
+
+	for {
+		select {
+		case <-s.spill.notify:
+		case <-s.done:
+			return
+		}
+
+		for s.spill.pendingCount() > 0 {
+			event, err := s.spill.peek()
+			if err != nil {
+				logging.Warnf("Подписчик %s: повреждённое событие в файле переполнения пропущено: %v", s.name, err)
+				s.dropped.Add(1)
+				s.mu.Lock()
+				s.spill.pop()
+				s.mu.Unlock()
+				continue
+			}
+
+			select {
+			case s.ch <- event:
+			case <-s.done:
+				return
+			}
+
+			// Счётчик на диске уменьшаем только после отправки: пока он не ноль,
+			// издатель пишет в файл, и порядок событий сохраняется
+			s.mu.Lock()
+			s.spill.pop()
+			s.mu.Unlock()
+			s.delivered.Add(1)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Type — тип доменного события
+type Type string
+
+const (
+	TypePlayerLogin       Type = "player_login"
+	TypePlayerLogout      Type = "player_logout"
+	TypeCommandIssued     Type = "command_issued"
+	TypeAdvancementEarned Type = "advancement_earned"
+	TypeServerStarted     Type = "server_started"
+	TypeServerStopping    Type = "server_stopping"
+	TypeChatMessage       Type = "chat_message"
+	TypePlayerDied        Type = "player_died"
+)
+
+// AllTypes — все типы событий в порядке объявления
+var AllTypes = []Type{
+	TypePlayerLogin, TypePlayerLogout, TypeCommandIssued, TypeAdvancementEarned,
+	TypeServerStarted, TypeServerStopping, TypeChatMessage, TypePlayerDied,
+}
+
+// Event — доменное событие, которое можно опубликовать в шине
+type Event interface {
+	EventType() Type
+	OccurredAt() time.Time
+}
+
+// PlayerLogin — игрок зашёл на сервер. IP-адрес в событие не попадает:
+// подписчики шины отправляют события наружу, а адрес хранится только в сессии.
+type PlayerLogin struct {
+	Server    string    `json:"server"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	SessionID uint      `json:"session_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PlayerLogout — игрок вышел с сервера
+type PlayerLogout struct {
+	Server    string    `json:"server"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CommandIssued — игрок выполнил команду
+type CommandIssued struct {
+	Server    string    `json:"server"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Command   string    `json:"command"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AdvancementEarned — игрок получил достижение
+type AdvancementEarned struct {
+	Server      string    `json:"server"`
+	PlayerID    string    `json:"player_id"`
+	Username    string    `json:"username"`
+	Advancement string    `json:"advancement"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ServerStarted — сервер запустился и принимает игроков
+type ServerStarted struct {
+	Server    string    `json:"server"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ServerStopping — сервер начал остановку
+type ServerStopping struct {
+	Server    string    `json:"server"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ChatMessage — игрок написал в общий чат
+type ChatMessage struct {
+	Server    string    `json:"server"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PlayerDied — игрок погиб; Message — сообщение о смерти как в логе
+type PlayerDied struct {
+	Server    string    `json:"server"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (e PlayerLogin) EventType() Type       { return TypePlayerLogin }
+func (e PlayerLogout) EventType() Type      { return TypePlayerLogout }
+func (e CommandIssued) EventType() Type     { return TypeCommandIssued }
+func (e AdvancementEarned) EventType() Type { return TypeAdvancementEarned }
+func (e ServerStarted) EventType() Type     { return TypeServerStarted }
+func (e ServerStopping) EventType() Type    { return TypeServerStopping }
+func (e ChatMessage) EventType() Type       { return TypeChatMessage }
+func (e PlayerDied) EventType() Type        { return TypePlayerDied }
+
+func (e PlayerLogin) OccurredAt() time.Time       { return e.Timestamp }
+func (e PlayerLogout) OccurredAt() time.Time      { return e.Timestamp }
+func (e CommandIssued) OccurredAt() time.Time     { return e.Timestamp }
+func (e AdvancementEarned) OccurredAt() time.Time { return e.Timestamp }
+func (e ServerStarted) OccurredAt() time.Time     { return e.Timestamp }
+func (e ServerStopping) OccurredAt() time.Time    { return e.Timestamp }
+func (e ChatMessage) OccurredAt() time.Time       { return e.Timestamp }
+func (e PlayerDied) OccurredAt() time.Time        { return e.Timestamp }
+
+// Subject — сервер и игрок, к которым относится событие (игрок пуст для событий сервера)
+type Subject struct {
+	Server   string
+	PlayerID string
+	Username string
+}
+
+// SubjectOf возвращает сервер и игрока события
+func SubjectOf(e Event) Subject {
+	switch e := e.(type) {
+	case PlayerLogin:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case PlayerLogout:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case CommandIssued:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case AdvancementEarned:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case ChatMessage:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case PlayerDied:
+		return Subject{e.Server, e.PlayerID, e.Username}
+	case ServerStarted:
+		return Subject{Server: e.Server}
+	case ServerStopping:
+		return Subject{Server: e.Server}
+	}
+	return Subject{}
+}
+
+// Envelope — сериализованное событие с указанием типа (для хранения на диске)
+type Envelope struct {
+	Type    Type            `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Encode упаковывает событие в конверт
+func Encode(e Event) (Envelope, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{Type: e.EventType(), Payload: payload}, nil
+}
+
+// Decode восстанавливает событие из конверта
+func Decode(env Envelope) (Event, error) {
+	var target Event
+	var err error
+
+	switch env.Type {
+	case TypePlayerLogin:
+		var e PlayerLogin
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypePlayerLogout:
+		var e PlayerLogout
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypeCommandIssued:
+		var e CommandIssued
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypeAdvancementEarned:
+		var e AdvancementEarned
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypeServerStarted:
+		var e ServerStarted
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypeServerStopping:
+		var e ServerStopping
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypeChatMessage:
+		var e ChatMessage
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	case TypePlayerDied:
+		var e PlayerDied
+		err = json.Unmarshal(env.Payload, &e)
+		target = e
+	default:
+		return nil, fmt.Errorf("неизвестный тип события %q", env.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать событие %s: %w", env.Type, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	feedSubscriber = "event-feed"
+	feedBusBuffer  = 1024
+)
+
+// Record — событие ленты с порядковым номером
+type Record struct {
+	ID    string // "<метка запуска>-<номер>", передаётся клиенту для возобновления
+	Seq   uint64
+	Event Event
+}
+
+// Feed — лента событий для потоковой выдачи клиентам. Хранит последние события
+// в кольцевом буфере и рассылает новые слушателям, так что переподключившийся клиент
+// получает пропущенное, если оно ещё в буфере. Номера начинаются заново при каждом
+// запуске, поэтому в идентификатор входит метка запуска.
+type Feed struct {
+	sub   *Subscription
+	epoch string
+
+	mu        sync.Mutex
+	records   []Record // кольцевой буфер
+	start     int      // позиция самой старой записи
+	count     int
+	seq       uint64
+	listeners map[*Listener]struct{}
+	closed    bool
+}
+
+// Listener — получатель событий ленты
+type Listener struct {
+	feed   *Feed
+	ch     chan Record
+	filter func(Event) bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewFeed подписывается на шину и хранит до capacity последних событий.
+// Лента не задерживает шину: при переполнении её буфера теряются самые старые события.
+func NewFeed(bus *Bus, capacity int) (*Feed, error) {
+	sub, err := bus.Subscribe(feedSubscriber, SubscriberOptions{Buffer: feedBusBuffer, Policy: DropOldest})
+	if err != nil {
+		return nil, err
+	}
+	f := &Feed{
+		sub:       sub,
+		epoch:     strconv.FormatInt(time.Now().Unix(), 36),
+		records:   make([]Record, max(capacity, 1)),
+		listeners: make(map[*Listener]struct{}),
+	}
+	go f.run()
+	return f, nil
+}
+
+func (f *Feed) run() {
+	for event := range f.sub.C() {
+		f.append(event)
+	}
+
+	// Шина закрыта: новых событий не будет, отпускаем слушателей
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for listener := range f.listeners {
+		listener.stop()
+	}
+	clear(f.listeners)
+}
+
+func (f *Feed) append(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	record := Record{ID: f.epoch + "-" + strconv.FormatUint(f.seq, 10), Seq: f.seq, Event: event}
+	if f.count < len(f.records) {
+		f.records[(f.start+f.count)%len(f.records)] = record
+		f.count++
+	} else {
+		f.records[f.start] = record
+		f.start = (f.start + 1) % len(f.records)
+	}
+
+	for listener := range f.listeners {
+		if listener.filter != nil && !listener.filter(event) {
+			continue
+		}
+		select {
+		case listener.ch <- record:
+		default:
+			// Клиент не успевает читать: отключаем его, при переподключении он дочитает из буфера
+			delete(f.listeners, listener)
+			listener.stop()
+		}
+	}
+}
+
+// Listen регистрирует слушателя и возвращает события из буфера, пропущенные после lastID.
+// Пустой lastID — только новые события; идентификатор прошлого запуска — весь буфер.
+// filter отбирает события (nil — все), buffer — размер очереди слушателя.
+func (f *Feed) Listen(lastID string, filter func(Event) bool, buffer int) (*Listener, []Record, error) {
+	after, resume, err := f.parseID(lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listener := &Listener{
+		feed:   f,
+		ch:     make(chan Record, max(buffer, 1)),
+		filter: filter,
+		done:   make(chan struct{}),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var replay []Record
+	if resume {
+		for i := 0; i < f.count; i++ {
+			record := f.records[(f.start+i)%len(f.records)]
+			if record.Seq > after && (filter == nil || filter(record.Event)) {
+				replay = append(replay, record)
+			}
+		}
+	}
+	if f.closed {
+		listener.stop()
+	} else {
+		f.listeners[listener] = struct{}{}
+	}
+	return listener, replay, nil
+}
+
+// parseID разбирает идентификатор события; resume=false, если возобновлять нечего
+func (f *Feed) parseID(id string) (after uint64, resume bool, err error) {
+	if id == "" {
+		return 0, false, nil
+	}
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, false, fmt.Errorf("некорректный идентификатор события %q", id)
+	}
+	if epoch != f.epoch {
+		// Событие из прошлого запуска: всё, что есть в буфере, клиент ещё не видел
+		return 0, true, nil
+	}
+	after, err = strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("некорректный идентификатор события %q", id)
+	}
+	return after, true, nil
+}
+
+// Listeners возвращает число подключённых слушателей
+func (f *Feed) Listeners() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.listeners)
+}
+
+// C возвращает канал новых событий
+func (l *Listener) C() <-chan Record {
+	return l.ch
+}
+
+// Done закрывается, когда лента отключила слушателя: он не успевал читать или шина закрыта
+func (l *Listener) Done() <-chan struct{} {
+	return l.done
+}
+
+// Close отключает слушателя от ленты
+func (l *Listener) Close() {
+	l.feed.mu.Lock()
+	delete(l.feed.listeners, l)
+	l.feed.mu.Unlock()
+	l.stop()
+}
+
+func (l *Listener) stop() {
+	l.once.Do(func() { close(l.done) })
+}
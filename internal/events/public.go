@@ -0,0 +1,42 @@
+package events
+
+import "time"
+
+// Public — представление события для внешних получателей (поток API, вебхуки).
+// Поля перечисляются явно: новое поле доменного события не уходит наружу,
+// пока его не добавят сюда. Пустые поля опускаются, поэтому у каждого типа
+// остаются только его поля.
+type Public struct {
+	Server      string    `json:"server"`
+	PlayerID    string    `json:"player_id,omitempty"`
+	Username    string    `json:"username,omitempty"`
+	SessionID   uint      `json:"session_id,omitempty"`  // player_login
+	Command     string    `json:"command,omitempty"`     // command_issued
+	Advancement string    `json:"advancement,omitempty"` // advancement_earned
+	Message     string    `json:"message,omitempty"`     // chat_message, player_died
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PublicOf возвращает публичное представление события
+func PublicOf(e Event) Public {
+	subject := SubjectOf(e)
+	view := Public{
+		Server:    subject.Server,
+		PlayerID:  subject.PlayerID,
+		Username:  subject.Username,
+		Timestamp: e.OccurredAt(),
+	}
+	switch e := e.(type) {
+	case PlayerLogin:
+		view.SessionID = e.SessionID
+	case CommandIssued:
+		view.Command = e.Command
+	case AdvancementEarned:
+		view.Advancement = e.Advancement
+	case ChatMessage:
+		view.Message = e.Message
+	case PlayerDied:
+		view.Message = e.Message
+	}
+	return view
+}
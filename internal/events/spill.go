@@ -0,0 +1,213 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// spillQueue — очередь событий в файле (одно событие JSON на строку).
+// Запись идёт в конец файла, чтение — последовательно с начала;
+// когда очередь опустевает, файл обрезается.
+type spillQueue struct {
+	mu      sync.Mutex
+	path    string
+	writer  *os.File
+	reader  *os.File
+	buf     *bufio.Reader
+	pending int
+	readOff int64  // смещение первого недоставленного события
+	headLen int64  // длина строки прочитанного события
+	head    *Event // прочитанное, но ещё не переданное подписчику событие
+	headErr error
+	notify  chan struct{}
+}
+
+func openSpillQueue(dir, name string) (*spillQueue, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог %s: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, name+".spill")
+	writer, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл переполнения %s: %w", path, err)
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("не удалось открыть файл переполнения %s: %w", path, err)
+	}
+
+	q := &spillQueue{
+		path:   path,
+		writer: writer,
+		reader: reader,
+		buf:    bufio.NewReader(reader),
+		notify: make(chan struct{}, 1),
+	}
+
+	// События, оставшиеся с прошлого запуска, будут доставлены первыми
+	pending, err := countLines(path)
+	if err != nil {
+		q.close()
+		return nil, err
+	}
+	q.pending = pending
+	if pending > 0 {
+		q.signal()
+	}
+	return q, nil
+}
+
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(chunk)
+		count += bytes.Count(chunk[:n], []byte{'\n'})
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (q *spillQueue) pendingCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+// push дописывает событие в конец файла
+func (q *spillQueue) push(event Event) error {
+	env, err := Encode(event)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	q.pending++
+	q.signal()
+	return nil
+}
+
+// peek возвращает первое событие очереди, не удаляя его
+func (q *spillQueue) peek() (Event, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.head != nil || q.headErr != nil {
+		return q.deref(), q.headErr
+	}
+
+	line, err := q.buf.ReadBytes('\n')
+	if err != nil {
+		q.headErr = fmt.Errorf("чтение файла переполнения: %w", err)
+		return nil, q.headErr
+	}
+	q.headLen = int64(len(line))
+
+	var env Envelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		q.headErr = err
+		return nil, err
+	}
+	event, err := Decode(env)
+	if err != nil {
+		q.headErr = err
+		return nil, err
+	}
+	q.head = &event
+	return event, nil
+}
+
+func (q *spillQueue) deref() Event {
+	if q.head == nil {
+		return nil
+	}
+	return *q.head
+}
+
+// pop удаляет первое событие; когда очередь пуста, файл обрезается
+func (q *spillQueue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.head, q.headErr = nil, nil
+	q.readOff += q.headLen
+	q.headLen = 0
+	if q.pending > 0 {
+		q.pending--
+	}
+	if q.pending == 0 {
+		if err := q.writer.Truncate(0); err == nil {
+			q.readOff = 0
+			q.reader.Seek(0, io.SeekStart)
+			q.buf.Reset(q.reader)
+		}
+	}
+}
+
+func (q *spillQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// close закрывает файл, предварительно удалив из него уже доставленные события,
+// чтобы после перезапуска они не были отправлены повторно
+func (q *spillQueue) close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reader.Close()
+
+	var compactErr error
+	if q.pending > 0 && q.readOff > 0 {
+		compactErr = q.compact()
+	}
+	if err := q.writer.Close(); err != nil {
+		return err
+	}
+	return compactErr
+}
+
+func (q *spillQueue) compact() error {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		return err
+	}
+	if q.readOff > int64(len(data)) {
+		return nil
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data[q.readOff:], 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
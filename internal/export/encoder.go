@@ -0,0 +1,91 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRowGroupSize — размер группы строк Parquet: группа копится в памяти до записи,
+// поэтому он и ограничивает память выгрузки
+const parquetRowGroupSize = 8 << 20
+
+// encoder пишет записи в поток в одном из форматов
+type encoder interface {
+	encode(r record) error
+	// close дописывает буферы и служебные части формата
+	close() error
+}
+
+func newEncoder(w io.Writer, format Format, spec tableSpec) (encoder, error) {
+	switch format {
+	case FormatCSV:
+		enc := &csvEncoder{w: csv.NewWriter(w)}
+		if err := enc.w.Write(spec.columns); err != nil {
+			return nil, err
+		}
+		return enc, nil
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return jsonEncoder{enc}, nil
+	case FormatParquet:
+		pw, err := writer.NewParquetWriterFromWriter(w, spec.schema, 1)
+		if err != nil {
+			return nil, fmt.Errorf("схема Parquet: %w", err)
+		}
+		pw.RowGroupSize = parquetRowGroupSize
+		return parquetEncoder{pw}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат %q", format)
+	}
+}
+
+type csvEncoder struct {
+	w     *csv.Writer
+	count int
+}
+
+func (e *csvEncoder) encode(r record) error {
+	if err := e.w.Write(r.csv()); err != nil {
+		return err
+	}
+	// Сброс каждые batchSize строк, чтобы данные уходили клиенту по мере выгрузки
+	if e.count++; e.count%batchSize == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e jsonEncoder) encode(r record) error {
+	return e.enc.Encode(r)
+}
+
+func (e jsonEncoder) close() error {
+	return nil
+}
+
+type parquetEncoder struct {
+	pw *writer.ParquetWriter
+}
+
+func (e parquetEncoder) encode(r record) error {
+	return e.pw.Write(r.parquet())
+}
+
+func (e parquetEncoder) close() error {
+	return e.pw.WriteStop()
+}
@@ -0,0 +1,129 @@
+// Package export выгружает сессии, команды и достижения в CSV, JSON Lines и Parquet.
+// Записи читаются из БД пачками и сразу пишутся в поток, поэтому память не растёт
+// с размером таблицы. Имена колонок одинаковы во всех форматах, время — в UTC.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mine-parser/internal/repo"
+	"slices"
+	"strings"
+)
+
+// Table — выгружаемая таблица
+type Table string
+
+const (
+	TableSessions     Table = "sessions"
+	TableCommands     Table = "commands"
+	TableAdvancements Table = "advancements"
+)
+
+// Tables — все таблицы в порядке справки
+var Tables = []Table{TableSessions, TableCommands, TableAdvancements}
+
+// Format — формат выгрузки
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// Formats — все форматы в порядке справки
+var Formats = []Format{FormatCSV, FormatJSONL, FormatParquet}
+
+// batchSize — записей в одном запросе к БД
+const batchSize = 1000
+
+// ParseTable проверяет имя таблицы
+func ParseTable(name string) (Table, error) {
+	table := Table(strings.ToLower(name))
+	if !slices.Contains(Tables, table) {
+		return "", fmt.Errorf("неизвестная таблица %q, допустимы: %s", name, joinNames(Tables))
+	}
+	return table, nil
+}
+
+// ParseFormat проверяет имя формата
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if !slices.Contains(Formats, format) {
+		return "", fmt.Errorf("неизвестный формат %q, допустимы: %s", name, joinNames(Formats))
+	}
+	return format, nil
+}
+
+func joinNames[T ~string](values []T) string {
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = string(value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ContentType — MIME-тип выгрузки в формате f
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// FileName — имя файла выгрузки таблицы t в формате f
+func (f Format) FileName(t Table) string {
+	return string(t) + "." + string(f)
+}
+
+// Filter — какие записи выгружать
+type Filter struct {
+	PlayerID string // UUID игрока; пусто — все игроки
+	Server   string // пусто — все серверы
+	// Period ограничивает время записи; для сессий — пересечение с интервалом
+	Period repo.TimeRange
+}
+
+// Write выгружает таблицу в w и возвращает число записей.
+// При ошибке посередине в w остаётся уже выгруженная часть.
+func Write(ctx context.Context, w io.Writer, source repo.ExportRepository, table Table, format Format, filter Filter) (int64, error) {
+	spec, ok := tables[table]
+	if !ok {
+		return 0, fmt.Errorf("неизвестная таблица %q", table)
+	}
+	enc, err := newEncoder(w, format, spec)
+	if err != nil {
+		return 0, err
+	}
+
+	query := repo.ExportFilter{
+		PlayerID: filter.PlayerID,
+		Server:   filter.Server,
+		Period:   filter.Period,
+		Limit:    batchSize,
+	}
+	var count int64
+	for {
+		records, lastID, err := spec.fetch(ctx, source, query)
+		if err != nil {
+			return count, err
+		}
+		for _, record := range records {
+			if err := enc.encode(record); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(records) < batchSize {
+			break
+		}
+		query.AfterID = lastID
+	}
+	return count, enc.close()
+}
@@ -0,0 +1,239 @@
+package export
+
+import (
+	"context"
+	"mine-parser/internal/repo"
+	"strconv"
+	"time"
+)
+
+// record — одна строка выгрузки; в JSON Lines она кодируется как есть
+type record interface {
+	csv() []string
+	parquet() any
+}
+
+// tableSpec описывает колонки таблицы и чтение её пачками
+type tableSpec struct {
+	columns []string
+	schema  any // пустая строка Parquet, по тегам которой строится схема файла
+	// fetch возвращает пачку записей и id последней из них
+	fetch func(ctx context.Context, source repo.ExportRepository, filter repo.ExportFilter) ([]record, uint, error)
+}
+
+var tables = map[Table]tableSpec{
+	TableSessions: {
+		columns: []string{"id", "player_id", "username", "server", "join_time", "leave_time", "duration_seconds"},
+		schema:  new(sessionParquet),
+		fetch: func(ctx context.Context, source repo.ExportRepository, filter repo.ExportFilter) ([]record, uint, error) {
+			rows, err := source.Sessions(ctx, filter)
+			if err != nil {
+				return nil, 0, err
+			}
+			return toRecords(rows, func(row repo.SessionExport) (record, uint) {
+				return newSessionRecord(row), row.ID
+			})
+		},
+	},
+	TableCommands: {
+		columns: []string{"id", "session_id", "player_id", "username", "server", "timestamp", "name", "command"},
+		schema:  new(commandParquet),
+		fetch: func(ctx context.Context, source repo.ExportRepository, filter repo.ExportFilter) ([]record, uint, error) {
+			rows, err := source.Commands(ctx, filter)
+			if err != nil {
+				return nil, 0, err
+			}
+			return toRecords(rows, func(row repo.CommandExport) (record, uint) {
+				return commandRecord{
+					ID:        row.ID,
+					SessionID: row.SessionID,
+					PlayerID:  row.PlayerID,
+					Username:  row.Username,
+					Server:    row.Server,
+					Timestamp: row.Timestamp.UTC(),
+					Name:      row.CommandName,
+					Command:   row.Command,
+				}, row.ID
+			})
+		},
+	},
+	TableAdvancements: {
+		columns: []string{"id", "player_id", "username", "server", "timestamp", "name"},
+		schema:  new(advancementParquet),
+		fetch: func(ctx context.Context, source repo.ExportRepository, filter repo.ExportFilter) ([]record, uint, error) {
+			rows, err := source.Advancements(ctx, filter)
+			if err != nil {
+				return nil, 0, err
+			}
+			return toRecords(rows, func(row repo.AdvancementExport) (record, uint) {
+				return advancementRecord{
+					ID:        row.ID,
+					PlayerID:  row.PlayerID,
+					Username:  row.Username,
+					Server:    row.Server,
+					Timestamp: row.Timestamp.UTC(),
+					Name:      row.AdvancementName,
+				}, row.ID
+			})
+		},
+	},
+}
+
+// toRecords превращает пачку строк репозитория в записи выгрузки
+func toRecords[T any](rows []T, convert func(T) (record, uint)) ([]record, uint, error) {
+	records := make([]record, len(rows))
+	var lastID uint
+	for i, row := range rows {
+		records[i], lastID = convert(row)
+	}
+	return records, lastID, nil
+}
+
+// formatTime — время в CSV: RFC 3339 в UTC, как в JSON
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// micros — время в Parquet: микросекунды от начала эпохи Unix
+func micros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+type sessionRecord struct {
+	ID        uint       `json:"id"`
+	PlayerID  string     `json:"player_id"`
+	Username  string     `json:"username"`
+	Server    string     `json:"server"`
+	JoinTime  time.Time  `json:"join_time"`
+	LeaveTime *time.Time `json:"leave_time"` // null, пока сессия открыта
+	// Длительность открытой сессии пуста, чтобы повторная выгрузка давала те же данные
+	DurationSeconds *int64 `json:"duration_seconds"`
+}
+
+func newSessionRecord(row repo.SessionExport) sessionRecord {
+	result := sessionRecord{
+		ID:       row.ID,
+		PlayerID: row.PlayerID,
+		Username: row.Username,
+		Server:   row.Server,
+		JoinTime: row.JoinTime.UTC(),
+	}
+	if row.LeaveTime != nil {
+		leave := row.LeaveTime.UTC()
+		duration := int64(leave.Sub(result.JoinTime).Seconds())
+		result.LeaveTime = &leave
+		result.DurationSeconds = &duration
+	}
+	return result
+}
+
+func (s sessionRecord) csv() []string {
+	leave, duration := "", ""
+	if s.LeaveTime != nil {
+		leave = formatTime(*s.LeaveTime)
+		duration = strconv.FormatInt(*s.DurationSeconds, 10)
+	}
+	return []string{formatID(s.ID), s.PlayerID, s.Username, s.Server, formatTime(s.JoinTime), leave, duration}
+}
+
+type sessionParquet struct {
+	ID              int64  `parquet:"name=id, type=INT64"`
+	PlayerID        string `parquet:"name=player_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Username        string `parquet:"name=username, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Server          string `parquet:"name=server, type=BYTE_ARRAY, convertedtype=UTF8"`
+	JoinTime        int64  `parquet:"name=join_time, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	LeaveTime       *int64 `parquet:"name=leave_time, type=INT64, repetitiontype=OPTIONAL, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	DurationSeconds *int64 `parquet:"name=duration_seconds, type=INT64, repetitiontype=OPTIONAL"`
+}
+
+func (s sessionRecord) parquet() any {
+	row := sessionParquet{
+		ID:              int64(s.ID),
+		PlayerID:        s.PlayerID,
+		Username:        s.Username,
+		Server:          s.Server,
+		JoinTime:        micros(s.JoinTime),
+		DurationSeconds: s.DurationSeconds,
+	}
+	if s.LeaveTime != nil {
+		leave := micros(*s.LeaveTime)
+		row.LeaveTime = &leave
+	}
+	return row
+}
+
+type commandRecord struct {
+	ID        uint      `json:"id"`
+	SessionID uint      `json:"session_id"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Server    string    `json:"server"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+	Command   string    `json:"command"`
+}
+
+func (c commandRecord) csv() []string {
+	return []string{formatID(c.ID), formatID(c.SessionID), c.PlayerID, c.Username, c.Server, formatTime(c.Timestamp), c.Name, c.Command}
+}
+
+type commandParquet struct {
+	ID        int64  `parquet:"name=id, type=INT64"`
+	SessionID int64  `parquet:"name=session_id, type=INT64"`
+	PlayerID  string `parquet:"name=player_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Username  string `parquet:"name=username, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Server    string `parquet:"name=server, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Timestamp int64  `parquet:"name=timestamp, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	Name      string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Command   string `parquet:"name=command, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func (c commandRecord) parquet() any {
+	return commandParquet{
+		ID:        int64(c.ID),
+		SessionID: int64(c.SessionID),
+		PlayerID:  c.PlayerID,
+		Username:  c.Username,
+		Server:    c.Server,
+		Timestamp: micros(c.Timestamp),
+		Name:      c.Name,
+		Command:   c.Command,
+	}
+}
+
+type advancementRecord struct {
+	ID        uint      `json:"id"`
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Server    string    `json:"server"` // пусто, если сервер не удалось определить
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+}
+
+func (a advancementRecord) csv() []string {
+	return []string{formatID(a.ID), a.PlayerID, a.Username, a.Server, formatTime(a.Timestamp), a.Name}
+}
+
+type advancementParquet struct {
+	ID        int64  `parquet:"name=id, type=INT64"`
+	PlayerID  string `parquet:"name=player_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Username  string `parquet:"name=username, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Server    string `parquet:"name=server, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Timestamp int64  `parquet:"name=timestamp, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	Name      string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func (a advancementRecord) parquet() any {
+	return advancementParquet{
+		ID:        int64(a.ID),
+		PlayerID:  a.PlayerID,
+		Username:  a.Username,
+		Server:    a.Server,
+		Timestamp: micros(a.Timestamp),
+		Name:      a.Name,
+	}
+}
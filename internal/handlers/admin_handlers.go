@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/models"
+	"mine-parser/internal/service"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	diagnosticsTopShapes = 10
+	maxSampleLength      = 300
+	webhookLogSize       = 15
+	maxWebhookErrorLen   = 120
+)
+
+// handleAdminCallback обрабатывает служебные callback'и вида "admin:<действие>"
+func (h *TelegramHandlers) handleAdminCallback(ctx context.Context, chatID int64, messageID int, userID int64, action string) {
+	if !h.cfg.Get().Tg.IsAdmin(userID) {
+		h.sendError(chatID, "Раздел доступен только администраторам")
+		return
+	}
+
+	if action == "diagnostics" {
+		h.showDiagnostics(ctx, chatID, messageID)
+	} else if strings.HasPrefix(action, "shape:") {
+		h.showShapeSamples(chatID, messageID, strings.TrimPrefix(action, "shape:"))
+	} else if action == "webhooks" {
+		h.showWebhooks(ctx, chatID, messageID)
+	} else if strings.HasPrefix(action, "webhook:") {
+		h.showWebhookLog(ctx, chatID, messageID, strings.TrimPrefix(action, "webhook:"))
+	} else if strings.HasPrefix(action, "webhook_enable:") {
+		h.enableWebhook(ctx, chatID, messageID, strings.TrimPrefix(action, "webhook_enable:"))
+	} else if action == "discord" {
+		h.showDiscordChannels(ctx, chatID, messageID)
+	} else if strings.HasPrefix(action, "discord:") {
+		h.showDiscordChannel(ctx, chatID, messageID, strings.TrimPrefix(action, "discord:"))
+	} else if strings.HasPrefix(action, "discord_toggle:") {
+		h.toggleDiscordChannel(ctx, chatID, messageID, strings.TrimPrefix(action, "discord_toggle:"))
+	} else if strings.HasPrefix(action, "discord_event:") {
+		name, eventType, _ := strings.Cut(strings.TrimPrefix(action, "discord_event:"), ":")
+		h.toggleDiscordEvent(ctx, chatID, messageID, name, eventType)
+	} else if strings.HasPrefix(action, "discord_bl:") {
+		name, number, _ := strings.Cut(strings.TrimPrefix(action, "discord_bl:"), ":")
+		h.showDiscordBlacklist(ctx, chatID, messageID, name, parsePage(number), "")
+	} else if strings.HasPrefix(action, "dbl:") {
+		name, playerID, _ := strings.Cut(strings.TrimPrefix(action, "dbl:"), ":")
+		h.toggleDiscordBlacklist(ctx, chatID, messageID, name, playerID)
+	}
+}
+
+func (h *TelegramHandlers) showDiagnostics(ctx context.Context, chatID int64, messageID int) {
+	summary := h.diagnostics.Summary()
+	shapes := h.diagnostics.TopUnknownShapes(diagnosticsTopShapes)
+
+	var text strings.Builder
+	text.WriteString("🛠 Диагностика парсера\n\n")
+	text.WriteString(fmt.Sprintf("Строк обработано: %d\nНе распознано: %d\nУникальных форм: %d\n",
+		summary.TotalLines, summary.UnmatchedLines, summary.ShapeCount))
+	if summary.DuplicateEvents > 0 {
+		text.WriteString(fmt.Sprintf("Пропущено дубликатов: %d\n", summary.DuplicateEvents))
+	}
+	if summary.DroppedShapes > 0 {
+		text.WriteString(fmt.Sprintf("Вне лимита форм: %d\n", summary.DroppedShapes))
+	}
+
+	text.WriteString("\n📐 Правила (срабатывания / ошибки / дубликаты):\n")
+	rules := h.diagnostics.RuleStats()
+	if len(rules) == 0 {
+		text.WriteString("Пока ни одно правило не сработало\n")
+	}
+	for _, rule := range rules {
+		text.WriteString(fmt.Sprintf("• %s: %d / %d / %d\n", rule.Rule, rule.Matched, rule.Errors, rule.Duplicates))
+		if rule.LastError != "" {
+			text.WriteString(fmt.Sprintf("   Последняя ошибка: %s\n", rule.LastError))
+		}
+	}
+
+	text.WriteString("\n📨 Подписчики событий (доставлено / потеряно / на диске):\n")
+	subscribers := h.bus.Metrics()
+	if len(subscribers) == 0 {
+		text.WriteString("Нет подписчиков\n")
+	}
+	for _, sub := range subscribers {
+		text.WriteString(fmt.Sprintf("• %s [%s]: %d / %d / %d, в очереди %d\n",
+			sub.Name, sub.Policy, sub.Delivered, sub.Dropped, sub.OnDisk, sub.Queued))
+	}
+
+	if outbox, err := h.notificationSvc.OutboxStats(ctx); err == nil {
+		text.WriteString(fmt.Sprintf("\n📬 Очередь уведомлений: ожидают %d, отправлено %d, ошибок %d, устарело %d\n",
+			outbox[models.OutboxPending], outbox[models.OutboxSent], outbox[models.OutboxFailed], outbox[models.OutboxExpired]))
+	}
+
+	text.WriteString("\n❓ Частые нераспознанные формы:\n")
+	if len(shapes) == 0 {
+		text.WriteString("Нет нераспознанных строк\n")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, shape := range shapes {
+		text.WriteString(fmt.Sprintf("%d. [%s] %s — %d\n", i+1, shape.Logger, shape.Shape, shape.Count))
+		button := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("Примеры #%d", i+1),
+			fmt.Sprintf("admin:shape:%s", shape.ID),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "admin:diagnostics"),
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) showShapeSamples(chatID int64, messageID int, shapeID string) {
+	shape, ok := h.diagnostics.GetShape(shapeID)
+	if !ok {
+		h.sendError(chatID, "Форма не найдена")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🔎 [%s] %s\nВстречалась: %d раз\nПоследний раз: %s\n\nПримеры:\n",
+		shape.Logger, shape.Shape, shape.Count, shape.LastSeen.Format("02.01.2006 15:04:05")))
+	for i, sample := range shape.Samples {
+		if len([]rune(sample)) > maxSampleLength {
+			sample = string([]rune(sample)[:maxSampleLength]) + "…"
+		}
+		text.WriteString(fmt.Sprintf("%d. %s\n", i+1, sample))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Назад к диагностике", "admin:diagnostics"),
+		),
+	)
+
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) showWebhooks(ctx context.Context, chatID int64, messageID int) {
+	webhooks, err := h.webhookSvc.List(ctx)
+	if err != nil {
+		logging.Errorf("Ошибка при получении вебхуков: %v", err)
+		h.sendError(chatID, "Не удалось получить список вебхуков")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("🔗 Вебхуки\n\n")
+	if len(webhooks) == 0 {
+		text.WriteString("Вебхуки не настроены (раздел webhooks.endpoints в конфигурации)\n")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, webhook := range webhooks {
+		status := "✅ работает"
+		if webhook.DisabledAt != nil {
+			status = fmt.Sprintf("⛔ отключён %s", h.localTime(*webhook.DisabledAt).Format("02.01.2006 15:04"))
+		}
+		text.WriteString(fmt.Sprintf("• %s — %s\n   %s\n", webhook.Name, status, webhook.URL))
+		filter := "все события"
+		if len(webhook.Events) > 0 {
+			filter = strings.Join(webhook.Events, ", ")
+		}
+		if len(webhook.Servers) > 0 {
+			filter += " · " + strings.Join(webhook.Servers, ", ")
+		}
+		text.WriteString(fmt.Sprintf("   %s\n   В очереди: %d, неудач подряд: %d\n", filter, webhook.Pending, webhook.ConsecutiveFailures))
+		if webhook.DisabledReason != "" {
+			text.WriteString(fmt.Sprintf("   Причина: %s\n", truncateRunes(webhook.DisabledReason, maxWebhookErrorLen)))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📜 Журнал "+webhook.Name, "admin:webhook:"+webhook.Name),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "admin:webhooks"),
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+// showWebhookLog показывает последние доставки вебхука
+func (h *TelegramHandlers) showWebhookLog(ctx context.Context, chatID int64, messageID int, name string) {
+	webhooks, err := h.webhookSvc.List(ctx)
+	if err != nil {
+		logging.Errorf("Ошибка при получении вебхуков: %v", err)
+		h.sendError(chatID, "Не удалось получить список вебхуков")
+		return
+	}
+	var current *service.WebhookStatus
+	for i := range webhooks {
+		if webhooks[i].Name == name {
+			current = &webhooks[i]
+		}
+	}
+	if current == nil {
+		h.sendError(chatID, "Вебхук не найден")
+		return
+	}
+	deliveries, err := h.webhookSvc.Deliveries(ctx, name, webhookLogSize)
+	if err != nil {
+		logging.Errorf("Ошибка при получении журнала вебхука %s: %v", name, err)
+		h.sendError(chatID, "Не удалось получить журнал доставки")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📜 Журнал вебхука %s\n", name))
+	if current.DisabledAt != nil {
+		text.WriteString("⛔ Вебхук отключён, новые события ему не отправляются\n")
+	}
+	text.WriteString("\n")
+	if len(deliveries) == 0 {
+		text.WriteString("Доставок пока не было\n")
+	}
+	for _, delivery := range deliveries {
+		text.WriteString(fmt.Sprintf("#%d %s %s — ", delivery.ID,
+			h.localTime(delivery.CreatedAt).Format("02.01 15:04:05"), delivery.EventType))
+		switch delivery.Status {
+		case models.WebhookDelivered:
+			text.WriteString(fmt.Sprintf("✅ %d за %d мс", delivery.StatusCode, delivery.DurationMs))
+		case models.WebhookPending:
+			text.WriteString(fmt.Sprintf("⏳ попыток %d", delivery.Attempts))
+			if delivery.Attempts > 0 {
+				text.WriteString(fmt.Sprintf(", повтор в %s", h.localTime(delivery.NextAttemptAt).Format("15:04:05")))
+			}
+		default:
+			text.WriteString(fmt.Sprintf("❌ попыток %d", delivery.Attempts))
+		}
+		text.WriteString("\n")
+		if delivery.Status != models.WebhookDelivered && delivery.LastError != "" {
+			text.WriteString(fmt.Sprintf("   %s\n", truncateRunes(delivery.LastError, maxWebhookErrorLen)))
+		}
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if current.DisabledAt != nil {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Включить", "admin:webhook_enable:"+name),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", "admin:webhook:"+name),
+		tgbotapi.NewInlineKeyboardButtonData("Назад к вебхукам", "admin:webhooks"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) enableWebhook(ctx context.Context, chatID int64, messageID int, name string) {
+	if err := h.webhookSvc.Enable(ctx, name); err != nil {
+		logging.Errorf("Не удалось включить вебхук %s: %v", name, err)
+		h.sendError(chatID, "Не удалось включить вебхук")
+		return
+	}
+	log.Printf("Вебхук %s включён администратором", name)
+	h.showWebhookLog(ctx, chatID, messageID, name)
+}
+
+// truncateRunes укорачивает текст до limit символов
+func truncateRunes(text string, limit int) string {
+	if len([]rune(text)) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "…"
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/service"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// botCommand — команда бота и её описание в меню Telegram на каждом языке
+type botCommand struct {
+	name        string
+	args        string // аргументы для справки
+	description map[string]string
+	privateOnly bool // только в личном чате: в группах меню не нужно
+}
+
+// commandLanguages — языки описаний; пустой код — описание по умолчанию для остальных языков
+var commandLanguages = []string{"", "en"}
+
+var botCommands = []botCommand{
+	{name: "start", description: map[string]string{"": "Главное меню", "en": "Main menu"}, privateOnly: true},
+	{name: "online", description: map[string]string{"": "Кто сейчас в игре", "en": "Who is playing now"}},
+	{name: "player", args: "<ник>", description: map[string]string{"": "Карточка игрока", "en": "Player profile"}},
+	{name: "seen", args: "<ник>", description: map[string]string{"": "Когда игрок был в игре", "en": "When a player was last online"}},
+	{name: "top", args: "[показатель] [период]", description: map[string]string{"": "Рейтинг игроков", "en": "Player leaderboard"}},
+	{name: "stats", description: map[string]string{"": "Статистика сервера за сегодня", "en": "Server stats for today"}},
+	{name: "advancements", args: "<ник>", description: map[string]string{"": "Достижения игрока", "en": "Player advancements"}},
+	{name: "link", args: "<ник>", description: map[string]string{"": "Привязать свой ник для рейтингов", "en": "Link your player for leaderboards"}},
+	{name: "unlink", description: map[string]string{"": "Отвязать ник", "en": "Unlink your player"}},
+	{name: "help", description: map[string]string{"": "Список команд", "en": "List of commands"}},
+}
+
+const suggestionLimit = 8 // кнопок «возможно, вы имели в виду»
+
+// RegisterCommands публикует список команд в меню Telegram для личных чатов и групп на всех языках
+func (h *TelegramHandlers) RegisterCommands() {
+	scopes := []struct {
+		scope   tgbotapi.BotCommandScope
+		private bool
+	}{
+		{tgbotapi.NewBotCommandScopeAllPrivateChats(), true},
+		{tgbotapi.NewBotCommandScopeAllGroupChats(), false},
+	}
+	for _, scope := range scopes {
+		for _, language := range commandLanguages {
+			var commands []tgbotapi.BotCommand
+			for _, command := range botCommands {
+				if command.privateOnly && !scope.private {
+					continue
+				}
+				commands = append(commands, tgbotapi.BotCommand{Command: command.name, Description: command.description[language]})
+			}
+			config := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope.scope, language, commands...)
+			if _, err := h.bot.Request(config); err != nil {
+				logging.Warnf("Не удалось зарегистрировать команды бота (%s, язык %q): %v", scope.scope.Type, language, err)
+			}
+		}
+	}
+}
+
+// addressedToOther сообщает, что команда вида /cmd@bot адресована другому боту в группе
+func (h *TelegramHandlers) addressedToOther(message *tgbotapi.Message) bool {
+	_, bot, ok := strings.Cut(message.CommandWithAt(), "@")
+	return ok && !strings.EqualFold(bot, h.bot.Self.UserName)
+}
+
+// commandArg — первое слово после команды; ники Minecraft не содержат пробелов
+func commandArg(message *tgbotapi.Message) string {
+	fields := strings.Fields(message.CommandArguments())
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func (h *TelegramHandlers) sendText(chatID int64, text string) {
+	if _, err := h.bot.Send(tgbotapi.NewMessage(chatID, text)); err != nil {
+		logging.Errorf("Ошибка при отправке сообщения: %v", err)
+	}
+}
+
+func (h *TelegramHandlers) sendHelp(chatID int64) {
+	var text strings.Builder
+	text.WriteString("Команды бота:\n\n")
+	for _, command := range botCommands {
+		text.WriteString("/" + command.name)
+		if command.args != "" {
+			text.WriteString(" " + command.args)
+		}
+		text.WriteString(" — " + command.description[""] + "\n")
+	}
+	text.WriteString("\nНик можно писать в любом регистре и не полностью.\n")
+	text.WriteString("Показатели /top: playtime (время), sessions (сессии), commands (команды), advancements (достижения), longest (рекорд — самая долгая сессия).\n")
+	text.WriteString("Периоды /top: неделя, месяц, всё или даты: /top время 01.09.2026 30.09.2026.")
+	h.sendText(chatID, text.String())
+}
+
+// withPlayer находит игрока по нику из команды и вызывает show с его UUID. Если ник
+// подходит нескольким игрокам, отправляет кнопки выбора с callback action:<UUID>.
+func (h *TelegramHandlers) withPlayer(ctx context.Context, message *tgbotapi.Message, action string, show func(playerID string)) {
+	chatID := message.Chat.ID
+	name := commandArg(message)
+	if name == "" {
+		h.sendText(chatID, fmt.Sprintf("Укажите ник: /%s <ник>", message.Command()))
+		return
+	}
+
+	match, err := h.playerSvc.MatchPlayer(ctx, name, suggestionLimit)
+	if err != nil {
+		logging.Errorf("Ошибка при поиске игрока %q: %v", name, err)
+		h.sendError(chatID, "Ошибка при поиске игрока")
+		return
+	}
+	if match.Player != nil {
+		show(match.Player.ID)
+		return
+	}
+	if len(match.Suggestions) == 0 {
+		h.sendText(chatID, fmt.Sprintf("Игрок %s не найден", name))
+		return
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, len(match.Suggestions))
+	for i, player := range match.Suggestions {
+		rows[i] = tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(player.Username, action+":"+player.ID),
+		)
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Игрок %s не найден. Возможно, вы имели в виду:", name))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	if _, err := h.bot.Send(msg); err != nil {
+		logging.Errorf("Ошибка при отправке сообщения: %v", err)
+	}
+}
+
+// showSeen показывает, в игре ли игрок сейчас или когда был в последний раз
+func (h *TelegramHandlers) showSeen(ctx context.Context, chatID int64, messageID int, playerID string) {
+	player, err := h.playerSvc.FindPlayer(ctx, playerID)
+	if err != nil || player == nil {
+		h.sendError(chatID, "Ошибка при получении информации об игроке")
+		return
+	}
+
+	var text string
+	if presence, online := h.playerSvc.GetPresence(playerID); online {
+		text = fmt.Sprintf("🟢 %s сейчас в игре", player.Username)
+		if presence.Server != "" && len(h.cfg.Get().Servers) > 1 {
+			text += " на сервере " + presence.Server
+		}
+		text += fmt.Sprintf(" с %s (%s)", h.localTime(presence.Since).Format("02.01.2006 15:04"), formatAgo(time.Since(presence.Since)))
+	} else {
+		session, err := h.playerSvc.GetLastSession(ctx, playerID)
+		if err != nil {
+			h.sendError(chatID, "Ошибка при получении информации об игроке")
+			return
+		}
+		last := player.LastSeen
+		if session != nil && session.LeaveTime != nil {
+			last = *session.LeaveTime
+		}
+		text = fmt.Sprintf("🔴 %s последний раз был в игре %s (%s назад)",
+			player.Username, h.localTime(last).Format("02.01.2006 15:04"), formatAgo(time.Since(last)))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👤 Игрок", "player:"+playerID),
+		),
+	)
+	h.show(chatID, messageID, text, keyboard)
+}
+
+// formatAgo — длительность в самой крупной подходящей единице: «5 мин», «3 ч», «2 дн»
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "меньше минуты"
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d ч", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%d дн", int(d/(24*time.Hour)))
+	}
+}
+
+// sendServerStats отправляет число игроков и активность за сегодняшний день
+func (h *TelegramHandlers) sendServerStats(ctx context.Context, chatID int64) {
+	location := h.cfg.Get().App.Location
+	now := time.Now().In(location)
+	today := repo.TimeRange{
+		From: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location),
+		To:   now,
+	}
+
+	playerCount, err := h.playerSvc.CountPlayers(ctx)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении статистики")
+		return
+	}
+	days, err := h.statsSvc.History(ctx, "", today, location)
+	if err != nil {
+		logging.Errorf("Ошибка при получении истории сервера: %v", err)
+		h.sendError(chatID, "Ошибка при получении статистики")
+		return
+	}
+	var day service.HistoryDay
+	if len(days) > 0 {
+		day = days[len(days)-1]
+	}
+
+	text := fmt.Sprintf("📊 Статистика сервера\n\n"+
+		"👥 Игроков всего: %d\n"+
+		"🟢 Сейчас онлайн: %d\n\n"+
+		"Сегодня, %s:\n"+
+		"Заходило игроков: %d\n"+
+		"Сессий: %d\n"+
+		"⏱ Время в игре: %s\n"+
+		"📈 Пик онлайна: %d",
+		playerCount, len(h.playerSvc.ListOnlinePlayers()),
+		now.Format("02.01.2006"),
+		day.Players, day.Sessions, formatPlayTime(day.PlayTime), day.PeakOnline)
+	h.sendText(chatID, text)
+}
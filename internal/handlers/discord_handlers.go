@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// discordEventNames — подписи типов событий на кнопках настройки канала Discord
+var discordEventNames = map[events.Type]string{
+	events.TypePlayerLogin:       "Вход",
+	events.TypePlayerLogout:      "Выход",
+	events.TypePlayerDied:        "Смерти",
+	events.TypeAdvancementEarned: "Достижения",
+	events.TypeChatMessage:       "Чат",
+	events.TypeServerStarted:     "Запуск сервера",
+	events.TypeServerStopping:    "Остановка сервера",
+}
+
+func (h *TelegramHandlers) showDiscordChannels(ctx context.Context, chatID int64, messageID int) {
+	channels, err := h.discordSvc.Channels(ctx)
+	if err != nil {
+		logging.Errorf("Ошибка при получении каналов Discord: %v", err)
+		h.sendError(chatID, "Не удалось получить каналы Discord")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("💬 Уведомления в Discord\n\n")
+	if len(channels) == 0 {
+		text.WriteString("Каналы не настроены (раздел discord.channels в конфигурации)\n")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, channel := range channels {
+		icon := "🔕"
+		if channel.Enabled {
+			icon = "🔔"
+		}
+		text.WriteString(fmt.Sprintf("%s %s — %s\n", icon, channel.Name, h.discordEventList(channel.Events)))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", icon, channel.Name), "admin:discord:"+channel.Name),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) discordEventList(eventTypes []string) string {
+	if len(eventTypes) == 0 {
+		return "события не выбраны"
+	}
+	names := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		names = append(names, strings.ToLower(discordEventNames[events.Type(eventType)]))
+	}
+	return strings.Join(names, ", ")
+}
+
+// showDiscordChannel показывает подписку канала и события, о которых он уведомляется
+func (h *TelegramHandlers) showDiscordChannel(ctx context.Context, chatID int64, messageID int, name string) {
+	channel, err := h.discordSvc.GetChannel(ctx, name)
+	if err != nil {
+		logging.Errorf("Ошибка при получении канала Discord %s: %v", name, err)
+		h.sendError(chatID, "Канал Discord не найден")
+		return
+	}
+
+	statusText := "🔕 Уведомления выключены"
+	buttonText := "Включить уведомления"
+	if channel.Enabled {
+		statusText = "🔔 Уведомления включены"
+		buttonText = "Выключить уведомления"
+	}
+	text := fmt.Sprintf("💬 Канал Discord %s\n\n%s\n\nОтметьте события, о которых сообщать в канал:", name, statusText)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(buttonText, "admin:discord_toggle:"+name),
+	))
+	for _, eventType := range config.DiscordEventTypes {
+		icon := "⬜"
+		if slices.Contains(channel.Events, string(eventType)) {
+			icon = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s %s", icon, discordEventNames[eventType]),
+				fmt.Sprintf("admin:discord_event:%s:%s", name, eventType),
+			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🚫 Черный список", "admin:discord_bl:"+name),
+	))
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад к каналам", "admin:discord"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) toggleDiscordChannel(ctx context.Context, chatID int64, messageID int, name string) {
+	enabled, err := h.discordSvc.ToggleSubscription(ctx, name)
+	if err != nil {
+		logging.Errorf("Ошибка при переключении уведомлений канала Discord %s: %v", name, err)
+		h.sendError(chatID, "Ошибка при переключении уведомлений")
+		return
+	}
+	log.Printf("Уведомления канала Discord %s переключены, новый статус: %v", name, enabled)
+	h.showDiscordChannel(ctx, chatID, messageID, name)
+}
+
+func (h *TelegramHandlers) toggleDiscordEvent(ctx context.Context, chatID int64, messageID int, name, eventType string) {
+	if _, err := h.discordSvc.ToggleEvent(ctx, name, eventType); err != nil {
+		logging.Errorf("Ошибка при изменении событий канала Discord %s: %v", name, err)
+		h.sendError(chatID, "Ошибка при изменении событий канала")
+		return
+	}
+	h.showDiscordChannel(ctx, chatID, messageID, name)
+}
+
+// showDiscordBlacklist показывает страницу number черного списка канала; если задан
+// focusPlayerID, то страницу с этим игроком. Список отсортирован по нику.
+func (h *TelegramHandlers) showDiscordBlacklist(ctx context.Context, chatID int64, messageID int, name string, number int, focusPlayerID string) {
+	// В данных кнопки игрока нет места для номера страницы, поэтому после переключения
+	// страница находится по положению игрока в списке
+	if focusPlayerID != "" {
+		position, err := h.playerSvc.PlayerPositionByName(ctx, focusPlayerID)
+		if err != nil {
+			h.sendError(chatID, "Ошибка при получении списка игроков")
+			return
+		}
+		if position >= 0 {
+			number = int(position) / pageSize
+		}
+	}
+	sorted, current, _, err := h.playerPage(ctx, sortByName, number)
+	if err != nil {
+		logging.Errorf("Ошибка при получении списка игроков: %v", err)
+		h.sendError(chatID, "Ошибка при получении списка игроков")
+		return
+	}
+	blacklist, err := h.discordSvc.GetBlacklist(ctx, name)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении черного списка")
+		return
+	}
+	blacklistMap := make(map[string]bool, len(blacklist))
+	for _, item := range blacklist {
+		blacklistMap[item.PlayerID] = true
+	}
+
+	text := fmt.Sprintf("🚫 Черный список канала %s (%d)\n\nО событиях игроков из черного списка канал не уведомляется.\n\nВыберите игрока:", name, len(blacklist))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, player := range sorted {
+		icon := "🔔"
+		if blacklistMap[player.ID] {
+			icon = "🔕"
+		}
+		// Данные кнопки ограничены 64 байтами: короткий префикс, имя канала до 16 символов и UUID
+		button := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %s", icon, player.Username),
+			fmt.Sprintf("admin:dbl:%s:%s", name, player.ID),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	if row := current.row(func(number int) string { return fmt.Sprintf("admin:discord_bl:%s:%d", name, number) }); row != nil {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "admin:discord:"+name),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
+
+func (h *TelegramHandlers) toggleDiscordBlacklist(ctx context.Context, chatID int64, messageID int, name, playerID string) {
+	if _, err := h.discordSvc.ToggleBlacklist(ctx, name, playerID); err != nil {
+		logging.Errorf("Ошибка при изменении черного списка канала Discord %s: %v", name, err)
+		h.sendError(chatID, "Ошибка при изменении черного списка")
+		return
+	}
+	h.showDiscordBlacklist(ctx, chatID, messageID, name, 0, playerID)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// deepLinkPlayerPrefix — payload ссылки t.me/<бот>?start=player_<UUID>
+const deepLinkPlayerPrefix = "player_"
+
+const (
+	inlineLimit     = 10 // результатов в ответе на инлайн-запрос
+	inlineCacheTime = 30 // секунд кэша ответа на стороне Telegram: статус онлайна быстро устаревает
+)
+
+// playerDeepLink — ссылка, открывающая карточку игрока в личном чате с ботом
+func (h *TelegramHandlers) playerDeepLink(playerID string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%s", h.bot.Self.UserName, deepLinkPlayerPrefix, playerID)
+}
+
+// HandleInlineQuery отвечает на запрос «@бот <ник>» карточками подходящих игроков.
+// Пустой запрос — недавно заходившие игроки.
+func (h *TelegramHandlers) HandleInlineQuery(ctx context.Context, query *tgbotapi.InlineQuery) {
+	players, err := h.inlinePlayers(ctx, strings.TrimSpace(query.Query))
+	if err != nil {
+		logging.Errorf("Ошибка при поиске игроков для инлайн-запроса %q: %v", query.Query, err)
+		players = nil
+	}
+
+	results := make([]interface{}, 0, len(players))
+	for _, player := range players {
+		card, err := h.playerCard(ctx, player.ID)
+		if err != nil {
+			logging.Errorf("Ошибка при получении карточки игрока %s: %v", player.ID, err)
+			continue
+		}
+
+		status := "🔴 Офлайн"
+		if card.Online {
+			status = "🟢 Онлайн"
+		}
+		article := tgbotapi.NewInlineQueryResultArticle(player.ID, player.Username, card.Text)
+		article.Description = fmt.Sprintf("%s · ⏱ %s · 🎯 %d",
+			status, formatPlayTime(card.Stats.TotalPlayTime), len(card.Stats.Advancements))
+		// В сообщениях инлайн-режима кнопки с callback не дойдут до чата с ботом, поэтому — ссылка
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL("Открыть в боте", h.playerDeepLink(player.ID)),
+			),
+		)
+		article.ReplyMarkup = &keyboard
+		results = append(results, article)
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineCacheTime,
+	}
+	if _, err := h.bot.Request(answer); err != nil {
+		logging.Errorf("Ошибка при ответе на инлайн-запрос: %v", err)
+	}
+}
+
+// inlinePlayers подбирает игроков для инлайн-запроса тем же нечётким поиском, что и команды
+func (h *TelegramHandlers) inlinePlayers(ctx context.Context, name string) ([]models.Player, error) {
+	if name == "" {
+		players, err := h.playerSvc.ListPlayers(ctx, repo.PlayerPage{Order: repo.PlayersByLastSeen, Limit: inlineLimit})
+		if err != nil {
+			return nil, err
+		}
+		result := make([]models.Player, len(players))
+		for i, player := range players {
+			result[i] = player.Player
+		}
+		return result, nil
+	}
+
+	match, err := h.playerSvc.MatchPlayer(ctx, name, inlineLimit)
+	if err != nil {
+		return nil, err
+	}
+	if match.Player != nil {
+		return []models.Player{*match.Player}, nil
+	}
+	return match.Suggestions, nil
+}
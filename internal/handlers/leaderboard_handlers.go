@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Рейтинги открываются командой /top и кнопкой главного меню. Показатель и период
+// хранятся в данных кнопок: top:<показатель>:<период>, период — w, m, a или даты
+// ГГГГММДД-ГГГГММДД включительно.
+
+const (
+	topLimit         = 10 // мест в рейтинге
+	topCommandsLimit = 10 // строк в списке популярных команд
+)
+
+// Показатели /top: названия из API и русские синонимы
+var leaderboardAliases = map[string]string{
+	"время":      service.LeaderboardPlayTime,
+	"сессии":     service.LeaderboardSessions,
+	"команды":    service.LeaderboardCommands,
+	"достижения": service.LeaderboardAdvancements,
+	"рекорд":     service.LeaderboardLongest,
+}
+
+var leaderboardTitles = map[string]string{
+	service.LeaderboardPlayTime:     "⏱ Время в игре",
+	service.LeaderboardSessions:     "🚪 Сессии",
+	service.LeaderboardCommands:     "⌨️ Команды",
+	service.LeaderboardAdvancements: "🎯 Достижения",
+	service.LeaderboardLongest:      "🏅 Самая долгая сессия",
+}
+
+// leaderboardButtons — кнопки показателей по рядам с короткими подписями
+var leaderboardButtons = [][]struct {
+	metric string
+	label  string
+}{
+	{{service.LeaderboardPlayTime, "⏱ Время"}, {service.LeaderboardSessions, "🚪 Сессии"}, {service.LeaderboardLongest, "🏅 Рекорд"}},
+	{{service.LeaderboardCommands, "⌨️ Команды"}, {service.LeaderboardAdvancements, "🎯 Достижения"}},
+}
+
+// Коды календарных периодов в данных кнопок
+const (
+	periodWeek  = "w"
+	periodMonth = "m"
+	periodAll   = "a"
+)
+
+var topPeriods = []struct {
+	code   string
+	period service.LeaderboardPeriod
+	label  string // подпись кнопки
+	title  string // подпись в заголовке рейтинга
+}{
+	{periodWeek, service.PeriodWeek, "Неделя", "за эту неделю"},
+	{periodMonth, service.PeriodMonth, "Месяц", "за этот месяц"},
+	{periodAll, service.PeriodAll, "Всё время", "за всё время"},
+}
+
+// Слова периода в аргументах /top
+var topPeriodAliases = map[string]string{
+	"неделя": periodWeek, "week": periodWeek,
+	"месяц": periodMonth, "month": periodMonth,
+	"всё": periodAll, "все": periodAll, "all": periodAll,
+}
+
+// customPeriodLayout — формат дат произвольного периода в данных кнопок
+const customPeriodLayout = "20060102"
+
+// topPeriod — период рейтинга: код для кнопок, границы и подпись
+type topPeriod struct {
+	Code  string
+	Range repo.TimeRange
+	Title string
+}
+
+// parseTopPeriod разбирает код периода из данных кнопки; пустой код — текущая неделя
+func (h *TelegramHandlers) parseTopPeriod(code string) (topPeriod, bool) {
+	location := h.cfg.Get().App.Location
+	if code == "" {
+		code = periodWeek
+	}
+	for _, option := range topPeriods {
+		if option.code == code {
+			return topPeriod{Code: code, Range: service.PeriodRange(option.period, time.Now(), location), Title: option.title}, true
+		}
+	}
+
+	fromValue, toValue, ok := strings.Cut(code, "-")
+	if !ok {
+		return topPeriod{}, false
+	}
+	from, err := time.ParseInLocation(customPeriodLayout, fromValue, location)
+	if err != nil {
+		return topPeriod{}, false
+	}
+	to, err := time.ParseInLocation(customPeriodLayout, toValue, location)
+	if err != nil || to.Before(from) {
+		return topPeriod{}, false
+	}
+	return customTopPeriod(from, to), true
+}
+
+// customTopPeriod — период с начала дня from до конца дня to
+func customTopPeriod(from, to time.Time) topPeriod {
+	return topPeriod{
+		Code:  from.Format(customPeriodLayout) + "-" + to.Format(customPeriodLayout),
+		Range: repo.TimeRange{From: from, To: to.AddDate(0, 0, 1)},
+		Title: fmt.Sprintf("за %s–%s", from.Format("02.01.2006"), to.Format("02.01.2006")),
+	}
+}
+
+// parseLeaderboardMetric принимает показатель из API или его русский синоним; по умолчанию — время в игре
+func parseLeaderboardMetric(arg string) (string, bool) {
+	arg = strings.ToLower(arg)
+	if arg == "" {
+		return service.LeaderboardPlayTime, true
+	}
+	if metric, ok := leaderboardAliases[arg]; ok {
+		return metric, true
+	}
+	_, ok := leaderboardTitles[arg]
+	return arg, ok
+}
+
+// parseTopArgs разбирает аргументы /top в любом порядке: показатель, слово периода
+// или одну-две даты (ДД.ММ.ГГГГ или ГГГГ-ММ-ДД). Одна дата — период с неё до сегодня.
+func (h *TelegramHandlers) parseTopArgs(args []string) (string, topPeriod, error) {
+	location := h.cfg.Get().App.Location
+	metric, periodCode := "", ""
+	var dates []time.Time
+	for _, arg := range args {
+		lower := strings.ToLower(arg)
+		if code, ok := topPeriodAliases[lower]; ok && periodCode == "" {
+			periodCode = code
+			continue
+		}
+		if date, err := parseTopDate(arg, location); err == nil {
+			dates = append(dates, date)
+			continue
+		}
+		if value, ok := parseLeaderboardMetric(lower); ok && metric == "" {
+			metric = value
+			continue
+		}
+		return "", topPeriod{}, fmt.Errorf("неизвестный аргумент «%s»", arg)
+	}
+	if metric == "" {
+		metric = service.LeaderboardPlayTime
+	}
+
+	switch len(dates) {
+	case 0:
+		period, _ := h.parseTopPeriod(periodCode)
+		return metric, period, nil
+	case 1, 2:
+		if periodCode != "" {
+			return "", topPeriod{}, fmt.Errorf("укажите либо период, либо даты")
+		}
+		from, to := dates[0], time.Now().In(location)
+		if len(dates) == 2 {
+			to = dates[1]
+		}
+		if to.Before(from) {
+			return "", topPeriod{}, fmt.Errorf("конец периода раньше начала")
+		}
+		to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, location)
+		return metric, customTopPeriod(from, to), nil
+	default:
+		return "", topPeriod{}, fmt.Errorf("укажите не больше двух дат")
+	}
+}
+
+func parseTopDate(value string, location *time.Location) (time.Time, error) {
+	if date, err := time.ParseInLocation("02.01.2006", value, location); err == nil {
+		return date, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, location)
+}
+
+// formatLeaderboardValue — значение показателя: длительность для времени, иначе число
+func formatLeaderboardValue(metric string, value int64) string {
+	switch metric {
+	case service.LeaderboardPlayTime, service.LeaderboardLongest:
+		return formatPlayTime(time.Duration(value) * time.Second)
+	default:
+		return strconv.FormatInt(value, 10)
+	}
+}
+
+// showTop показывает рейтинг по показателю за период и место пользователя userID,
+// если его аккаунт привязан к игроку
+func (h *TelegramHandlers) showTop(ctx context.Context, chatID int64, messageID int, userID int64, metric string, period topPeriod) {
+	board, err := h.leaderboardSvc.Leaderboard(ctx, service.LeaderboardQuery{
+		Metric:     metric,
+		Period:     period.Range,
+		Limit:      topLimit,
+		TelegramID: userID,
+	})
+	if err != nil {
+		logging.Errorf("Ошибка при получении рейтинга %s: %v", metric, err)
+		h.sendError(chatID, "Ошибка при получении рейтинга")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("🏆 Рейтинг: " + leaderboardTitles[metric] + " — " + period.Title + "\n\n")
+	if len(board.Entries) == 0 {
+		text.WriteString("Пока нет данных\n")
+	}
+	for _, entry := range board.Entries {
+		text.WriteString(fmt.Sprintf("%d. %s — %s\n", entry.Rank, entry.Player.Username, formatLeaderboardValue(metric, entry.Value)))
+	}
+
+	switch {
+	case userID == 0:
+	case board.Viewer == nil:
+		text.WriteString("\nЧтобы видеть своё место, привяжите ник: /link <ник>")
+	case board.ViewerEntry == nil:
+		text.WriteString(fmt.Sprintf("\n📍 %s: нет в рейтинге %s", board.Viewer.Username, period.Title))
+	default:
+		text.WriteString(fmt.Sprintf("\n📍 %s: %d место из %d — %s", board.Viewer.Username,
+			board.ViewerEntry.Rank, board.Total, formatLeaderboardValue(metric, board.ViewerEntry.Value)))
+	}
+
+	h.show(chatID, messageID, text.String(), topKeyboard(metric, period))
+}
+
+// topKeyboard — кнопки показателей и периодов; выбранные отмечены точкой
+func topKeyboard(metric string, period topPeriod) tgbotapi.InlineKeyboardMarkup {
+	mark := func(label string, selected bool) string {
+		if selected {
+			return "• " + label
+		}
+		return label
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, buttons := range leaderboardButtons {
+		row := make([]tgbotapi.InlineKeyboardButton, len(buttons))
+		for i, button := range buttons {
+			row[i] = tgbotapi.NewInlineKeyboardButtonData(mark(button.label, button.metric == metric),
+				"top:"+button.metric+":"+period.Code)
+		}
+		rows = append(rows, row)
+	}
+
+	var periods []tgbotapi.InlineKeyboardButton
+	custom := true
+	for _, option := range topPeriods {
+		custom = custom && option.code != period.Code
+		periods = append(periods, tgbotapi.NewInlineKeyboardButtonData(mark(option.label, option.code == period.Code),
+			"top:"+metric+":"+option.code))
+	}
+	if custom {
+		// Произвольный период задаётся только командой, кнопка лишь показывает, что он выбран
+		periods = append(periods, tgbotapi.NewInlineKeyboardButtonData(mark("Даты", true), "top:"+metric+":"+period.Code))
+	}
+	rows = append(rows, periods,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⌨️ Популярные команды", "top_commands:"+metric+":"+period.Code),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// showTopCommands показывает самые частые команды за всё время; кнопка «Назад»
+// возвращает к рейтингу, из которого открыт список
+func (h *TelegramHandlers) showTopCommands(ctx context.Context, chatID int64, messageID int, metric string, period topPeriod) {
+	usages, err := h.commandSvc.GetMostUsedCommands(ctx, topCommandsLimit)
+	if err != nil {
+		logging.Errorf("Ошибка при получении популярных команд: %v", err)
+		h.sendError(chatID, "Ошибка при получении популярных команд")
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("⌨️ Популярные команды за всё время\n\n")
+	if len(usages) == 0 {
+		text.WriteString("Пока нет данных")
+	}
+	for i, usage := range usages {
+		text.WriteString(fmt.Sprintf("%d. %s — %d\n", i+1, usage.CommandName, usage.Count))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Назад", "top:"+metric+":"+period.Code),
+		),
+	)
+	h.show(chatID, messageID, text.String(), keyboard)
+}
+
+// handleTopCallback разбирает top:<показатель>:<период> и top_commands:<показатель>:<период>;
+// в старых кнопках периода нет — открывается текущая неделя
+func (h *TelegramHandlers) handleTopCallback(ctx context.Context, chatID int64, messageID int, userID int64, data string) {
+	view, args, _ := strings.Cut(data, ":")
+	metricValue, periodCode, _ := strings.Cut(args, ":")
+	metric, ok := parseLeaderboardMetric(metricValue)
+	if !ok {
+		return
+	}
+	period, ok := h.parseTopPeriod(periodCode)
+	if !ok {
+		return
+	}
+	if view == "top_commands" {
+		h.showTopCommands(ctx, chatID, messageID, metric, period)
+		return
+	}
+	h.showTop(ctx, chatID, messageID, userID, metric, period)
+}
+
+// handleLink привязывает отправителя /link к игроку или, без ника, показывает текущую привязку
+func (h *TelegramHandlers) handleLink(ctx context.Context, message *tgbotapi.Message, userID int64) {
+	chatID := message.Chat.ID
+	if userID == 0 {
+		return
+	}
+	if commandArg(message) != "" {
+		h.withPlayer(ctx, message, "link", func(playerID string) {
+			h.linkPlayer(ctx, chatID, 0, userID, playerID)
+		})
+		return
+	}
+
+	player, err := h.leaderboardSvc.LinkedPlayer(ctx, userID)
+	if err != nil {
+		logging.Errorf("Ошибка при получении привязки пользователя %d: %v", userID, err)
+		h.sendError(chatID, "Ошибка при получении привязки")
+		return
+	}
+	if player == nil {
+		h.sendText(chatID, "Ник не привязан. Укажите его: /link <ник>")
+		return
+	}
+	h.sendText(chatID, fmt.Sprintf("Ваш ник: %s. Сменить: /link <ник>, отвязать: /unlink", player.Username))
+}
+
+// linkPlayer привязывает пользователя userID к игроку и предлагает открыть рейтинги
+func (h *TelegramHandlers) linkPlayer(ctx context.Context, chatID int64, messageID int, userID int64, playerID string) {
+	player, err := h.playerSvc.FindPlayer(ctx, playerID)
+	if err != nil || player == nil {
+		h.sendError(chatID, "Ошибка при получении информации об игроке")
+		return
+	}
+	if err := h.leaderboardSvc.Link(ctx, userID, player.ID); err != nil {
+		if errors.Is(err, service.ErrPlayerLinked) {
+			h.sendText(chatID, fmt.Sprintf("Ник %s уже привязан к другому аккаунту Telegram", player.Username))
+			return
+		}
+		logging.Errorf("Ошибка при привязке пользователя %d к игроку %s: %v", userID, player.ID, err)
+		h.sendError(chatID, "Ошибка при привязке ника")
+		return
+	}
+
+	text := fmt.Sprintf("✅ Ник %s привязан. Теперь в рейтингах видно ваше место.", player.Username)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏆 Рейтинги", "top"),
+		),
+	)
+	h.show(chatID, messageID, text, keyboard)
+}
+
+func (h *TelegramHandlers) unlinkPlayer(ctx context.Context, chatID int64, userID int64) {
+	if userID == 0 {
+		return
+	}
+	if err := h.leaderboardSvc.Unlink(ctx, userID); err != nil {
+		logging.Errorf("Ошибка при отвязке пользователя %d: %v", userID, err)
+		h.sendError(chatID, "Ошибка при отвязке ника")
+		return
+	}
+	h.sendText(chatID, "Ник отвязан")
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Списки бота выводятся страницами: в клавиатуре Telegram ограничено число кнопок,
+// а в сообщении — 4096 символов. Номер страницы и сортировка хранятся в данных кнопок,
+// поэтому листание не требует состояния на стороне бота.
+
+const pageSize = 10 // записей на странице
+
+// noopCallback — данные кнопки-индикатора страницы, нажатие ничего не делает
+const noopCallback = "noop"
+
+// page — положение страницы в списке; номера считаются с нуля
+type page struct {
+	Number int
+	Count  int
+}
+
+// newPage приводит запрошенный номер к существующей странице списка из total записей
+func newPage(number, total int) page {
+	count := max(1, (total+pageSize-1)/pageSize)
+	return page{Number: min(max(number, 0), count-1), Count: count}
+}
+
+// Offset — сколько записей пропустить до начала страницы
+func (p page) Offset() int {
+	return p.Number * pageSize
+}
+
+// paginate возвращает записи страницы number и её положение
+func paginate[T any](items []T, number int) ([]T, page) {
+	p := newPage(number, len(items))
+	start := p.Offset()
+	end := min(start+pageSize, len(items))
+	return items[start:end], p
+}
+
+// row — кнопки «назад», номер страницы и «вперёд»; nil, если страница одна.
+// callback строит данные кнопки для страницы с заданным номером.
+func (p page) row(callback func(number int) string) []tgbotapi.InlineKeyboardButton {
+	if p.Count <= 1 {
+		return nil
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	if p.Number > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", callback(p.Number-1)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", p.Number+1, p.Count), noopCallback))
+	if p.Number < p.Count-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", callback(p.Number+1)))
+	}
+	return row
+}
+
+// parsePage читает номер страницы из данных кнопки; 0, если его нет
+func parsePage(value string) int {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0
+	}
+	return number
+}
+
+// playerSort — порядок списка игроков, код хранится в данных кнопок
+type playerSort string
+
+const (
+	sortByName     playerSort = "n" // по нику
+	sortByLastSeen playerSort = "s" // недавно заходившие первыми
+	sortByPlayTime playerSort = "p" // больше всего времени в игре первыми
+)
+
+var playerSorts = []struct {
+	sort  playerSort
+	order repo.PlayerOrder
+	label string
+}{
+	{sortByName, repo.PlayersByName, "🔤 Ник"},
+	{sortByLastSeen, repo.PlayersByLastSeen, "🕒 Вход"},
+	{sortByPlayTime, repo.PlayersByPlayTime, "⏱ Время"},
+}
+
+// parsePlayerSort читает код сортировки; неизвестный код — сортировка по нику
+func parsePlayerSort(value string) playerSort {
+	for _, option := range playerSorts {
+		if string(option.sort) == value {
+			return option.sort
+		}
+	}
+	return sortByName
+}
+
+// order — порядок выборки игроков для сортировки
+func (s playerSort) order() repo.PlayerOrder {
+	for _, option := range playerSorts {
+		if option.sort == s {
+			return option.order
+		}
+	}
+	return repo.PlayersByName
+}
+
+// sortRow — кнопки выбора сортировки; текущая отмечена точкой. Смена сортировки
+// начинает список с первой страницы.
+func sortRow(current playerSort, callback func(sort playerSort) string) []tgbotapi.InlineKeyboardButton {
+	row := make([]tgbotapi.InlineKeyboardButton, len(playerSorts))
+	for i, option := range playerSorts {
+		label := option.label
+		if option.sort == current {
+			label = "• " + label
+		}
+		row[i] = tgbotapi.NewInlineKeyboardButtonData(label, callback(option.sort))
+	}
+	return row
+}
+
+// sortedPlayer — игрок в списке с подписью показателя, по которому список отсортирован
+type sortedPlayer struct {
+	models.Player
+	Detail string // пусто при сортировке по нику
+}
+
+// Label — текст кнопки игрока
+func (p sortedPlayer) Label() string {
+	if p.Detail == "" {
+		return p.Username
+	}
+	return p.Username + " · " + p.Detail
+}
+
+// playerPage возвращает страницу number списка всех игроков в порядке order,
+// её положение и число игроков
+func (h *TelegramHandlers) playerPage(ctx context.Context, order playerSort, number int) ([]sortedPlayer, page, int64, error) {
+	total, err := h.playerSvc.CountPlayers(ctx)
+	if err != nil {
+		return nil, page{}, 0, err
+	}
+	current := newPage(number, int(total))
+	players, err := h.playerSvc.ListPlayers(ctx, repo.PlayerPage{Order: order.order(), Offset: current.Offset(), Limit: pageSize})
+	if err != nil {
+		return nil, page{}, 0, err
+	}
+
+	result := make([]sortedPlayer, len(players))
+	for i, player := range players {
+		result[i] = sortedPlayer{Player: player.Player}
+		switch order {
+		case sortByLastSeen:
+			result[i].Detail = h.localTime(player.LastSeen).Format("02.01.06")
+			if h.playerSvc.IsPlayerOnline(player.ID) {
+				result[i].Detail = "🟢"
+			}
+		case sortByPlayTime:
+			result[i].Detail = formatPlayTime(time.Duration(player.PlayTime) * time.Second)
+		}
+	}
+	return result, current, total, nil
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"mine-parser/internal/models"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/repo"
 	"mine-parser/internal/service"
 	"strings"
 	"time"
@@ -18,105 +22,218 @@ func (h *TelegramHandlers) sendEditMessage(edit tgbotapi.EditMessageTextConfig)
 		// Игнорируем ошибку "message is not modified" - это нормальная ситуация
 		errStr := err.Error()
 		if !strings.Contains(errStr, "message is not modified") {
-			log.Printf("Ошибка при отправке сообщения: %v", err)
+			logging.Errorf("Ошибка при отправке сообщения: %v", err)
 		}
 	}
 }
 
+// show редактирует сообщение messageID, а если его нет (ответ на команду), отправляет новое
+func (h *TelegramHandlers) show(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	if messageID > 0 {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		edit.ReplyMarkup = &keyboard
+		h.sendEditMessage(edit)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	if _, err := h.bot.Send(msg); err != nil {
+		logging.Errorf("Ошибка при отправке сообщения: %v", err)
+	}
+}
+
 type TelegramHandlers struct {
 	bot             *tgbotapi.BotAPI
+	cfg             *config.Store
 	playerSvc       service.PlayerService
 	commandSvc      service.CommandService
 	advanceSvc      service.AdvancementService
+	statsSvc        service.StatsService
+	leaderboardSvc  service.LeaderboardService
 	notificationSvc service.NotificationService
+	discordSvc      service.DiscordNotificationService
+	webhookSvc      service.WebhookService
+	diagnostics     service.ParseDiagnostics
+	bus             *events.Bus
 }
 
 func NewTelegramHandlers(
 	bot *tgbotapi.BotAPI,
+	cfg *config.Store,
 	playerSvc service.PlayerService,
 	commandSvc service.CommandService,
 	advanceSvc service.AdvancementService,
+	statsSvc service.StatsService,
+	leaderboardSvc service.LeaderboardService,
 	notificationSvc service.NotificationService,
+	discordSvc service.DiscordNotificationService,
+	webhookSvc service.WebhookService,
+	diagnostics service.ParseDiagnostics,
+	bus *events.Bus,
 ) *TelegramHandlers {
 	return &TelegramHandlers{
 		bot:             bot,
+		cfg:             cfg,
 		playerSvc:       playerSvc,
 		commandSvc:      commandSvc,
 		advanceSvc:      advanceSvc,
+		statsSvc:        statsSvc,
+		leaderboardSvc:  leaderboardSvc,
 		notificationSvc: notificationSvc,
+		discordSvc:      discordSvc,
+		webhookSvc:      webhookSvc,
+		diagnostics:     diagnostics,
+		bus:             bus,
 	}
 }
 
-func (h *TelegramHandlers) HandleMessage(message *tgbotapi.Message) {
-	if !message.IsCommand() {
+func (h *TelegramHandlers) HandleMessage(ctx context.Context, message *tgbotapi.Message) {
+	if !message.IsCommand() || h.addressedToOther(message) {
 		return
 	}
 
+	var userID int64
+	if message.From != nil {
+		userID = message.From.ID
+	}
+
+	chatID := message.Chat.ID
 	switch message.Command() {
 	case "start":
-		h.sendMainMenu(message.Chat.ID, 0)
+		// Глубокая ссылка t.me/<бот>?start=player_<UUID> сразу открывает карточку игрока
+		if playerID, ok := strings.CutPrefix(message.CommandArguments(), deepLinkPlayerPrefix); ok {
+			h.showPlayerInfo(ctx, chatID, 0, playerID)
+			return
+		}
+		h.sendMainMenu(chatID, 0, userID)
+	case "help":
+		h.sendHelp(chatID)
+	case "online":
+		h.showOnlinePlayers(chatID, 0)
+	case "player":
+		h.withPlayer(ctx, message, "player", func(playerID string) {
+			h.showPlayerInfo(ctx, chatID, 0, playerID)
+		})
+	case "seen":
+		h.withPlayer(ctx, message, "seen", func(playerID string) {
+			h.showSeen(ctx, chatID, 0, playerID)
+		})
+	case "advancements":
+		h.withPlayer(ctx, message, "advancements", func(playerID string) {
+			h.showAdvancements(ctx, chatID, 0, playerID, 0)
+		})
+	case "top":
+		metric, period, err := h.parseTopArgs(strings.Fields(message.CommandArguments()))
+		if err != nil {
+			h.sendText(chatID, "Не удалось разобрать /top: "+err.Error()+".\nПример: /top время неделя или /top сессии 01.09.2026 30.09.2026")
+			return
+		}
+		h.showTop(ctx, chatID, 0, userID, metric, period)
+	case "link":
+		h.handleLink(ctx, message, userID)
+	case "unlink":
+		h.unlinkPlayer(ctx, chatID, userID)
+	case "stats":
+		h.sendServerStats(ctx, chatID)
 	}
 }
 
-func (h *TelegramHandlers) HandleCallback(callback *tgbotapi.CallbackQuery) {
+func (h *TelegramHandlers) HandleCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	// Отвечаем на callback query немедленно, чтобы убрать индикатор загрузки
 	callbackConfig := tgbotapi.NewCallback(callback.ID, "")
 	if _, err := h.bot.Request(callbackConfig); err != nil {
-		log.Printf("Ошибка при ответе на callback: %v", err)
+		logging.Errorf("Ошибка при ответе на callback: %v", err)
+	}
+
+	// У сообщений, отправленных через инлайн-режим, нет Message — их нельзя изменить по chat_id
+	if callback.Message == nil {
+		return
 	}
 
 	data := callback.Data
 	chatID := callback.Message.Chat.ID
 	messageID := callback.Message.MessageID
+	userID := callback.From.ID
 
-	// Обрабатываем callback асинхронно, чтобы не блокировать основной поток
-	go func() {
-		if data == "back:main" {
-			h.sendMainMenu(chatID, messageID)
-			return
-		}
+	if data == "back:main" {
+		h.sendMainMenu(chatID, messageID, userID)
+		return
+	}
+
+	if strings.HasPrefix(data, "admin:") {
+		h.handleAdminCallback(ctx, chatID, messageID, userID, strings.TrimPrefix(data, "admin:"))
+		return
+	}
 
-		if strings.HasPrefix(data, "player:") {
-			playerID := strings.TrimPrefix(data, "player:")
-			h.showPlayerInfo(chatID, messageID, playerID)
-		} else if strings.HasPrefix(data, "advancements:") {
-			playerID := strings.TrimPrefix(data, "advancements:")
-			h.showAdvancements(chatID, messageID, playerID)
-		} else if strings.HasPrefix(data, "commands:") {
-			playerID := strings.TrimPrefix(data, "commands:")
-			h.showCommands(chatID, messageID, playerID)
-		} else if data == "online" {
-			h.showOnlinePlayers(chatID, messageID)
-		} else if data == "all_players" {
-			h.showAllPlayers(chatID, messageID)
-		} else if data == "connection_guide" {
-			h.showConnectionGuide(chatID, messageID)
-		} else if data == "world_map" {
-			h.showWorldMap(chatID, messageID)
-		} else if data == "notifications" {
-			h.showNotificationsMenu(chatID, messageID)
-		} else if data == "enable_notifications" {
-			h.enableNotifications(chatID, messageID)
-		} else if data == "disable_notifications" {
-			h.disableNotifications(chatID, messageID)
-		} else if data == "blacklist" {
-			h.showBlacklist(chatID, messageID)
-		} else if strings.HasPrefix(data, "blacklist_toggle:") {
-			playerID := strings.TrimPrefix(data, "blacklist_toggle:")
-			h.toggleBlacklistPlayer(chatID, messageID, playerID)
-		} else if data == "back" {
-			h.sendMainMenu(chatID, messageID)
+	if data == noopCallback {
+		return
+	}
+
+	if strings.HasPrefix(data, "player:") {
+		playerID := strings.TrimPrefix(data, "player:")
+		h.showPlayerInfo(ctx, chatID, messageID, playerID)
+	} else if strings.HasPrefix(data, "advancements:") {
+		playerID, number, _ := strings.Cut(strings.TrimPrefix(data, "advancements:"), ":")
+		h.showAdvancements(ctx, chatID, messageID, playerID, parsePage(number))
+	} else if strings.HasPrefix(data, "seen:") {
+		playerID := strings.TrimPrefix(data, "seen:")
+		h.showSeen(ctx, chatID, messageID, playerID)
+	} else if data == "top" || strings.HasPrefix(data, "top:") || strings.HasPrefix(data, "top_commands:") {
+		h.handleTopCallback(ctx, chatID, messageID, userID, data)
+	} else if strings.HasPrefix(data, "link:") {
+		h.linkPlayer(ctx, chatID, messageID, userID, strings.TrimPrefix(data, "link:"))
+	} else if strings.HasPrefix(data, "commands:") {
+		playerID, number, _ := strings.Cut(strings.TrimPrefix(data, "commands:"), ":")
+		h.showCommands(ctx, chatID, messageID, playerID, parsePage(number))
+	} else if strings.HasPrefix(data, "sessions:") {
+		playerID, number, _ := strings.Cut(strings.TrimPrefix(data, "sessions:"), ":")
+		h.showSessions(ctx, chatID, messageID, playerID, parsePage(number))
+	} else if data == "online" {
+		h.showOnlinePlayers(chatID, messageID)
+	} else if data == "all_players" {
+		h.showAllPlayers(ctx, chatID, messageID, sortByName, 0)
+	} else if strings.HasPrefix(data, "players:") {
+		order, number, _ := strings.Cut(strings.TrimPrefix(data, "players:"), ":")
+		h.showAllPlayers(ctx, chatID, messageID, parsePlayerSort(order), parsePage(number))
+	} else if data == "connection_guide" {
+		h.showConnectionGuide(chatID, messageID)
+	} else if data == "world_map" {
+		h.showWorldMap(chatID, messageID)
+	} else if data == "notifications" {
+		h.showNotificationsMenu(ctx, chatID, messageID)
+	} else if data == "enable_notifications" {
+		h.enableNotifications(ctx, chatID, messageID)
+	} else if data == "disable_notifications" {
+		h.disableNotifications(ctx, chatID, messageID)
+	} else if data == "blacklist" {
+		h.showBlacklist(ctx, chatID, messageID, sortByName, 0)
+	} else if strings.HasPrefix(data, "blacklist:") {
+		order, number, _ := strings.Cut(strings.TrimPrefix(data, "blacklist:"), ":")
+		h.showBlacklist(ctx, chatID, messageID, parsePlayerSort(order), parsePage(number))
+	} else if strings.HasPrefix(data, "blacklist_toggle:") {
+		// blacklist_toggle:<UUID>:<сортировка>:<страница> — после переключения остаёмся на той же странице
+		parts := strings.SplitN(strings.TrimPrefix(data, "blacklist_toggle:"), ":", 3)
+		order, number := sortByName, 0
+		if len(parts) == 3 {
+			order, number = parsePlayerSort(parts[1]), parsePage(parts[2])
 		}
-	}()
+		h.toggleBlacklistPlayer(ctx, chatID, messageID, parts[0], order, number)
+	} else if data == "back" {
+		h.sendMainMenu(chatID, messageID, userID)
+	}
 }
 
-func (h *TelegramHandlers) sendMainMenu(chatID int64, messageID int) {
-	text := "📊 Статистика сервера Minecraft\n\nВыберите раздел:"
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+func (h *TelegramHandlers) sendMainMenu(chatID int64, messageID int, userID int64) {
+	cfg := h.cfg.Get()
+	text := h.renderText(cfg.Texts.MainMenu, cfg.ServersView())
+	rows := [][]tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("👥 Онлайн игроки", "online"),
 			tgbotapi.NewInlineKeyboardButtonData("📜 Все игроки", "all_players"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏆 Рейтинги", "top"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🗺 Карта мира", "world_map"),
 		),
@@ -126,7 +243,18 @@ func (h *TelegramHandlers) sendMainMenu(chatID int64, messageID int) {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("🔔 Уведомления", "notifications"),
 		),
-	)
+	}
+
+	// Служебные разделы видят только администраторы
+	if cfg.Tg.IsAdmin(userID) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛠 Диагностика парсера", "admin:diagnostics"),
+			tgbotapi.NewInlineKeyboardButtonData("🔗 Вебхуки", "admin:webhooks"),
+		), tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💬 Discord", "admin:discord"),
+		))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	var sentMsg tgbotapi.Chattable
 	if messageID > 0 {
@@ -141,41 +269,50 @@ func (h *TelegramHandlers) sendMainMenu(chatID int64, messageID int) {
 		sentMsg = msg
 	}
 	if _, err := h.bot.Send(sentMsg); err != nil {
-		log.Printf("Ошибка при отправке сообщения: %v", err)
+		logging.Errorf("Ошибка при отправке сообщения: %v", err)
 	}
 }
 
 func (h *TelegramHandlers) showOnlinePlayers(chatID int64, messageID int) {
-	players, err := h.playerSvc.ListOnlinePlayers()
-	if err != nil {
-		h.sendError(chatID, "Ошибка при получении списка игроков")
-		return
-	}
+	online := h.playerSvc.ListOnlinePlayers()
 
-	if len(players) == 0 {
+	if len(online) == 0 {
 		text := "Нет игроков онлайн"
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
 			),
 		)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		edit.ReplyMarkup = &keyboard
-		h.sendEditMessage(edit)
+		h.show(chatID, messageID, text, keyboard)
 		return
 	}
 
-	h.showPlayerList(chatID, messageID, players, "Игроки онлайн")
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, presence := range online {
+		buttonText := fmt.Sprintf("%s · с %s", presence.Username, h.localTime(presence.Since).Format("15:04"))
+		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("player:%s", presence.PlayerID))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	text := fmt.Sprintf("Игроки онлайн (%d):\nВыберите игрока", len(online))
+
+	h.show(chatID, messageID, text, keyboard)
 }
 
-func (h *TelegramHandlers) showAllPlayers(chatID int64, messageID int) {
-	players, err := h.playerSvc.ListAllPlayers()
+func (h *TelegramHandlers) showAllPlayers(ctx context.Context, chatID int64, messageID int, order playerSort, number int) {
+	players, current, total, err := h.playerPage(ctx, order, number)
 	if err != nil {
+		logging.Errorf("Ошибка при получении списка игроков: %v", err)
 		h.sendError(chatID, "Ошибка при получении списка игроков")
 		return
 	}
 
-	if len(players) == 0 {
+	if total == 0 {
 		text := "Нет игроков в базе"
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
@@ -188,15 +325,19 @@ func (h *TelegramHandlers) showAllPlayers(chatID int64, messageID int) {
 		return
 	}
 
-	h.showPlayerList(chatID, messageID, players, "Все игроки")
+	h.showPlayerList(chatID, messageID, players, current, order, fmt.Sprintf("Все игроки (%d)", total))
 }
 
-func (h *TelegramHandlers) showPlayerList(chatID int64, messageID int, players []models.Player, title string) {
+func (h *TelegramHandlers) showPlayerList(chatID int64, messageID int, players []sortedPlayer, current page, order playerSort, title string) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, player := range players {
-		button := tgbotapi.NewInlineKeyboardButtonData(player.Username, fmt.Sprintf("player:%s", player.ID))
+		button := tgbotapi.NewInlineKeyboardButtonData(player.Label(), fmt.Sprintf("player:%s", player.ID))
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
+	if row := current.row(func(number int) string { return fmt.Sprintf("players:%s:%d", order, number) }); row != nil {
+		rows = append(rows, row)
+	}
+	rows = append(rows, sortRow(order, func(order playerSort) string { return fmt.Sprintf("players:%s:0", order) }))
 
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
@@ -210,39 +351,64 @@ func (h *TelegramHandlers) showPlayerList(chatID int64, messageID int, players [
 	h.sendEditMessage(edit)
 }
 
-func (h *TelegramHandlers) showPlayerInfo(chatID int64, messageID int, playerID string) {
-	player, err := h.playerSvc.GetPlayerStats(playerID)
+func (h *TelegramHandlers) showPlayerInfo(ctx context.Context, chatID int64, messageID int, playerID string) {
+	card, err := h.playerCard(ctx, playerID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении информации об игроке")
 		return
 	}
 
-	isOnline, err := h.playerSvc.IsPlayerOnline(playerID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Достижения", fmt.Sprintf("advancements:%s", playerID)),
+			tgbotapi.NewInlineKeyboardButtonData("Команды", fmt.Sprintf("commands:%s", playerID)),
+			tgbotapi.NewInlineKeyboardButtonData("Сессии", fmt.Sprintf("sessions:%s", playerID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			// Открывает выбор чата и подставляет «@бот <ник>» — карточка уходит через инлайн-режим
+			tgbotapi.NewInlineKeyboardButtonSwitch("📤 Поделиться", card.Stats.Player.Username),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+		),
+	)
+
+	h.show(chatID, messageID, card.Text, keyboard)
+}
+
+// playerCardView — карточка игрока: полный текст и данные для краткого описания
+type playerCardView struct {
+	Text   string
+	Online bool
+	Stats  *service.PlayerStats
+}
+
+// playerCard собирает текст карточки игрока для бота и инлайн-режима
+func (h *TelegramHandlers) playerCard(ctx context.Context, playerID string) (playerCardView, error) {
+	player, err := h.playerSvc.GetPlayerStats(ctx, playerID)
 	if err != nil {
-		h.sendError(chatID, "Ошибка при проверке статуса игрока")
-		return
+		return playerCardView{}, err
 	}
 
+	presence, isOnline := h.playerSvc.GetPresence(playerID)
+
 	var statusText string
 	var lastSessionText string
 
 	if isOnline {
 		statusText = "🟢 Онлайн"
-		lastSession, err := h.playerSvc.GetLastSession(playerID)
-		if err == nil && lastSession != nil {
-			lastSessionText = fmt.Sprintf("Время входа: %s", lastSession.JoinTime.Format("02.01.2006 15:04"))
-		}
+		lastSessionText = fmt.Sprintf("Время входа: %s", h.localTime(presence.Since).Format("02.01.2006 15:04"))
 	} else {
 		statusText = "🔴 Офлайн"
-		lastSession, err := h.playerSvc.GetLastSession(playerID)
+		lastSession, err := h.playerSvc.GetLastSession(ctx, playerID)
 		if err == nil && lastSession != nil {
 			if lastSession.LeaveTime != nil {
 				lastSessionText = fmt.Sprintf("Последний вход: %s\nВремя выхода: %s",
-					lastSession.JoinTime.Format("02.01.2006 15:04"),
-					lastSession.LeaveTime.Format("02.01.2006 15:04"))
+					h.localTime(lastSession.JoinTime).Format("02.01.2006 15:04"),
+					h.localTime(*lastSession.LeaveTime).Format("02.01.2006 15:04"))
 			} else {
 				lastSessionText = fmt.Sprintf("Последний вход: %s",
-					lastSession.JoinTime.Format("02.01.2006 15:04"))
+					h.localTime(lastSession.JoinTime).Format("02.01.2006 15:04"))
 			}
 		}
 	}
@@ -256,29 +422,17 @@ func (h *TelegramHandlers) showPlayerInfo(chatID int64, messageID int, playerID
 		lastSessionText,
 		totalHours)
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Достижения", fmt.Sprintf("advancements:%s", playerID)),
-			tgbotapi.NewInlineKeyboardButtonData("Команды", fmt.Sprintf("commands:%s", playerID)),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
-		),
-	)
-
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	edit.ReplyMarkup = &keyboard
-	h.sendEditMessage(edit)
+	return playerCardView{Text: text, Online: isOnline, Stats: player}, nil
 }
 
-func (h *TelegramHandlers) showAdvancements(chatID int64, messageID int, playerID string) {
-	advancements, err := h.advanceSvc.GetPlayerAdvancements(playerID)
+func (h *TelegramHandlers) showAdvancements(ctx context.Context, chatID int64, messageID int, playerID string, number int) {
+	advancements, err := h.advanceSvc.GetPlayerAdvancements(ctx, playerID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении достижений")
 		return
 	}
 
-	player, err := h.playerSvc.GetPlayerStats(playerID)
+	player, err := h.playerSvc.GetPlayerStats(ctx, playerID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении информации об игроке")
 		return
@@ -291,46 +445,43 @@ func (h *TelegramHandlers) showAdvancements(chatID int64, messageID int, playerI
 				tgbotapi.NewInlineKeyboardButtonData("Назад к игроку", fmt.Sprintf("player:%s", playerID)),
 			),
 		)
-		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-		edit.ReplyMarkup = &keyboard
-		h.sendEditMessage(edit)
+		h.show(chatID, messageID, text, keyboard)
 		return
 	}
 
+	advancements, current := paginate(advancements, number)
 	var advText strings.Builder
-	advText.WriteString(fmt.Sprintf("🎯 Достижения игрока %s:\n\n", player.Player.Username))
+	advText.WriteString(fmt.Sprintf("🎯 Достижения игрока %s (%d):\n\n", player.Player.Username, len(player.Advancements)))
 	for i, adv := range advancements {
 		advText.WriteString(fmt.Sprintf("%d. %s\n   Получено: %s\n\n",
-			i+1,
+			current.Offset()+i+1,
 			adv.AdvancementName,
-			adv.Timestamp.Format("02.01.2006 15:04")))
+			h.localTime(adv.Timestamp).Format("02.01.2006 15:04")))
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Назад к игроку", fmt.Sprintf("player:%s", playerID)),
-		),
-	)
-
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, advText.String())
-	edit.ReplyMarkup = &keyboard
-	h.sendEditMessage(edit)
+	h.show(chatID, messageID, advText.String(), playerPageKeyboard(playerID, current, "advancements"))
 }
 
-func (h *TelegramHandlers) showCommands(chatID int64, messageID int, playerID string) {
-	commands, err := h.commandSvc.GetCommandHistory(playerID, 50)
-	if err != nil {
-		h.sendError(chatID, "Ошибка при получении команд")
-		return
+// playerPageKeyboard — листание списка игрока (callback <view>:<UUID>:<страница>) и возврат к игроку
+func playerPageKeyboard(playerID string, current page, view string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if row := current.row(func(number int) string { return fmt.Sprintf("%s:%s:%d", view, playerID, number) }); row != nil {
+		rows = append(rows, row)
 	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад к игроку", fmt.Sprintf("player:%s", playerID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-	player, err := h.playerSvc.GetPlayerStats(playerID)
+func (h *TelegramHandlers) showCommands(ctx context.Context, chatID int64, messageID int, playerID string, number int) {
+	player, err := h.playerSvc.GetPlayerStats(ctx, playerID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении информации об игроке")
 		return
 	}
 
-	if len(commands) == 0 {
+	if player.CommandsUsed == 0 {
 		text := fmt.Sprintf("⌨️ Команды игрока %s:\n\nНет команд", player.Player.Username)
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
@@ -343,49 +494,73 @@ func (h *TelegramHandlers) showCommands(chatID int64, messageID int, playerID st
 		return
 	}
 
+	current := newPage(number, int(player.CommandsUsed))
+	commands, err := h.commandSvc.ListCommands(ctx, repo.CommandFilter{PlayerID: playerID, Offset: current.Offset(), Limit: pageSize})
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении команд")
+		return
+	}
+
 	var cmdText strings.Builder
-	cmdText.WriteString(fmt.Sprintf("⌨️ Команды игрока %s:\n\n", player.Player.Username))
+	cmdText.WriteString(fmt.Sprintf("⌨️ Команды игрока %s (%d):\n\n", player.Player.Username, player.CommandsUsed))
 	for i, cmd := range commands {
-		if i >= 20 { // Ограничиваем вывод 20 командами
-			cmdText.WriteString(fmt.Sprintf("\n... и еще %d команд", len(commands)-20))
-			break
-		}
 		cmdText.WriteString(fmt.Sprintf("%d. %s\n   Время: %s\n\n",
-			i+1,
+			current.Offset()+i+1,
 			cmd.Command,
-			cmd.Timestamp.Format("02.01.2006 15:04")))
+			h.localTime(cmd.Timestamp).Format("02.01.2006 15:04")))
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Назад к игроку", fmt.Sprintf("player:%s", playerID)),
-		),
-	)
-
 	edit := tgbotapi.NewEditMessageText(chatID, messageID, cmdText.String())
+	keyboard := playerPageKeyboard(playerID, current, "commands")
 	edit.ReplyMarkup = &keyboard
 	h.sendEditMessage(edit)
 }
 
-func (h *TelegramHandlers) showConnectionGuide(chatID int64, messageID int) {
-	text := `📖 Инструкция по подключению к серверу
-
-Добро пожаловать на наш сервер Minecraft!
-
-Для подключения к серверу выполните следующие шаги:
-
-1️⃣ Скачайте и установите Minecraft версии 1.21.4
-
-2️⃣ Запустите игру и выберите "Сетевая игра" (Multiplayer)
+// showSessions показывает сессии игрока от новых к старым
+func (h *TelegramHandlers) showSessions(ctx context.Context, chatID int64, messageID int, playerID string, number int) {
+	player, err := h.playerSvc.GetPlayerStats(ctx, playerID)
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении информации об игроке")
+		return
+	}
 
-3️⃣ Нажмите "Добавить сервер" (Add Server)
+	current := newPage(number, player.SessionCount)
+	sessions, err := h.playerSvc.ListSessions(ctx, repo.SessionFilter{PlayerID: playerID, Offset: current.Offset(), Limit: pageSize})
+	if err != nil {
+		h.sendError(chatID, "Ошибка при получении сессий")
+		return
+	}
 
-4️⃣ Введите IP-адрес сервера:
-   🌐 89.169.161.207
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🚪 Сессии игрока %s (%d):\n\n", player.Player.Username, player.SessionCount))
+	if len(sessions) == 0 {
+		text.WriteString("Нет сессий")
+	}
+	multiServer := len(h.cfg.Get().Servers) > 1
+	for i, session := range sessions {
+		join := h.localTime(session.JoinTime)
+		line := fmt.Sprintf("%d. %s", current.Offset()+i+1, join.Format("02.01.2006 15:04"))
+		if session.LeaveTime != nil {
+			line += fmt.Sprintf(" – %s (%s)", h.localTime(*session.LeaveTime).Format("15:04"),
+				formatPlayTime(session.LeaveTime.Sub(session.JoinTime)))
+		} else {
+			line += " — сейчас в игре"
+		}
+		if multiServer && session.Server != "" {
+			line += " · " + session.Server
+		}
+		text.WriteString(line + "\n")
+	}
 
-5️⃣ Нажмите "Готово" и подключитесь к серверу
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text.String())
+	keyboard := playerPageKeyboard(playerID, current, "sessions")
+	edit.ReplyMarkup = &keyboard
+	h.sendEditMessage(edit)
+}
 
-Удачной игры! 🎮`
+func (h *TelegramHandlers) showConnectionGuide(chatID int64, messageID int) {
+	cfg := h.cfg.Get()
+	text := h.renderText(cfg.Texts.ConnectionGuide, cfg.ServersView())
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -399,31 +574,59 @@ func (h *TelegramHandlers) showConnectionGuide(chatID int64, messageID int) {
 }
 
 func (h *TelegramHandlers) showWorldMap(chatID int64, messageID int) {
-	text := `🗺 Карта мира
+	cfg := h.cfg.Get()
+	text := h.renderText(cfg.Texts.WorldMap, cfg.ServersView())
 
-В этом разделе вы можете посмотреть карту мира, которая обновляется в реальном времени и показывает положение всех игроков на сервере.`
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("🌐 Открыть карту", "https://minecraft.shapedby.ru"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
-		),
-	)
+	// Кнопка карты для каждого сервера, у которого она есть
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, server := range cfg.Servers {
+		if server.MapURL == "" {
+			continue
+		}
+		label := "🌐 Открыть карту"
+		if len(cfg.Servers) > 1 {
+			label += " — " + server.Name
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(label, server.MapURL),
+		))
+	}
+	if len(rows) == 0 {
+		text += "\n\nКарта пока не настроена."
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Назад", "back"),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
 	edit.ReplyMarkup = &keyboard
 	h.sendEditMessage(edit)
 }
 
+// renderText подставляет данные в текст из конфигурации. Шаблоны проверены при загрузке,
+// поэтому при ошибке показываем текст как есть, а не пустое сообщение.
+func (h *TelegramHandlers) renderText(text string, data any) string {
+	rendered, err := config.Render(text, data)
+	if err != nil {
+		logging.Errorf("Ошибка в шаблоне текста: %v", err)
+		return text
+	}
+	return rendered
+}
+
 func (h *TelegramHandlers) sendError(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, "❌ "+text)
 	if _, err := h.bot.Send(msg); err != nil {
-		log.Printf("Ошибка при отправке сообщения: %v", err)
+		logging.Errorf("Ошибка при отправке сообщения: %v", err)
 	}
 }
 
+// localTime переводит время из БД в часовой пояс, в котором его показываем пользователям
+func (h *TelegramHandlers) localTime(t time.Time) time.Time {
+	return t.In(h.cfg.Get().App.Location)
+}
+
 // formatPlayTime форматирует время игры в формат "X.Xч"
 func formatPlayTime(duration time.Duration) string {
 	hours := duration.Hours()
@@ -431,16 +634,16 @@ func formatPlayTime(duration time.Duration) string {
 	return fmt.Sprintf("%.1fч", hours)
 }
 
-func (h *TelegramHandlers) showBlacklist(chatID int64, messageID int) {
-	// Получаем всех игроков
-	players, err := h.playerSvc.ListAllPlayers()
+func (h *TelegramHandlers) showBlacklist(ctx context.Context, chatID int64, messageID int, order playerSort, number int) {
+	sorted, current, _, err := h.playerPage(ctx, order, number)
 	if err != nil {
+		logging.Errorf("Ошибка при получении списка игроков: %v", err)
 		h.sendError(chatID, "Ошибка при получении списка игроков")
 		return
 	}
 
 	// Получаем черный список для этого чата
-	blacklist, err := h.notificationSvc.GetBlacklist(chatID)
+	blacklist, err := h.notificationSvc.GetBlacklist(ctx, chatID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении черного списка")
 		return
@@ -452,19 +655,23 @@ func (h *TelegramHandlers) showBlacklist(chatID int64, messageID int) {
 		blacklistMap[item.PlayerID] = true
 	}
 
-	text := "🚫 Черный список\n\nЧерный список нужен для того, чтобы не получать уведомления, когда конкретный игрок заходит на сервер.\n\nВыберите игрока:"
+	text := fmt.Sprintf("🚫 Черный список (%d)\n\nЧерный список нужен для того, чтобы не получать уведомления, когда конкретный игрок заходит на сервер.\n\nВыберите игрока:", len(blacklist))
 
 	var rows [][]tgbotapi.InlineKeyboardButton
-	for _, player := range players {
+	for _, player := range sorted {
 		// Определяем иконку: 🔔 если не в черном списке, 🔕 если в черном списке
 		icon := "🔔"
 		if blacklistMap[player.ID] {
 			icon = "🔕"
 		}
-		buttonText := fmt.Sprintf("%s %s", icon, player.Username)
-		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("blacklist_toggle:%s", player.ID))
+		buttonText := fmt.Sprintf("%s %s", icon, player.Label())
+		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("blacklist_toggle:%s:%s:%d", player.ID, order, current.Number))
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
+	if row := current.row(func(number int) string { return fmt.Sprintf("blacklist:%s:%d", order, number) }); row != nil {
+		rows = append(rows, row)
+	}
+	rows = append(rows, sortRow(order, func(order playerSort) string { return fmt.Sprintf("blacklist:%s:0", order) }))
 
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Назад", "notifications"),
@@ -477,20 +684,20 @@ func (h *TelegramHandlers) showBlacklist(chatID int64, messageID int) {
 	h.sendEditMessage(edit)
 }
 
-func (h *TelegramHandlers) toggleBlacklistPlayer(chatID int64, messageID int, playerID string) {
+func (h *TelegramHandlers) toggleBlacklistPlayer(ctx context.Context, chatID int64, messageID int, playerID string, order playerSort, number int) {
 	// Переключаем статус в черном списке
-	_, err := h.notificationSvc.ToggleBlacklist(chatID, playerID)
+	_, err := h.notificationSvc.ToggleBlacklist(ctx, chatID, playerID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при изменении черного списка")
 		return
 	}
 
-	// Показываем обновленный черный список
-	h.showBlacklist(chatID, messageID)
+	// Показываем обновленный черный список на той же странице
+	h.showBlacklist(ctx, chatID, messageID, order, number)
 }
 
-func (h *TelegramHandlers) showNotificationsMenu(chatID int64, messageID int) {
-	status, err := h.notificationSvc.GetSubscriptionStatus(chatID)
+func (h *TelegramHandlers) showNotificationsMenu(ctx context.Context, chatID int64, messageID int) {
+	status, err := h.notificationSvc.GetSubscriptionStatus(ctx, chatID)
 	if err != nil {
 		h.sendError(chatID, "Ошибка при получении статуса уведомлений")
 		return
@@ -535,10 +742,10 @@ func (h *TelegramHandlers) showNotificationsMenu(chatID int64, messageID int) {
 	h.sendEditMessage(edit)
 }
 
-func (h *TelegramHandlers) enableNotifications(chatID int64, messageID int) {
-	enabled, err := h.notificationSvc.ToggleSubscription(chatID)
+func (h *TelegramHandlers) enableNotifications(ctx context.Context, chatID int64, messageID int) {
+	enabled, err := h.notificationSvc.ToggleSubscription(ctx, chatID)
 	if err != nil {
-		log.Printf("Ошибка при включении уведомлений для чата %d: %v", chatID, err)
+		logging.Errorf("Ошибка при включении уведомлений для чата %d: %v", chatID, err)
 		h.sendError(chatID, "Ошибка при включении уведомлений")
 		return
 	}
@@ -546,13 +753,13 @@ func (h *TelegramHandlers) enableNotifications(chatID int64, messageID int) {
 	log.Printf("Уведомления для чата %d переключены, новый статус: %v", chatID, enabled)
 
 	// Показываем обновленное меню уведомлений
-	h.showNotificationsMenu(chatID, messageID)
+	h.showNotificationsMenu(ctx, chatID, messageID)
 }
 
-func (h *TelegramHandlers) disableNotifications(chatID int64, messageID int) {
-	enabled, err := h.notificationSvc.ToggleSubscription(chatID)
+func (h *TelegramHandlers) disableNotifications(ctx context.Context, chatID int64, messageID int) {
+	enabled, err := h.notificationSvc.ToggleSubscription(ctx, chatID)
 	if err != nil {
-		log.Printf("Ошибка при выключении уведомлений для чата %d: %v", chatID, err)
+		logging.Errorf("Ошибка при выключении уведомлений для чата %d: %v", chatID, err)
 		h.sendError(chatID, "Ошибка при выключении уведомлений")
 		return
 	}
@@ -560,5 +767,5 @@ func (h *TelegramHandlers) disableNotifications(chatID int64, messageID int) {
 	log.Printf("Уведомления для чата %d переключены, новый статус: %v", chatID, enabled)
 
 	// Показываем обновленное меню уведомлений
-	h.showNotificationsMenu(chatID, messageID)
+	h.showNotificationsMenu(ctx, chatID, messageID)
 }
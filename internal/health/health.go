@@ -0,0 +1,131 @@
+// Package health собирает состояние компонентов для проверок живости и готовности
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status — итог проверки
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // работает, но с отклонениями
+	StatusFailed   Status = "failed"
+)
+
+func (s Status) severity() int {
+	switch s {
+	case StatusDegraded:
+		return 1
+	case StatusFailed:
+		return 2
+	}
+	return 0
+}
+
+// Result — результат одной проверки
+type Result struct {
+	Status  Status         `json:"status"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func OK(details map[string]any) Result {
+	return Result{Status: StatusOK, Details: details}
+}
+
+func Degraded(details map[string]any, format string, args ...any) Result {
+	return Result{Status: StatusDegraded, Message: fmt.Sprintf(format, args...), Details: details}
+}
+
+func Failed(details map[string]any, format string, args ...any) Result {
+	return Result{Status: StatusFailed, Message: fmt.Sprintf(format, args...), Details: details}
+}
+
+// Check — именованная проверка компонента
+type Check struct {
+	Name string
+	// Liveness — проверка входит в /healthz: её провал означает, что процесс нужно перезапустить.
+	// Остальные проверки (например, доступность БД) влияют только на готовность.
+	Liveness bool
+	Run      func(ctx context.Context) Result
+}
+
+// Report — сводка проверок; общий статус равен худшему из результатов
+type Report struct {
+	Status    Status            `json:"status"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Registry хранит проверки и выполняет их параллельно
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []Check
+	timeout func() time.Duration
+}
+
+// NewRegistry создаёт реестр; timeout ограничивает время одной проверки
+func NewRegistry(timeout func() time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// Run выполняет проверки (только проверки живости, если livenessOnly).
+// Проверка, не уложившаяся в таймаут, считается проваленной.
+func (r *Registry) Run(ctx context.Context, livenessOnly bool) Report {
+	r.mu.RLock()
+	checks := make([]Check, 0, len(r.checks))
+	for _, check := range r.checks {
+		if !livenessOnly || check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+
+	timeout := r.timeout()
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runWithTimeout(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: time.Now().UTC(), Checks: make(map[string]Result, len(checks))}
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status.severity() > report.Status.severity() {
+			report.Status = results[i].Status
+		}
+	}
+	return report
+}
+
+func runWithTimeout(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() { done <- check.Run(ctx) }()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return Failed(nil, "проверка не уложилась в %s", timeout)
+	}
+}
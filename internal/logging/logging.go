@@ -0,0 +1,90 @@
+// Package logging добавляет уровни к стандартному логу. Обычные сообщения пишутся
+// через log.Printf и считаются информационными; предупреждения и ошибки — через
+// Warnf и Errorf, подробности для отладки — через Debugf.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level — минимальный уровень сообщений, попадающих в лог
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel разбирает уровень по имени: debug, info, warn или error
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("неизвестный уровень логирования %q (допустимо: debug, info, warn, error)", name)
+}
+
+var (
+	current atomic.Int32
+	output  = os.Stderr
+	// Предупреждения и ошибки пишутся отдельным логгером, чтобы не зависеть
+	// от отключения стандартного лога на уровнях выше info
+	leveled = log.New(output, "", log.LstdFlags)
+)
+
+func init() {
+	current.Store(int32(LevelInfo))
+}
+
+// SetLevel задаёт минимальный уровень. Выше info стандартный лог отключается.
+func SetLevel(level Level) {
+	current.Store(int32(level))
+	if level > LevelInfo {
+		log.SetOutput(io.Discard)
+	} else {
+		log.SetOutput(output)
+	}
+}
+
+// Enabled сообщает, пишутся ли сообщения уровня level
+func Enabled(level Level) bool {
+	return level >= Level(current.Load())
+}
+
+func Debugf(format string, args ...any) {
+	write(LevelDebug, "DEBUG ", format, args)
+}
+
+func Warnf(format string, args ...any) {
+	write(LevelWarn, "WARN ", format, args)
+}
+
+func Errorf(format string, args ...any) {
+	write(LevelError, "ERROR ", format, args)
+}
+
+func write(level Level, prefix, format string, args []any) {
+	if !Enabled(level) {
+		return
+	}
+	leveled.Print(prefix + fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,49 @@
+// Package metrics — метрики приложения в формате Prometheus.
+// Здесь объявлены метрики, которые обновляются в момент события; величины,
+// которые проще прочитать из состояния компонентов, собирает коллектор приложения при опросе.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace — общий префикс имён метрик
+const Namespace = "mclog"
+
+var (
+	// DBQueryDuration — время запросов к БД по репозиторию и методу
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Время выполнения запросов к БД по методам репозиториев.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+
+	// TelegramRequests — запросы к Bot API (кроме получения обновлений) по методу и результату
+	TelegramRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "telegram_requests_total",
+		Help:      "Запросы к Telegram Bot API по методу и результату: ok или класс ошибки.",
+	}, []string{"method", "result"})
+)
+
+// Handler отдаёт метрики процесса, метрики пакета и метрики переданных коллекторов
+func Handler(extra ...prometheus.Collector) (http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	all := append([]prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		DBQueryDuration,
+		TelegramRequests,
+	}, extra...)
+	for _, collector := range all {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
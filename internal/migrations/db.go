@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mine-parser/internal/config"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// sqlitePragmas включают внешние ключи, ожидание блокировки вместо ошибки и журнал WAL
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// DialectOf определяет СУБД по DATABASE_URL: sqlite://path.db или строка подключения Postgres
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, config.SQLiteScheme) {
+		return DialectSQLite
+	}
+	return DialectPostgres
+}
+
+// Open подключается к БД без изменения схемы
+func Open(dsn string) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+
+	switch DialectOf(dsn) {
+	case DialectSQLite:
+		path := strings.TrimPrefix(dsn, config.SQLiteScheme)
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		// Драйвер сохраняет время вместе со смещением и сравнивает его как текст,
+		// поэтому всё время в SQLite пишется в UTC
+		db, err = gorm.Open(sqlite.Open(path+separator+sqlitePragmas), &gorm.Config{
+			NowFunc: func() time.Time { return time.Now().UTC() },
+		})
+		if err == nil {
+			// SQLite допускает одного писателя: общий пул из одного соединения
+			// избавляет от ошибок "database is locked" между горутинами
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				sqlDB.SetMaxOpenConns(1)
+			}
+		}
+	default:
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось подключиться к БД: %w", err)
+	}
+	return db, nil
+}
+
+// InitDB подключается к БД и, если autoMigrate включён, применяет недостающие миграции
+func InitDB(dsn string, autoMigrate bool) (*gorm.DB, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if !autoMigrate {
+		return db, nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := NewMigrator(sqlDB, DialectOf(dsn))
+	if err != nil {
+		return nil, err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка миграции: %w", err)
+	}
+
+	if applied > 0 {
+		log.Printf("Применено миграций: %d", applied)
+	} else {
+		log.Println("Схема БД актуальна.")
+	}
+	return db, nil
+}
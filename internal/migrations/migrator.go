@@ -0,0 +1,334 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"mine-parser/internal/logging"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql
+var migrationFiles embed.FS
+
+// Dialect — тип СУБД; у каждого свой набор миграций с общей нумерацией версий
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// migrationLockKey — ключ advisory-блокировки: одновременно миграции выполняет только один экземпляр
+const migrationLockKey int64 = 0x6d696e65 // "mine"
+
+var (
+	migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	placeholderRe   = regexp.MustCompile(`\$\d+`)
+)
+
+// Migration — пронумерованная миграция схемы или данных
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus — состояние миграции в базе
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Missing   bool // применена в базе, но отсутствует в сборке
+}
+
+// Migrator применяет и откатывает встроенные SQL-миграции.
+// Применённые версии хранятся в таблице schema_version.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// NewMigrator загружает встроенные миграции для указанной СУБД
+func NewMigrator(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := loadMigrations(path.Join("sql", string(dialect)))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать миграции: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileRe.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("неверное имя файла миграции %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("у миграции %d разные имена: %s и %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет файла up или down", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up применяет все ещё не применённые миграции и возвращает их число
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down откатывает последние steps применённых миграций и возвращает их число
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		count, err = m.down(ctx, conn, steps)
+		return err
+	})
+	return count, err
+}
+
+// Redo откатывает и заново применяет последнюю миграцию
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		last, ok := m.lastApplied(applied)
+		if !ok {
+			return fmt.Errorf("нет применённых миграций")
+		}
+		if err := m.apply(ctx, conn, last, false); err != nil {
+			return err
+		}
+		return m.apply(ctx, conn, last, true)
+	})
+}
+
+// Status возвращает состояние всех известных и применённых миграций
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var result []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[int]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if record, ok := applied[migration.Version]; ok {
+				appliedAt := record.appliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			result = append(result, status)
+		}
+		for version, record := range applied {
+			if !known[version] {
+				appliedAt := record.appliedAt
+				result = append(result, MigrationStatus{
+					Version: version, Name: record.name, Applied: true, AppliedAt: &appliedAt, Missing: true,
+				})
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+		return nil
+	})
+	return result, err
+}
+
+type appliedRecord struct {
+	name      string
+	appliedAt time.Time
+}
+
+func (m *Migrator) down(ctx context.Context, conn *sql.Conn, steps int) (int, error) {
+	count := 0
+	for ; count < steps; count++ {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return count, err
+		}
+		last, ok := m.lastApplied(applied)
+		if !ok {
+			break
+		}
+		if err := m.apply(ctx, conn, last, false); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// lastApplied возвращает последнюю применённую миграцию, известную этой сборке
+func (m *Migrator) lastApplied(applied map[int]appliedRecord) (Migration, bool) {
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			return m.migrations[i], true
+		}
+	}
+	return Migration{}, false
+}
+
+// apply выполняет миграцию и обновляет schema_version в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	direction, script := "up", migration.Up
+	if !up {
+		direction, script = "down", migration.Down
+	}
+	started := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("миграция %04d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, m.rebind(`INSERT INTO schema_version (version, name) VALUES ($1, $2)`), migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, m.rebind(`DELETE FROM schema_version WHERE version = $1`), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось обновить schema_version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Миграция %04d_%s (%s) выполнена за %s", migration.Version, migration.Name, direction, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]appliedRecord, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedRecord)
+	for rows.Next() {
+		var version int
+		var record appliedRecord
+		if err := rows.Scan(&version, &record.name, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// withLock выполняет fn на отдельном соединении под advisory-блокировкой,
+// предварительно создав таблицу schema_version
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// В SQLite advisory-блокировок нет: файл базы использует один экземпляр,
+	// а повторная запись версии отклоняется первичным ключом schema_version
+	if m.dialect == DialectPostgres {
+		// Блокировка сессионная, поэтому держится, пока открыто это соединение
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+				logging.Errorf("Не удалось снять блокировку миграций: %v", err)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.schemaVersionDDL()); err != nil {
+		return fmt.Errorf("не удалось создать schema_version: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) schemaVersionDDL() string {
+	if m.dialect == DialectSQLite {
+		return `CREATE TABLE IF NOT EXISTS schema_version (
+		version    INTEGER  PRIMARY KEY,
+		name       TEXT     NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	}
+	return `CREATE TABLE IF NOT EXISTS schema_version (
+		version    integer     PRIMARY KEY,
+		name       text        NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`
+}
+
+// rebind заменяет плейсхолдеры $N на ? для SQLite
+func (m *Migrator) rebind(query string) string {
+	if m.dialect != DialectSQLite {
+		return query
+	}
+	return placeholderRe.ReplaceAllString(query, "?")
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS outbox_messages;
+DROP TABLE IF EXISTS processed_events;
+DROP TABLE IF EXISTS notification_blacklists;
+DROP TABLE IF EXISTS notification_subscriptions;
+DROP TABLE IF EXISTS advancements;
+DROP TABLE IF EXISTS commands;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS players;
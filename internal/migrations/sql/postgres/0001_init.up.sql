@@ -0,0 +1,89 @@
+-- Исходная схема. IF NOT EXISTS позволяет принять под управление базы,
+-- созданные раньше через GORM AutoMigrate: имена индексов и ключей совпадают.
+
+CREATE TABLE IF NOT EXISTS players (
+    id         uuid        NOT NULL PRIMARY KEY,
+    username   varchar(32) NOT NULL,
+    first_seen timestamptz NOT NULL,
+    last_seen  timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id         bigserial   PRIMARY KEY,
+    player_id  uuid        NOT NULL,
+    join_time  timestamptz NOT NULL,
+    leave_time timestamptz NULL,
+    ip_address varchar(45) NOT NULL,
+    entity_id  bigint      NOT NULL,
+    server     varchar(64) NOT NULL DEFAULT '',
+    CONSTRAINT fk_sessions_player FOREIGN KEY (player_id) REFERENCES players (id)
+);
+-- Колонка появилась позже первых версий схемы
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS server varchar(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_sessions_player_id ON sessions (player_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_server ON sessions (server);
+
+CREATE TABLE IF NOT EXISTS commands (
+    id           bigserial   PRIMARY KEY,
+    session_id   bigint      NOT NULL,
+    timestamp    timestamptz NOT NULL,
+    command      text        NOT NULL,
+    command_name varchar(64) NOT NULL,
+    args         text        NULL,
+    CONSTRAINT fk_commands_session FOREIGN KEY (session_id) REFERENCES sessions (id)
+);
+CREATE INDEX IF NOT EXISTS idx_commands_session_id ON commands (session_id);
+CREATE INDEX IF NOT EXISTS idx_commands_command_name ON commands (command_name);
+
+CREATE TABLE IF NOT EXISTS advancements (
+    id               bigserial    PRIMARY KEY,
+    player_id        uuid         NOT NULL,
+    timestamp        timestamptz  NOT NULL,
+    advancement_name varchar(128) NOT NULL,
+    CONSTRAINT fk_advancements_player FOREIGN KEY (player_id) REFERENCES players (id)
+);
+CREATE INDEX IF NOT EXISTS idx_advancements_player_id ON advancements (player_id);
+
+CREATE TABLE IF NOT EXISTS notification_subscriptions (
+    id         bigserial   PRIMARY KEY,
+    chat_id    bigint      NOT NULL,
+    enabled    boolean     NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_subscriptions_chat_id ON notification_subscriptions (chat_id);
+
+CREATE TABLE IF NOT EXISTS notification_blacklists (
+    id         bigserial   PRIMARY KEY,
+    chat_id    bigint      NOT NULL,
+    player_id  uuid        NOT NULL,
+    created_at timestamptz NOT NULL,
+    CONSTRAINT fk_notification_blacklists_player FOREIGN KEY (player_id) REFERENCES players (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notification_blacklists_chat_id ON notification_blacklists (chat_id);
+CREATE INDEX IF NOT EXISTS idx_notification_blacklists_player_id ON notification_blacklists (player_id);
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    id          bigserial   PRIMARY KEY,
+    fingerprint varchar(64) NOT NULL,
+    rule        varchar(32) NOT NULL,
+    created_at  timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_processed_events_fingerprint ON processed_events (fingerprint);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id              bigserial   PRIMARY KEY,
+    event_ref       varchar(64) NOT NULL,
+    chat_id         bigint      NOT NULL,
+    payload         text        NOT NULL,
+    status          varchar(16) NOT NULL DEFAULT 'pending',
+    attempts        bigint      NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    expires_at      timestamptz NOT NULL,
+    last_error      text        NULL,
+    sent_at         timestamptz NULL,
+    created_at      timestamptz NOT NULL,
+    updated_at      timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_chat ON outbox_messages (event_ref, chat_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox_messages (status, next_attempt_at);
@@ -0,0 +1,11 @@
+UPDATE players
+SET first_seen = first_seen + interval '3 hours',
+    last_seen  = last_seen + interval '3 hours';
+
+UPDATE sessions
+SET join_time  = join_time + interval '3 hours',
+    leave_time = leave_time + interval '3 hours';
+
+UPDATE commands SET timestamp = timestamp + interval '3 hours';
+
+UPDATE advancements SET timestamp = timestamp + interval '3 hours';
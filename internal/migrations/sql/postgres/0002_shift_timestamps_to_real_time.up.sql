@@ -0,0 +1,15 @@
+-- Раньше парсер записывал время событий с прибавленными 3 часами, чтобы при выводе
+-- получалось московское время. Теперь хранится реальное время, а часовой пояс
+-- применяется при отображении, поэтому сдвигаем накопленные данные назад.
+
+UPDATE players
+SET first_seen = first_seen - interval '3 hours',
+    last_seen  = last_seen - interval '3 hours';
+
+UPDATE sessions
+SET join_time  = join_time - interval '3 hours',
+    leave_time = leave_time - interval '3 hours';
+
+UPDATE commands SET timestamp = timestamp - interval '3 hours';
+
+UPDATE advancements SET timestamp = timestamp - interval '3 hours';
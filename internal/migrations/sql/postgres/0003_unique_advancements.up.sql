@@ -0,0 +1,9 @@
+-- Достижение выдаётся игроку один раз: удаляем дубликаты, оставляя самую раннюю запись,
+-- и закрепляем это ограничением
+DELETE FROM advancements a
+USING advancements b
+WHERE a.player_id = b.player_id
+  AND a.advancement_name = b.advancement_name
+  AND (a.timestamp > b.timestamp OR (a.timestamp = b.timestamp AND a.id > b.id));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_advancements_player_name ON advancements (player_id, advancement_name);
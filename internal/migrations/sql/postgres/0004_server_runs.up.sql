@@ -0,0 +1,10 @@
+-- Запуски серверов по строкам «Done (…)! For help» и «Stopping the server»:
+-- по ним считается время работы сервера
+CREATE TABLE IF NOT EXISTS server_runs (
+    id         bigserial   PRIMARY KEY,
+    server     varchar(64) NOT NULL,
+    started_at timestamptz NOT NULL,
+    stopped_at timestamptz NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_runs_server_started ON server_runs (server, started_at);
@@ -0,0 +1,26 @@
+-- Исходящие вебхуки: очередь и журнал доставки, состояние получателей
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              bigserial   PRIMARY KEY,
+    webhook         varchar(64) NOT NULL,
+    event_type      varchar(32) NOT NULL,
+    payload         text        NOT NULL,
+    status          varchar(16) NOT NULL DEFAULT 'pending',
+    attempts        bigint      NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    status_code     bigint      NOT NULL DEFAULT 0,
+    last_error      text        NULL,
+    duration_ms     bigint      NOT NULL DEFAULT 0,
+    delivered_at    timestamptz NULL,
+    created_at      timestamptz NOT NULL,
+    updated_at      timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_states (
+    webhook              varchar(64) PRIMARY KEY,
+    consecutive_failures bigint      NOT NULL DEFAULT 0,
+    disabled_at          timestamptz NULL,
+    disabled_reason      text        NULL,
+    updated_at           timestamptz NOT NULL
+);
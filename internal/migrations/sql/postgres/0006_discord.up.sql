@@ -0,0 +1,24 @@
+-- Уведомления в Discord: у сообщения в очереди появляется канал доставки,
+-- а получателем может быть канал Discord (target) вместо чата Telegram
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS channel varchar(16) NOT NULL DEFAULT 'telegram';
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS target varchar(32) NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_outbox_event_chat;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_recipient ON outbox_messages (event_ref, channel, chat_id, target);
+
+CREATE TABLE IF NOT EXISTS discord_subscriptions (
+    channel    varchar(32) PRIMARY KEY,
+    enabled    boolean     NOT NULL DEFAULT false,
+    events     text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS discord_blacklists (
+    id         bigserial   PRIMARY KEY,
+    channel    varchar(32) NOT NULL,
+    player_id  uuid        NOT NULL,
+    created_at timestamptz NOT NULL,
+    CONSTRAINT fk_discord_blacklists_player FOREIGN KEY (player_id) REFERENCES players (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_discord_blacklists_channel_player ON discord_blacklists (channel, player_id);
+CREATE INDEX IF NOT EXISTS idx_discord_blacklists_player_id ON discord_blacklists (player_id);
@@ -0,0 +1,10 @@
+-- Привязка аккаунтов Telegram к игрокам: бот показывает пользователю его место в рейтингах.
+-- Игрока может привязать только один аккаунт.
+CREATE TABLE IF NOT EXISTS telegram_links (
+    telegram_id bigint      PRIMARY KEY,
+    player_id   uuid        NOT NULL,
+    created_at  timestamptz NOT NULL,
+    updated_at  timestamptz NOT NULL,
+    CONSTRAINT fk_telegram_links_player FOREIGN KEY (player_id) REFERENCES players (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_links_player_id ON telegram_links (player_id);
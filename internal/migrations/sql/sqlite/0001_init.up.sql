@@ -0,0 +1,82 @@
+-- Исходная схема для SQLite. UUID хранятся как текст, время — как текст в формате драйвера (UTC).
+
+CREATE TABLE IF NOT EXISTS players (
+    id         TEXT     NOT NULL PRIMARY KEY,
+    username   TEXT     NOT NULL,
+    first_seen DATETIME NOT NULL,
+    last_seen  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    player_id  TEXT     NOT NULL REFERENCES players (id),
+    join_time  DATETIME NOT NULL,
+    leave_time DATETIME NULL,
+    ip_address TEXT     NOT NULL,
+    entity_id  INTEGER  NOT NULL,
+    server     TEXT     NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_player_id ON sessions (player_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_server ON sessions (server);
+
+CREATE TABLE IF NOT EXISTS commands (
+    id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+    session_id   INTEGER  NOT NULL REFERENCES sessions (id),
+    timestamp    DATETIME NOT NULL,
+    command      TEXT     NOT NULL,
+    command_name TEXT     NOT NULL,
+    args         TEXT     NULL
+);
+CREATE INDEX IF NOT EXISTS idx_commands_session_id ON commands (session_id);
+CREATE INDEX IF NOT EXISTS idx_commands_command_name ON commands (command_name);
+
+CREATE TABLE IF NOT EXISTS advancements (
+    id               INTEGER  PRIMARY KEY AUTOINCREMENT,
+    player_id        TEXT     NOT NULL REFERENCES players (id),
+    timestamp        DATETIME NOT NULL,
+    advancement_name TEXT     NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_advancements_player_id ON advancements (player_id);
+
+CREATE TABLE IF NOT EXISTS notification_subscriptions (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    chat_id    INTEGER  NOT NULL,
+    enabled    BOOLEAN  NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_subscriptions_chat_id ON notification_subscriptions (chat_id);
+
+CREATE TABLE IF NOT EXISTS notification_blacklists (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    chat_id    INTEGER  NOT NULL,
+    player_id  TEXT     NOT NULL REFERENCES players (id),
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_blacklists_chat_id ON notification_blacklists (chat_id);
+CREATE INDEX IF NOT EXISTS idx_notification_blacklists_player_id ON notification_blacklists (player_id);
+
+CREATE TABLE IF NOT EXISTS processed_events (
+    id          INTEGER  PRIMARY KEY AUTOINCREMENT,
+    fingerprint TEXT     NOT NULL,
+    rule        TEXT     NOT NULL,
+    created_at  DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_processed_events_fingerprint ON processed_events (fingerprint);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id              INTEGER  PRIMARY KEY AUTOINCREMENT,
+    event_ref       TEXT     NOT NULL,
+    chat_id         INTEGER  NOT NULL,
+    payload         TEXT     NOT NULL,
+    status          TEXT     NOT NULL DEFAULT 'pending',
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    expires_at      DATETIME NOT NULL,
+    last_error      TEXT     NULL,
+    sent_at         DATETIME NULL,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_chat ON outbox_messages (event_ref, chat_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox_messages (status, next_attempt_at);
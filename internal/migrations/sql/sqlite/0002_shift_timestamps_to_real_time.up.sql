@@ -0,0 +1,3 @@
+-- В SQLite данные никогда не записывались со сдвигом +3 часа: миграция сохраняет
+-- общую нумерацию версий с Postgres и ничего не меняет.
+SELECT 1;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_advancements_player_name;
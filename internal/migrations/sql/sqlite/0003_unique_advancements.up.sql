@@ -0,0 +1,13 @@
+-- Достижение выдаётся игроку один раз: удаляем дубликаты, оставляя самую раннюю запись,
+-- и закрепляем это ограничением
+DELETE FROM advancements
+WHERE id NOT IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY player_id, advancement_name ORDER BY timestamp, id
+        ) AS position
+        FROM advancements
+    ) WHERE position = 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_advancements_player_name ON advancements (player_id, advancement_name);
@@ -0,0 +1,10 @@
+-- Запуски серверов по строкам «Done (…)! For help» и «Stopping the server»:
+-- по ним считается время работы сервера
+CREATE TABLE IF NOT EXISTS server_runs (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    server     TEXT     NOT NULL,
+    started_at DATETIME NOT NULL,
+    stopped_at DATETIME NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_runs_server_started ON server_runs (server, started_at);
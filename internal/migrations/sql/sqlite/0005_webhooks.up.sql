@@ -0,0 +1,26 @@
+-- Исходящие вебхуки: очередь и журнал доставки, состояние получателей
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              INTEGER  PRIMARY KEY AUTOINCREMENT,
+    webhook         TEXT     NOT NULL,
+    event_type      TEXT     NOT NULL,
+    payload         TEXT     NOT NULL,
+    status          TEXT     NOT NULL DEFAULT 'pending',
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    status_code     INTEGER  NOT NULL DEFAULT 0,
+    last_error      TEXT     NULL,
+    duration_ms     INTEGER  NOT NULL DEFAULT 0,
+    delivered_at    DATETIME NULL,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_states (
+    webhook              TEXT     PRIMARY KEY,
+    consecutive_failures INTEGER  NOT NULL DEFAULT 0,
+    disabled_at          DATETIME NULL,
+    disabled_reason      TEXT     NULL,
+    updated_at           DATETIME NOT NULL
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS discord_blacklists;
+DROP TABLE IF EXISTS discord_subscriptions;
+
+DELETE FROM outbox_messages WHERE channel <> 'telegram';
+DROP INDEX IF EXISTS idx_outbox_event_recipient;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_chat ON outbox_messages (event_ref, chat_id);
+ALTER TABLE outbox_messages DROP COLUMN target;
+ALTER TABLE outbox_messages DROP COLUMN channel;
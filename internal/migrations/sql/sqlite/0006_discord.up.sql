@@ -0,0 +1,23 @@
+-- Уведомления в Discord: у сообщения в очереди появляется канал доставки,
+-- а получателем может быть канал Discord (target) вместо чата Telegram
+ALTER TABLE outbox_messages ADD COLUMN channel TEXT NOT NULL DEFAULT 'telegram';
+ALTER TABLE outbox_messages ADD COLUMN target TEXT NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_outbox_event_chat;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_recipient ON outbox_messages (event_ref, channel, chat_id, target);
+
+CREATE TABLE IF NOT EXISTS discord_subscriptions (
+    channel    TEXT     PRIMARY KEY,
+    enabled    BOOLEAN  NOT NULL DEFAULT 0,
+    events     TEXT     NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS discord_blacklists (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    channel    TEXT     NOT NULL,
+    player_id  TEXT     NOT NULL REFERENCES players (id),
+    created_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_discord_blacklists_channel_player ON discord_blacklists (channel, player_id);
+CREATE INDEX IF NOT EXISTS idx_discord_blacklists_player_id ON discord_blacklists (player_id);
@@ -0,0 +1,9 @@
+-- Привязка аккаунтов Telegram к игрокам: бот показывает пользователю его место в рейтингах.
+-- Игрока может привязать только один аккаунт.
+CREATE TABLE IF NOT EXISTS telegram_links (
+    telegram_id INTEGER  PRIMARY KEY,
+    player_id   TEXT     NOT NULL REFERENCES players (id),
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_links_player_id ON telegram_links (player_id);
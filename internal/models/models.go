@@ -20,6 +20,7 @@ type Session struct {
 	LeaveTime *time.Time `gorm:"null" json:"leave_time,omitempty"`
 	IPAddress string     `gorm:"type:varchar(45);not null" json:"ip_address"` // IPv6 совместимо
 	EntityID  int        `gorm:"not null" json:"entity_id"`
+	Server    string     `gorm:"type:varchar(64);not null;default:'';index" json:"server"`
 
 	Player Player `gorm:"foreignKey:PlayerID;references:ID"`
 }
@@ -39,13 +40,21 @@ type Command struct {
 // Advancement — полученное достижение
 type Advancement struct {
 	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	PlayerID        string    `gorm:"type:uuid;not null;index" json:"player_id"`
+	PlayerID        string    `gorm:"type:uuid;not null;index;uniqueIndex:idx_advancements_player_name" json:"player_id"`
 	Timestamp       time.Time `gorm:"not null" json:"timestamp"`
-	AdvancementName string    `gorm:"type:varchar(128);not null" json:"advancement_name"`
+	AdvancementName string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_advancements_player_name" json:"advancement_name"`
 
 	Player Player `gorm:"foreignKey:PlayerID;references:ID"`
 }
 
+// ServerRun — запуск сервера Minecraft; StoppedAt пуст, пока сервер работает
+type ServerRun struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Server    string     `gorm:"type:varchar(64);not null;index:idx_server_runs_server_started,priority:1" json:"server"`
+	StartedAt time.Time  `gorm:"not null;index:idx_server_runs_server_started,priority:2" json:"started_at"`
+	StoppedAt *time.Time `gorm:"null" json:"stopped_at,omitempty"`
+}
+
 // NotificationSubscription — подписка на уведомления в Telegram
 type NotificationSubscription struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -64,3 +73,112 @@ type NotificationBlacklist struct {
 
 	Player Player `gorm:"foreignKey:PlayerID;references:ID"`
 }
+
+// DiscordSubscription — настройки уведомлений канала Discord, изменённые администратором.
+// Пока записи нет, действуют настройки канала из конфигурации.
+type DiscordSubscription struct {
+	Channel   string    `gorm:"type:varchar(32);primaryKey" json:"channel"`
+	Enabled   bool      `gorm:"not null;default:false" json:"enabled"`
+	Events    string    `gorm:"type:text;not null;default:''" json:"events"` // типы событий через запятую
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+// DiscordBlacklist — игроки, о которых не уведомляется канал Discord
+type DiscordBlacklist struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Channel   string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_discord_blacklists_channel_player,priority:1" json:"channel"`
+	PlayerID  string    `gorm:"type:uuid;not null;uniqueIndex:idx_discord_blacklists_channel_player,priority:2;index" json:"player_id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+
+	Player Player `gorm:"foreignKey:PlayerID;references:ID"`
+}
+
+// TelegramLink — игрок, которым назвался пользователь Telegram. Привязка не подтверждается
+// в игре и служит только для показа пользователю его места в рейтингах; игрока
+// может привязать только один пользователь.
+type TelegramLink struct {
+	TelegramID int64     `gorm:"primaryKey;autoIncrement:false" json:"telegram_id"`
+	PlayerID   string    `gorm:"type:uuid;not null;uniqueIndex" json:"player_id"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"not null" json:"updated_at"`
+
+	Player Player `gorm:"foreignKey:PlayerID;references:ID"`
+}
+
+// ProcessedEvent — отпечаток уже обработанного события лога (для идемпотентной загрузки)
+type ProcessedEvent struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Fingerprint string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"fingerprint"`
+	Rule        string    `gorm:"type:varchar(32);not null" json:"rule"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+}
+
+// Статусы сообщений в очереди исходящих уведомлений
+const (
+	OutboxPending = "pending" // ожидает отправки (в том числе повторной)
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"  // попытки исчерпаны или ошибка неисправима
+	OutboxExpired = "expired" // уведомление устарело до успешной отправки
+)
+
+// Каналы доставки уведомлений
+const (
+	ChannelTelegram = "telegram" // получатель — чат ChatID
+	ChannelDiscord  = "discord"  // получатель — канал Discord с именем Target из конфигурации
+)
+
+// OutboxMessage — уведомление, ожидающее доставки получателю.
+// Получатель (channel, chat_id, target) получает событие event_ref не больше одного раза.
+type OutboxMessage struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventRef string `gorm:"type:varchar(64);not null;uniqueIndex:idx_outbox_event_recipient,priority:1" json:"event_ref"` // отпечаток события
+	Channel  string `gorm:"type:varchar(16);not null;default:'telegram';uniqueIndex:idx_outbox_event_recipient,priority:2" json:"channel"`
+	ChatID   int64  `gorm:"not null;default:0;uniqueIndex:idx_outbox_event_recipient,priority:3" json:"chat_id"`
+	Target   string `gorm:"type:varchar(32);not null;default:'';uniqueIndex:idx_outbox_event_recipient,priority:4" json:"target,omitempty"`
+	// Готовое сообщение: текст для Telegram, JSON тела запроса для Discord
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"type:varchar(16);not null;default:'pending';index:idx_outbox_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	LastError     string     `gorm:"type:text;null" json:"last_error,omitempty"`
+	SentAt        *time.Time `gorm:"null" json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// Статусы доставки событий вебхукам
+const (
+	WebhookPending   = "pending" // ожидает отправки (в том числе повторной)
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed" // попытки исчерпаны, ответ неисправим или вебхук отключён
+)
+
+// WebhookDelivery — доставка события вебхуку. Записи служат и очередью,
+// и журналом доставки: в них остаются код ответа и ошибка последней попытки.
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Webhook       string     `gorm:"type:varchar(64);not null;index:idx_webhook_deliveries_webhook" json:"webhook"`
+	EventType     string     `gorm:"type:varchar(32);not null" json:"event_type"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"type:varchar(16);not null;default:'pending';index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	StatusCode    int        `gorm:"not null;default:0" json:"status_code,omitempty"` // HTTP-код последней попытки; 0 — ответа не было
+	LastError     string     `gorm:"type:text;null" json:"last_error,omitempty"`
+	DurationMs    int64      `gorm:"not null;default:0" json:"duration_ms"` // длительность последней попытки
+	DeliveredAt   *time.Time `gorm:"null" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// WebhookState — состояние вебхука из конфигурации: серия неудачных попыток подряд
+// и отключение после слишком длинной серии. Нет записи — неудач не было.
+type WebhookState struct {
+	Webhook             string     `gorm:"type:varchar(64);primaryKey" json:"webhook"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time `gorm:"null" json:"disabled_at,omitempty"`
+	DisabledReason      string     `gorm:"type:text;null" json:"disabled_reason,omitempty"`
+	UpdatedAt           time.Time  `gorm:"not null" json:"updated_at"`
+}
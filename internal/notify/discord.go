@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification — событие, о котором сообщает уведомление в Discord
+type Notification struct {
+	Type      events.Type
+	Server    string
+	PlayerID  string // пусто для событий сервера
+	Username  string
+	Text      string // сообщение о смерти, достижение или сообщение в чате
+	Online    int    // игроков онлайн после события
+	Timestamp time.Time
+}
+
+// Цвет полосы embed по типу события
+var discordColors = map[events.Type]int{
+	events.TypePlayerLogin:       0x57f287,
+	events.TypePlayerLogout:      0x95a5a6,
+	events.TypePlayerDied:        0xed4245,
+	events.TypeAdvancementEarned: 0xfee75c,
+	events.TypeChatMessage:       0x5865f2,
+	events.TypeServerStarted:     0x3ba55c,
+	events.TypeServerStopping:    0xe67e22,
+}
+
+// Ограничения Discord на длину полей embed
+const (
+	maxEmbedTitle       = 256
+	maxEmbedDescription = 4096
+	maxErrorBody        = 512 // байт тела ответа в тексте ошибки
+)
+
+type discordMessage struct {
+	Username        string                 `json:"username,omitempty"`
+	Embeds          []discordEmbed         `json:"embeds"`
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Thumbnail   *discordImage  `json:"thumbnail,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// Пустой parse запрещает упоминания: ник или сообщение в чате не должны звать @everyone
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// DiscordPayload готовит тело запроса к вебхуку Discord: embed с цветом по типу события
+// и аватаром игрока по шаблону cfg.AvatarURL
+func DiscordPayload(n Notification, cfg config.DiscordConfig) (string, error) {
+	embed := discordEmbed{
+		Title:       truncate(discordTitle(n), maxEmbedTitle),
+		Description: truncate(n.Text, maxEmbedDescription),
+		Color:       discordColors[n.Type],
+		Timestamp:   n.Timestamp.UTC().Format(time.RFC3339),
+	}
+
+	footer := n.Server
+	switch n.Type {
+	case events.TypePlayerLogin, events.TypePlayerLogout:
+		footer += fmt.Sprintf(" · онлайн: %d", n.Online)
+	}
+	if footer != "" {
+		embed.Footer = &discordFooter{Text: footer}
+	}
+
+	if n.PlayerID != "" && cfg.AvatarURL != "" {
+		avatar, err := config.Render(cfg.AvatarURL, config.AvatarView{PlayerID: n.PlayerID, Username: n.Username})
+		if err != nil {
+			return "", fmt.Errorf("не удалось подготовить адрес аватара: %w", err)
+		}
+		embed.Thumbnail = &discordImage{URL: avatar}
+	}
+
+	body, err := json.Marshal(discordMessage{
+		Username:        cfg.Username,
+		Embeds:          []discordEmbed{embed},
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func discordTitle(n Notification) string {
+	switch n.Type {
+	case events.TypePlayerLogin:
+		return fmt.Sprintf("🟢 %s зашел на сервер", n.Username)
+	case events.TypePlayerLogout:
+		return fmt.Sprintf("🔴 %s вышел с сервера", n.Username)
+	case events.TypePlayerDied:
+		return fmt.Sprintf("💀 %s погиб", n.Username)
+	case events.TypeAdvancementEarned:
+		return fmt.Sprintf("🏆 %s получил достижение", n.Username)
+	case events.TypeChatMessage:
+		return fmt.Sprintf("💬 %s", n.Username)
+	case events.TypeServerStarted:
+		return fmt.Sprintf("✅ Сервер %s запущен", n.Server)
+	case events.TypeServerStopping:
+		return fmt.Sprintf("⛔ Сервер %s останавливается", n.Server)
+	default:
+		return string(n.Type)
+	}
+}
+
+func truncate(text string, limit int) string {
+	if len([]rune(text)) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+// Discord отправляет уведомления в каналы Discord через их входящие вебхуки.
+// Адрес вебхука содержит токен, поэтому в очереди хранится только имя канала,
+// а адрес берётся из действующей конфигурации.
+type Discord struct {
+	cfg  *config.Store
+	http *http.Client
+}
+
+func NewDiscord(cfg *config.Store) *Discord {
+	return &Discord{cfg: cfg, http: &http.Client{}}
+}
+
+// Send отправляет сообщение в канал Target
+func (d *Discord) Send(ctx context.Context, message models.OutboxMessage) error {
+	cfg := d.cfg.Get().Discord
+	channel, ok := cfg.Channel(message.Target)
+	if !ok {
+		return &Error{Err: fmt.Errorf("канал Discord %q удалён из конфигурации", message.Target), Permanent: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.WebhookURL, strings.NewReader(message.Payload))
+	if err != nil {
+		return &Error{Err: err, Permanent: true}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := d.http.Do(request)
+	if err != nil {
+		// В тексте ошибки net/http есть адрес вебхука вместе с токеном
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = "вебхук канала " + channel.Name
+		}
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		// Тело дочитывается, чтобы соединение вернулось в пул
+		_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+		return nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+	text := fmt.Sprintf("Discord ответил %d", response.StatusCode)
+	if trimmed := strings.TrimSpace(string(snippet)); trimmed != "" {
+		text += ": " + trimmed
+	}
+	sendErr := &Error{Err: errors.New(text)}
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests:
+		sendErr.RetryAfter = discordRetryAfter(response.Header, snippet)
+	case response.StatusCode >= 500:
+	default:
+		// Вебхук удалён (404), токен неверен (401) или сообщение отклонено (400)
+		sendErr.Permanent = true
+	}
+	return sendErr
+}
+
+// discordRetryAfter читает задержку ограничения частоты: в секундах, возможно дробных,
+// из заголовка Retry-After или поля retry_after тела ответа
+func discordRetryAfter(header http.Header, body []byte) time.Duration {
+	seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64)
+	if err != nil {
+		var payload struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(body, &payload) == nil {
+			seconds = payload.RetryAfter
+		}
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
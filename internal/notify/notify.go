@@ -0,0 +1,42 @@
+// Package notify доставляет уведомления из очереди исходящих сообщений получателям
+// разных каналов: чатам Telegram и каналам Discord
+package notify
+
+import (
+	"context"
+	"errors"
+	"mine-parser/internal/models"
+	"time"
+)
+
+// Notifier отправляет готовое уведомление получателю своего канала.
+// Текст сообщения подготовлен при постановке в очередь и лежит в Payload.
+type Notifier interface {
+	Send(ctx context.Context, message models.OutboxMessage) error
+}
+
+// Error — неудачная отправка с подсказкой, как её повторять
+type Error struct {
+	Err        error
+	Permanent  bool          // повтор бесполезен: бот заблокирован, вебхук удалён и т.п.
+	RetryAfter time.Duration // получатель просит подождать перед повтором
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// IsPermanent сообщает, что повторять отправку бесполезно
+func IsPermanent(err error) bool {
+	var sendErr *Error
+	return errors.As(err, &sendErr) && sendErr.Permanent
+}
+
+// RetryAfter возвращает задержку, которую получатель просит выдержать перед повтором
+func RetryAfter(err error) time.Duration {
+	var sendErr *Error
+	if errors.As(err, &sendErr) {
+		return sendErr.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"mine-parser/internal/models"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram отправляет уведомления в чаты Telegram от имени бота
+type Telegram struct {
+	bot *tgbotapi.BotAPI
+}
+
+func NewTelegram(bot *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{bot: bot}
+}
+
+// Send отправляет текст уведомления в чат ChatID. Библиотека бота не принимает
+// контекст, поэтому ctx не используется.
+func (t *Telegram) Send(_ context.Context, message models.OutboxMessage) error {
+	_, err := t.bot.Send(tgbotapi.NewMessage(message.ChatID, message.Payload))
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	return &Error{
+		Err: err,
+		// Бот заблокирован, чат удалён и т.п.
+		Permanent:  apiErr.Code == http.StatusBadRequest || apiErr.Code == http.StatusForbidden,
+		RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second,
+	}
+}
@@ -1,16 +1,38 @@
 package repo
 
 import (
+	"context"
 	"mine-parser/internal/models"
 
 	"gorm.io/gorm"
 )
 
 type AdvancementRepository interface {
-	Create(adv *models.Advancement) error
-	ListByPlayer(playerID string) ([]models.Advancement, error)
-	HasPlayerCompleted(advancementName string, playerID string) (bool, error)
-	CountAdvancementsByPlayer(playerID string) (int64, error)
+	Create(ctx context.Context, adv *models.Advancement) error
+	ListByPlayer(ctx context.Context, playerID string) ([]models.Advancement, error)
+	HasPlayerCompleted(ctx context.Context, advancementName string, playerID string) (bool, error)
+	CountAdvancementsByPlayer(ctx context.Context, playerID string) (int64, error)
+	// ListCompletedNames возвращает множество ключей "player_id|advancement_name" для указанных игроков
+	ListCompletedNames(ctx context.Context, playerIDs []string) (map[string]bool, error)
+	CreateMany(ctx context.Context, advancements []*models.Advancement) error
+	// List возвращает страницу достижений от новых к старым
+	List(ctx context.Context, filter AdvancementFilter) ([]models.Advancement, error)
+	// CountByPlayer возвращает игроков с наибольшим числом достижений.
+	// Достижения не привязаны к серверу, поэтому filter.Server не учитывается.
+	CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error)
+}
+
+// AdvancementFilter — параметры выборки достижений
+type AdvancementFilter struct {
+	PlayerID string // пусто — достижения всех игроков
+	Period   TimeRange
+	After    *TimeKey // ключ (timestamp, id) последнего достижения предыдущей страницы
+	Limit    int
+}
+
+// AdvancementKey строит ключ пары игрок/достижение для ListCompletedNames
+func AdvancementKey(playerID, advancementName string) string {
+	return playerID + "|" + advancementName
 }
 
 type advancementRepository struct {
@@ -21,21 +43,21 @@ func NewAdvancementRepository(db *gorm.DB) AdvancementRepository {
 	return &advancementRepository{db: db}
 }
 
-func (r *advancementRepository) Create(adv *models.Advancement) error {
-	return r.db.Create(adv).Error
+func (r *advancementRepository) Create(ctx context.Context, adv *models.Advancement) error {
+	return r.db.WithContext(ctx).Create(adv).Error
 }
 
-func (r *advancementRepository) ListByPlayer(playerID string) ([]models.Advancement, error) {
+func (r *advancementRepository) ListByPlayer(ctx context.Context, playerID string) ([]models.Advancement, error) {
 	var advancements []models.Advancement
-	err := r.db.Where("player_id = ?", playerID).
+	err := r.db.WithContext(ctx).Where("player_id = ?", playerID).
 		Order("timestamp DESC").
 		Find(&advancements).Error
 	return advancements, err
 }
 
-func (r *advancementRepository) HasPlayerCompleted(advancementName string, playerID string) (bool, error) {
+func (r *advancementRepository) HasPlayerCompleted(ctx context.Context, advancementName string, playerID string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.Advancement{}).
+	err := r.db.WithContext(ctx).Model(&models.Advancement{}).
 		Where("player_id = ? AND advancement_name = ?", playerID, advancementName).
 		Count(&count).Error
 	if err != nil {
@@ -44,10 +66,68 @@ func (r *advancementRepository) HasPlayerCompleted(advancementName string, playe
 	return count > 0, nil
 }
 
-func (r *advancementRepository) CountAdvancementsByPlayer(playerID string) (int64, error) {
+func (r *advancementRepository) CountAdvancementsByPlayer(ctx context.Context, playerID string) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Advancement{}).
+	err := r.db.WithContext(ctx).Model(&models.Advancement{}).
 		Where("player_id = ?", playerID).
 		Count(&count).Error
 	return count, err
 }
+
+func (r *advancementRepository) ListCompletedNames(ctx context.Context, playerIDs []string) (map[string]bool, error) {
+	completed := make(map[string]bool)
+	if len(playerIDs) == 0 {
+		return completed, nil
+	}
+
+	var rows []struct {
+		PlayerID        string
+		AdvancementName string
+	}
+	err := r.db.WithContext(ctx).Model(&models.Advancement{}).
+		Select("player_id, advancement_name").
+		Where("player_id IN ?", playerIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		completed[AdvancementKey(row.PlayerID, row.AdvancementName)] = true
+	}
+	return completed, nil
+}
+
+func (r *advancementRepository) CreateMany(ctx context.Context, advancements []*models.Advancement) error {
+	if len(advancements) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(advancements, batchInsertSize).Error
+}
+
+func (r *advancementRepository) List(ctx context.Context, filter AdvancementFilter) ([]models.Advancement, error) {
+	query := r.db.WithContext(ctx).Model(&models.Advancement{})
+	if filter.PlayerID != "" {
+		query = query.Where("player_id = ?", filter.PlayerID)
+	}
+	query = within(query, "timestamp", filter.Period)
+
+	var advancements []models.Advancement
+	err := newestFirst(query, "timestamp", "id", filter.After, filter.Limit).Find(&advancements).Error
+	return advancements, err
+}
+
+func (r *advancementRepository) CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error) {
+	query := r.db.WithContext(ctx).Model(&models.Advancement{}).
+		Select("player_id, COUNT(*) AS count")
+	query = within(query, "timestamp", filter.Period).
+		Group("player_id").
+		Order("count DESC").Order("player_id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var counts []PlayerCount
+	err := query.Scan(&counts).Error
+	return counts, err
+}
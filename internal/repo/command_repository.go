@@ -1,17 +1,33 @@
 package repo
 
 import (
+	"context"
 	"mine-parser/internal/models"
 
 	"gorm.io/gorm"
 )
 
 type CommandRepository interface {
-	Create(cmd *models.Command) error
-	ListByPlayer(playerID string, limit int) ([]models.Command, error)
-	ListByCommandName(name string) ([]models.Command, error)
-	CountCommandsByPlayer(playerID string) (int64, error)
-	GetMostUsedCommands(limit int) ([]CommandUsage, error)
+	Create(ctx context.Context, cmd *models.Command) error
+	ListByPlayer(ctx context.Context, playerID string, limit int) ([]models.Command, error)
+	ListByCommandName(ctx context.Context, name string) ([]models.Command, error)
+	CountCommandsByPlayer(ctx context.Context, playerID string) (int64, error)
+	GetMostUsedCommands(ctx context.Context, limit int) ([]CommandUsage, error)
+	CreateMany(ctx context.Context, commands []*models.Command) error
+	// List возвращает страницу команд от новых к старым вместе с сессиями, в которых они выполнены
+	List(ctx context.Context, filter CommandFilter) ([]models.Command, error)
+	// CountByPlayer возвращает игроков с наибольшим числом команд
+	CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error)
+}
+
+// CommandFilter — параметры выборки команд
+type CommandFilter struct {
+	PlayerID string // пусто — команды всех игроков
+	Name     string // имя команды без аргументов; пусто — любые
+	Period   TimeRange
+	After    *TimeKey // ключ (timestamp, id) последней команды предыдущей страницы
+	Offset   int      // пропустить записей — для списков с номерами страниц
+	Limit    int
 }
 
 type CommandUsage struct {
@@ -27,13 +43,20 @@ func NewCommandRepository(db *gorm.DB) CommandRepository {
 	return &commandRepository{db: db}
 }
 
-func (r *commandRepository) Create(cmd *models.Command) error {
-	return r.db.Create(cmd).Error
+func (r *commandRepository) Create(ctx context.Context, cmd *models.Command) error {
+	return r.db.WithContext(ctx).Create(cmd).Error
+}
+
+func (r *commandRepository) CreateMany(ctx context.Context, commands []*models.Command) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(commands, batchInsertSize).Error
 }
 
-func (r *commandRepository) ListByPlayer(playerID string, limit int) ([]models.Command, error) {
+func (r *commandRepository) ListByPlayer(ctx context.Context, playerID string, limit int) ([]models.Command, error) {
 	var commands []models.Command
-	query := r.db.Joins("JOIN sessions ON commands.session_id = sessions.id").
+	query := r.db.WithContext(ctx).Joins("JOIN sessions ON commands.session_id = sessions.id").
 		Where("sessions.player_id = ?", playerID).
 		Order("commands.timestamp DESC")
 
@@ -45,30 +68,30 @@ func (r *commandRepository) ListByPlayer(playerID string, limit int) ([]models.C
 	return commands, err
 }
 
-func (r *commandRepository) ListByCommandName(name string) ([]models.Command, error) {
+func (r *commandRepository) ListByCommandName(ctx context.Context, name string) ([]models.Command, error) {
 	var commands []models.Command
-	err := r.db.Where("command_name = ?", name).
+	err := r.db.WithContext(ctx).Where("command_name = ?", name).
 		Order("timestamp DESC").
 		Find(&commands).Error
 	return commands, err
 }
 
-func (r *commandRepository) CountCommandsByPlayer(playerID string) (int64, error) {
+func (r *commandRepository) CountCommandsByPlayer(ctx context.Context, playerID string) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Command{}).
+	err := r.db.WithContext(ctx).Model(&models.Command{}).
 		Joins("JOIN sessions ON commands.session_id = sessions.id").
 		Where("sessions.player_id = ?", playerID).
 		Count(&count).Error
 	return count, err
 }
 
-func (r *commandRepository) GetMostUsedCommands(limit int) ([]CommandUsage, error) {
+func (r *commandRepository) GetMostUsedCommands(ctx context.Context, limit int) ([]CommandUsage, error) {
 	var results []struct {
 		CommandName string `gorm:"column:command_name"`
 		Count       int64  `gorm:"column:count"`
 	}
 
-	query := r.db.Model(&models.Command{}).
+	query := r.db.WithContext(ctx).Model(&models.Command{}).
 		Select("command_name, COUNT(*) as count").
 		Group("command_name").
 		Order("count DESC")
@@ -92,3 +115,40 @@ func (r *commandRepository) GetMostUsedCommands(limit int) ([]CommandUsage, erro
 
 	return usages, nil
 }
+
+func (r *commandRepository) List(ctx context.Context, filter CommandFilter) ([]models.Command, error) {
+	query := r.db.WithContext(ctx).Model(&models.Command{}).Preload("Session")
+	if filter.PlayerID != "" {
+		query = query.Joins("JOIN sessions ON commands.session_id = sessions.id").
+			Where("sessions.player_id = ?", filter.PlayerID)
+	}
+	if filter.Name != "" {
+		query = query.Where("commands.command_name = ?", filter.Name)
+	}
+	query = within(query, "commands.timestamp", filter.Period)
+
+	var commands []models.Command
+	err := newestFirst(query, "commands.timestamp", "commands.id", filter.After, filter.Limit).
+		Offset(filter.Offset).
+		Find(&commands).Error
+	return commands, err
+}
+
+func (r *commandRepository) CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error) {
+	query := r.db.WithContext(ctx).Model(&models.Command{}).
+		Select("sessions.player_id AS player_id, COUNT(*) AS count").
+		Joins("JOIN sessions ON commands.session_id = sessions.id")
+	if filter.Server != "" {
+		query = query.Where("sessions.server = ?", filter.Server)
+	}
+	query = within(query, "commands.timestamp", filter.Period).
+		Group("sessions.player_id").
+		Order("count DESC").Order("sessions.player_id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var counts []PlayerCount
+	err := query.Scan(&counts).Error
+	return counts, err
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DiscordRepository interface {
+	// GetSubscription возвращает настройки канала или nil, если их ещё не меняли
+	GetSubscription(ctx context.Context, channel string) (*models.DiscordSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.DiscordSubscription, error)
+	SaveSubscription(ctx context.Context, subscription models.DiscordSubscription) error
+	// Методы для черного списка
+	AddToBlacklist(ctx context.Context, channel, playerID string) error
+	RemoveFromBlacklist(ctx context.Context, channel, playerID string) error
+	IsInBlacklist(ctx context.Context, channel, playerID string) (bool, error)
+	GetBlacklist(ctx context.Context, channel string) ([]models.DiscordBlacklist, error)
+	// ListBlacklistByPlayers возвращает записи черных списков всех каналов для указанных игроков
+	ListBlacklistByPlayers(ctx context.Context, playerIDs []string) ([]models.DiscordBlacklist, error)
+}
+
+type discordRepository struct {
+	db *gorm.DB
+}
+
+func NewDiscordRepository(db *gorm.DB) DiscordRepository {
+	return &discordRepository{db: db}
+}
+
+func (r *discordRepository) GetSubscription(ctx context.Context, channel string) (*models.DiscordSubscription, error) {
+	var subscription models.DiscordSubscription
+	err := r.db.WithContext(ctx).Where("channel = ?", channel).First(&subscription).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *discordRepository) ListSubscriptions(ctx context.Context) ([]models.DiscordSubscription, error) {
+	var subscriptions []models.DiscordSubscription
+	err := r.db.WithContext(ctx).Order("channel ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *discordRepository) SaveSubscription(ctx context.Context, subscription models.DiscordSubscription) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "events", "updated_at"}),
+	}).Create(&subscription).Error
+}
+
+func (r *discordRepository) AddToBlacklist(ctx context.Context, channel, playerID string) error {
+	entry := models.DiscordBlacklist{Channel: channel, PlayerID: playerID}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
+}
+
+func (r *discordRepository) RemoveFromBlacklist(ctx context.Context, channel, playerID string) error {
+	return r.db.WithContext(ctx).Where("channel = ? AND player_id = ?", channel, playerID).
+		Delete(&models.DiscordBlacklist{}).Error
+}
+
+func (r *discordRepository) IsInBlacklist(ctx context.Context, channel, playerID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.DiscordBlacklist{}).
+		Where("channel = ? AND player_id = ?", channel, playerID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *discordRepository) GetBlacklist(ctx context.Context, channel string) ([]models.DiscordBlacklist, error) {
+	var blacklist []models.DiscordBlacklist
+	err := r.db.WithContext(ctx).Where("channel = ?", channel).Find(&blacklist).Error
+	return blacklist, err
+}
+
+func (r *discordRepository) ListBlacklistByPlayers(ctx context.Context, playerIDs []string) ([]models.DiscordBlacklist, error) {
+	var blacklist []models.DiscordBlacklist
+	if len(playerIDs) == 0 {
+		return blacklist, nil
+	}
+	err := r.db.WithContext(ctx).Where("player_id IN ?", playerIDs).Find(&blacklist).Error
+	return blacklist, err
+}
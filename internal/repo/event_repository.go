@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type EventRepository interface {
+	// ListExisting возвращает те отпечатки из списка, которые уже были обработаны
+	ListExisting(ctx context.Context, fingerprints []string) (map[string]bool, error)
+	CreateMany(ctx context.Context, events []models.ProcessedEvent) error
+}
+
+type eventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) EventRepository {
+	return &eventRepository{db: db}
+}
+
+func (r *eventRepository) ListExisting(ctx context.Context, fingerprints []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(fingerprints) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).Model(&models.ProcessedEvent{}).
+		Where("fingerprint IN ?", fingerprints).
+		Pluck("fingerprint", &found).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fingerprint := range found {
+		existing[fingerprint] = true
+	}
+	return existing, nil
+}
+
+func (r *eventRepository) CreateMany(ctx context.Context, events []models.ProcessedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	// Конфликт по уникальному fingerprint — ошибка: транзакция вызывающего должна откатиться
+	return r.db.WithContext(ctx).CreateInBatches(events, batchInsertSize).Error
+}
@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExportFilter — параметры выгрузки. Записи выбираются пачками по возрастанию id:
+// следующая пачка начинается после AfterID последней записи предыдущей.
+type ExportFilter struct {
+	PlayerID string // пусто — все игроки
+	Server   string // пусто — все серверы
+	// Period ограничивает время записи; сессии выбираются пересекающиеся с интервалом
+	Period  TimeRange
+	AfterID uint
+	Limit   int
+}
+
+// SessionExport — сессия вместе с ником игрока
+type SessionExport struct {
+	ID        uint
+	PlayerID  string
+	Username  string
+	Server    string
+	JoinTime  time.Time
+	LeaveTime *time.Time
+}
+
+// CommandExport — команда вместе с игроком и сервером её сессии
+type CommandExport struct {
+	ID          uint
+	SessionID   uint
+	PlayerID    string
+	Username    string
+	Server      string
+	Timestamp   time.Time
+	CommandName string
+	Command     string
+}
+
+// AdvancementExport — достижение вместе с ником игрока и сервером, на котором оно получено
+type AdvancementExport struct {
+	ID              uint
+	PlayerID        string
+	Username        string
+	Server          string // пусто, если сессия на момент получения не найдена
+	Timestamp       time.Time
+	AdvancementName string
+}
+
+type ExportRepository interface {
+	Sessions(ctx context.Context, filter ExportFilter) ([]SessionExport, error)
+	Commands(ctx context.Context, filter ExportFilter) ([]CommandExport, error)
+	Advancements(ctx context.Context, filter ExportFilter) ([]AdvancementExport, error)
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+// ascending продолжает выборку после filter.AfterID в порядке возрастания idColumn
+func ascending(query *gorm.DB, idColumn string, filter ExportFilter) *gorm.DB {
+	if filter.AfterID > 0 {
+		query = query.Where(idColumn+" > ?", filter.AfterID)
+	}
+	query = query.Order(idColumn + " ASC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return query
+}
+
+func (r *exportRepository) Sessions(ctx context.Context, filter ExportFilter) ([]SessionExport, error) {
+	query := r.db.WithContext(ctx).Model(&models.Session{}).
+		Select("sessions.id, sessions.player_id, players.username, sessions.server, sessions.join_time, sessions.leave_time").
+		Joins("JOIN players ON players.id = sessions.player_id")
+	if filter.PlayerID != "" {
+		query = query.Where("sessions.player_id = ?", filter.PlayerID)
+	}
+	if filter.Server != "" {
+		query = query.Where("sessions.server = ?", filter.Server)
+	}
+	if !filter.Period.From.IsZero() {
+		query = query.Where("sessions.leave_time IS NULL OR sessions.leave_time >= ?", filter.Period.From)
+	}
+	if !filter.Period.To.IsZero() {
+		query = query.Where("sessions.join_time < ?", filter.Period.To)
+	}
+
+	var rows []SessionExport
+	err := ascending(query, "sessions.id", filter).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *exportRepository) Commands(ctx context.Context, filter ExportFilter) ([]CommandExport, error) {
+	query := r.db.WithContext(ctx).Model(&models.Command{}).
+		Select("commands.id, commands.session_id, sessions.player_id, players.username, sessions.server, " +
+			"commands.timestamp, commands.command_name, commands.command").
+		Joins("JOIN sessions ON sessions.id = commands.session_id").
+		Joins("JOIN players ON players.id = sessions.player_id")
+	if filter.PlayerID != "" {
+		query = query.Where("sessions.player_id = ?", filter.PlayerID)
+	}
+	if filter.Server != "" {
+		query = query.Where("sessions.server = ?", filter.Server)
+	}
+	query = within(query, "commands.timestamp", filter.Period)
+
+	var rows []CommandExport
+	err := ascending(query, "commands.id", filter).Scan(&rows).Error
+	return rows, err
+}
+
+// advancementServer — сервер последней сессии игрока, начатой не позже получения достижения
+const advancementServer = "(SELECT sessions.server FROM sessions" +
+	" WHERE sessions.player_id = advancements.player_id AND sessions.join_time <= advancements.timestamp" +
+	" ORDER BY sessions.join_time DESC, sessions.id DESC LIMIT 1)"
+
+func (r *exportRepository) Advancements(ctx context.Context, filter ExportFilter) ([]AdvancementExport, error) {
+	query := r.db.WithContext(ctx).Model(&models.Advancement{}).
+		Select("advancements.id, advancements.player_id, players.username, " +
+			"COALESCE(" + advancementServer + ", '') AS server, " +
+			"advancements.timestamp, advancements.advancement_name").
+		Joins("JOIN players ON players.id = advancements.player_id")
+	if filter.PlayerID != "" {
+		query = query.Where("advancements.player_id = ?", filter.PlayerID)
+	}
+	if filter.Server != "" {
+		query = query.Where(advancementServer+" = ?", filter.Server)
+	}
+	query = within(query, "advancements.timestamp", filter.Period)
+
+	var rows []AdvancementExport
+	err := ascending(query, "advancements.id", filter).Scan(&rows).Error
+	return rows, err
+}
@@ -1,21 +1,24 @@
 package repo
 
 import (
+	"context"
 	"mine-parser/internal/models"
 
 	"gorm.io/gorm"
 )
 
 type NotificationRepository interface {
-	CreateOrUpdate(chatID int64, enabled bool) error
-	GetByChatID(chatID int64) (*models.NotificationSubscription, error)
-	GetAllEnabled() ([]models.NotificationSubscription, error)
-	Delete(chatID int64) error
+	CreateOrUpdate(ctx context.Context, chatID int64, enabled bool) error
+	GetByChatID(ctx context.Context, chatID int64) (*models.NotificationSubscription, error)
+	GetAllEnabled(ctx context.Context) ([]models.NotificationSubscription, error)
+	Delete(ctx context.Context, chatID int64) error
 	// Методы для черного списка
-	AddToBlacklist(chatID int64, playerID string) error
-	RemoveFromBlacklist(chatID int64, playerID string) error
-	IsInBlacklist(chatID int64, playerID string) (bool, error)
-	GetBlacklist(chatID int64) ([]models.NotificationBlacklist, error)
+	AddToBlacklist(ctx context.Context, chatID int64, playerID string) error
+	RemoveFromBlacklist(ctx context.Context, chatID int64, playerID string) error
+	IsInBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error)
+	GetBlacklist(ctx context.Context, chatID int64) ([]models.NotificationBlacklist, error)
+	// ListBlacklistByPlayers возвращает записи черных списков всех чатов для указанных игроков
+	ListBlacklistByPlayers(ctx context.Context, playerIDs []string) ([]models.NotificationBlacklist, error)
 }
 
 type notificationRepository struct {
@@ -26,9 +29,9 @@ func NewNotificationRepository(db *gorm.DB) NotificationRepository {
 	return &notificationRepository{db: db}
 }
 
-func (r *notificationRepository) CreateOrUpdate(chatID int64, enabled bool) error {
+func (r *notificationRepository) CreateOrUpdate(ctx context.Context, chatID int64, enabled bool) error {
 	var subscription models.NotificationSubscription
-	err := r.db.Where("chat_id = ?", chatID).First(&subscription).Error
+	err := r.db.WithContext(ctx).Where("chat_id = ?", chatID).First(&subscription).Error
 
 	if err == gorm.ErrRecordNotFound {
 		// Создаем новую запись
@@ -36,19 +39,19 @@ func (r *notificationRepository) CreateOrUpdate(chatID int64, enabled bool) erro
 			ChatID:  chatID,
 			Enabled: enabled,
 		}
-		return r.db.Create(&subscription).Error
+		return r.db.WithContext(ctx).Create(&subscription).Error
 	} else if err != nil {
 		return err
 	}
 
 	// Обновляем существующую запись
 	subscription.Enabled = enabled
-	return r.db.Save(&subscription).Error
+	return r.db.WithContext(ctx).Save(&subscription).Error
 }
 
-func (r *notificationRepository) GetByChatID(chatID int64) (*models.NotificationSubscription, error) {
+func (r *notificationRepository) GetByChatID(ctx context.Context, chatID int64) (*models.NotificationSubscription, error) {
 	var subscription models.NotificationSubscription
-	err := r.db.Where("chat_id = ?", chatID).First(&subscription).Error
+	err := r.db.WithContext(ctx).Where("chat_id = ?", chatID).First(&subscription).Error
 	if err != nil {
 		// Если запись не найдена, это нормально - подписки еще нет
 		if err == gorm.ErrRecordNotFound {
@@ -59,41 +62,50 @@ func (r *notificationRepository) GetByChatID(chatID int64) (*models.Notification
 	return &subscription, nil
 }
 
-func (r *notificationRepository) GetAllEnabled() ([]models.NotificationSubscription, error) {
+func (r *notificationRepository) GetAllEnabled(ctx context.Context) ([]models.NotificationSubscription, error) {
 	var subscriptions []models.NotificationSubscription
-	err := r.db.Where("enabled = ?", true).Find(&subscriptions).Error
+	err := r.db.WithContext(ctx).Where("enabled = ?", true).Find(&subscriptions).Error
 	return subscriptions, err
 }
 
-func (r *notificationRepository) Delete(chatID int64) error {
-	return r.db.Where("chat_id = ?", chatID).Delete(&models.NotificationSubscription{}).Error
+func (r *notificationRepository) Delete(ctx context.Context, chatID int64) error {
+	return r.db.WithContext(ctx).Where("chat_id = ?", chatID).Delete(&models.NotificationSubscription{}).Error
 }
 
-func (r *notificationRepository) AddToBlacklist(chatID int64, playerID string) error {
+func (r *notificationRepository) AddToBlacklist(ctx context.Context, chatID int64, playerID string) error {
 	blacklist := &models.NotificationBlacklist{
 		ChatID:   chatID,
 		PlayerID: playerID,
 	}
 	// Используем FirstOrCreate, чтобы не создавать дубликаты
-	return r.db.Where("chat_id = ? AND player_id = ?", chatID, playerID).
+	return r.db.WithContext(ctx).Where("chat_id = ? AND player_id = ?", chatID, playerID).
 		FirstOrCreate(blacklist).Error
 }
 
-func (r *notificationRepository) RemoveFromBlacklist(chatID int64, playerID string) error {
-	return r.db.Where("chat_id = ? AND player_id = ?", chatID, playerID).
+func (r *notificationRepository) RemoveFromBlacklist(ctx context.Context, chatID int64, playerID string) error {
+	return r.db.WithContext(ctx).Where("chat_id = ? AND player_id = ?", chatID, playerID).
 		Delete(&models.NotificationBlacklist{}).Error
 }
 
-func (r *notificationRepository) IsInBlacklist(chatID int64, playerID string) (bool, error) {
+func (r *notificationRepository) IsInBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.NotificationBlacklist{}).
+	err := r.db.WithContext(ctx).Model(&models.NotificationBlacklist{}).
 		Where("chat_id = ? AND player_id = ?", chatID, playerID).
 		Count(&count).Error
 	return count > 0, err
 }
 
-func (r *notificationRepository) GetBlacklist(chatID int64) ([]models.NotificationBlacklist, error) {
+func (r *notificationRepository) GetBlacklist(ctx context.Context, chatID int64) ([]models.NotificationBlacklist, error) {
 	var blacklist []models.NotificationBlacklist
-	err := r.db.Where("chat_id = ?", chatID).Find(&blacklist).Error
+	err := r.db.WithContext(ctx).Where("chat_id = ?", chatID).Find(&blacklist).Error
+	return blacklist, err
+}
+
+func (r *notificationRepository) ListBlacklistByPlayers(ctx context.Context, playerIDs []string) ([]models.NotificationBlacklist, error) {
+	var blacklist []models.NotificationBlacklist
+	if len(playerIDs) == 0 {
+		return blacklist, nil
+	}
+	err := r.db.WithContext(ctx).Where("player_id IN ?", playerIDs).Find(&blacklist).Error
 	return blacklist, err
 }
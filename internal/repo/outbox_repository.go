@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository interface {
+	// CreateMany ставит сообщения в очередь, пропуская события, уже поставленные получателю
+	CreateMany(ctx context.Context, messages []models.OutboxMessage) error
+	// ListDue возвращает сообщения каналов channels, которые пора отправить, от старых к новым
+	ListDue(ctx context.Context, channels []string, now time.Time, limit int) ([]models.OutboxMessage, error)
+	// BeginAttempt увеличивает счётчик попыток и откладывает следующую попытку до nextAttempt
+	BeginAttempt(ctx context.Context, id uint, nextAttempt time.Time) error
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	// MarkRetry оставляет сообщение в очереди с ошибкой последней попытки
+	MarkRetry(ctx context.Context, id uint, nextAttempt time.Time, lastError string) error
+	// MarkFinal переводит сообщение в конечный статус (failed или expired)
+	MarkFinal(ctx context.Context, id uint, status, lastError string) error
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) CreateMany(ctx context.Context, messages []models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(messages, batchInsertSize).Error
+}
+
+func (r *outboxRepository) ListDue(ctx context.Context, channels []string, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	if len(channels) == 0 {
+		return messages, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ? AND channel IN ?", models.OutboxPending, now, channels).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *outboxRepository) BeginAttempt(ctx context.Context, id uint, nextAttempt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttempt,
+		}).Error
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.OutboxSent,
+			"sent_at":    sentAt,
+			"last_error": "",
+		}).Error
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id uint, nextAttempt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"next_attempt_at": nextAttempt,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (r *outboxRepository) MarkFinal(ctx context.Context, id uint, status, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"last_error": lastError,
+		}).Error
+}
+
+func (r *outboxRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Списки для API выбираются по ключу (keyset pagination): следующая страница начинается
+// сразу после последней записи предыдущей, поэтому новые записи не сдвигают страницы,
+// как при OFFSET, а запрос не замедляется с номером страницы.
+
+// TimeKey — ключ записи в списке, упорядоченном от новых к старым по времени и id
+type TimeKey struct {
+	Time time.Time
+	ID   uint
+}
+
+// TimeRange — интервал [From, To); нулевая граница не ограничивает выборку
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// newestFirst сортирует выборку по убыванию column и id и продолжает её после after
+func newestFirst(query *gorm.DB, column, idColumn string, after *TimeKey, limit int) *gorm.DB {
+	if after != nil {
+		query = query.Where(
+			column+" < ? OR ("+column+" = ? AND "+idColumn+" < ?)",
+			after.Time, after.Time, after.ID,
+		)
+	}
+	query = query.Order(column + " DESC").Order(idColumn + " DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return query
+}
+
+// within ограничивает column интервалом r. Границы переводятся в UTC:
+// SQLite сравнивает время как текст, а хранится оно в UTC.
+func within(query *gorm.DB, column string, r TimeRange) *gorm.DB {
+	if !r.From.IsZero() {
+		query = query.Where(column+" >= ?", r.From.UTC())
+	}
+	if !r.To.IsZero() {
+		query = query.Where(column+" < ?", r.To.UTC())
+	}
+	return query
+}
+
+// likePattern строит шаблон LIKE для поиска подстроки; символы % и _ из s экранируются
+func likePattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(s) + "%"
+}
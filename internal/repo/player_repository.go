@@ -1,18 +1,68 @@
 package repo
 
 import (
+	"context"
 	"mine-parser/internal/models"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PlayerRepository interface {
-	GetOrCreate(playerID string, username string, timestamp time.Time) (*models.Player, error)
-	UpdateLastSeen(playerID string, lastSeen time.Time) error
-	FindByID(playerID string) (*models.Player, error)
-	ListAll() ([]models.Player, error)
-	FindByUsername(username string) (*models.Player, error)
+	GetOrCreate(ctx context.Context, playerID string, username string, timestamp time.Time) (*models.Player, error)
+	UpdateLastSeen(ctx context.Context, playerID string, lastSeen time.Time) error
+	FindByID(ctx context.Context, playerID string) (*models.Player, error)
+	FindByIDs(ctx context.Context, playerIDs []string) ([]models.Player, error)
+	ListAll(ctx context.Context) ([]models.Player, error)
+	FindByUsername(ctx context.Context, username string) (*models.Player, error)
+	// UpsertMany создаёт игроков или обновляет существующих: last_seen и first_seen
+	// расширяются до нового интервала, username меняется только более поздним событием
+	UpsertMany(ctx context.Context, players []models.Player) error
+	// Search возвращает страницу игроков, упорядоченных по username
+	Search(ctx context.Context, filter PlayerFilter) ([]models.Player, error)
+	// ListPage возвращает страницу списка игроков с номерами страниц в порядке page.Order
+	ListPage(ctx context.Context, page PlayerPage) ([]PlayerPlayTime, error)
+	Count(ctx context.Context) (int64, error)
+	// PositionByName возвращает число игроков перед playerID в порядке PlayersByName; -1, если игрока нет
+	PositionByName(ctx context.Context, playerID string) (int64, error)
+}
+
+// PlayerOrder — порядок списка игроков; при равенстве — по нику без учёта регистра и id
+type PlayerOrder string
+
+const (
+	PlayersByName     PlayerOrder = "name"
+	PlayersByLastSeen PlayerOrder = "last_seen" // недавно заходившие первыми
+	PlayersByPlayTime PlayerOrder = "play_time" // больше всего времени в игре первыми
+)
+
+// PlayerPage — параметры страницы списка игроков
+type PlayerPage struct {
+	Order  PlayerOrder
+	Now    time.Time // открытые сессии длятся до Now; нужен для PlayersByPlayTime
+	Offset int
+	Limit  int
+}
+
+// PlayerPlayTime — игрок и его время в игре
+type PlayerPlayTime struct {
+	models.Player
+	PlayTime int64 // секунды; считается только для PlayersByPlayTime
+}
+
+// PlayerFilter — параметры выборки игроков
+type PlayerFilter struct {
+	Query string     // подстрока username без учёта регистра; пусто — все игроки
+	After *PlayerKey // ключ последнего игрока предыдущей страницы
+	Limit int
+}
+
+// PlayerKey — ключ игрока в списке, упорядоченном по username и id
+type PlayerKey struct {
+	Username string
+	ID       string
 }
 
 type playerRepository struct {
@@ -23,7 +73,7 @@ func NewPlayerRepository(db *gorm.DB) PlayerRepository {
 	return &playerRepository{db: db}
 }
 
-func (r *playerRepository) GetOrCreate(playerID string, username string, timestamp time.Time) (*models.Player, error) {
+func (r *playerRepository) GetOrCreate(ctx context.Context, playerID string, username string, timestamp time.Time) (*models.Player, error) {
 	player := &models.Player{
 		ID:        playerID,
 		Username:  username,
@@ -31,7 +81,7 @@ func (r *playerRepository) GetOrCreate(playerID string, username string, timesta
 		LastSeen:  timestamp,
 	}
 
-	err := r.db.FirstOrCreate(player, models.Player{ID: playerID}).Error
+	err := r.db.WithContext(ctx).FirstOrCreate(player, models.Player{ID: playerID}).Error
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +90,7 @@ func (r *playerRepository) GetOrCreate(playerID string, username string, timesta
 	if player.FirstSeen.Before(timestamp) {
 		player.Username = username
 		player.LastSeen = timestamp
-		if err := r.db.Save(player).Error; err != nil {
+		if err := r.db.WithContext(ctx).Save(player).Error; err != nil {
 			return nil, err
 		}
 	}
@@ -48,32 +98,128 @@ func (r *playerRepository) GetOrCreate(playerID string, username string, timesta
 	return player, nil
 }
 
-func (r *playerRepository) UpdateLastSeen(playerID string, lastSeen time.Time) error {
-	return r.db.Model(&models.Player{}).
-		Where("id = ?", playerID).
+func (r *playerRepository) UpdateLastSeen(ctx context.Context, playerID string, lastSeen time.Time) error {
+	// Событие из старого лога не сдвигает last_seen назад
+	return r.db.WithContext(ctx).Model(&models.Player{}).
+		Where("id = ? AND last_seen < ?", playerID, lastSeen.UTC()).
 		Update("last_seen", lastSeen).Error
 }
 
-func (r *playerRepository) FindByID(playerID string) (*models.Player, error) {
+func (r *playerRepository) FindByID(ctx context.Context, playerID string) (*models.Player, error) {
 	var player models.Player
-	err := r.db.Where("id = ?", playerID).First(&player).Error
+	err := r.db.WithContext(ctx).Where("id = ?", playerID).First(&player).Error
 	if err != nil {
 		return nil, err
 	}
 	return &player, nil
 }
 
-func (r *playerRepository) ListAll() ([]models.Player, error) {
+func (r *playerRepository) FindByIDs(ctx context.Context, playerIDs []string) ([]models.Player, error) {
 	var players []models.Player
-	err := r.db.Find(&players).Error
+	if len(playerIDs) == 0 {
+		return players, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", playerIDs).Find(&players).Error
+	return players, err
+}
+
+func (r *playerRepository) ListAll(ctx context.Context) ([]models.Player, error) {
+	var players []models.Player
+	err := r.db.WithContext(ctx).Find(&players).Error
 	return players, err
 }
 
-func (r *playerRepository) FindByUsername(username string) (*models.Player, error) {
+func (r *playerRepository) FindByUsername(ctx context.Context, username string) (*models.Player, error) {
 	var player models.Player
-	err := r.db.Where("username = ?", username).First(&player).Error
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&player).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil // не ошибка, просто нет такого
 	}
 	return &player, err
 }
+
+func (r *playerRepository) UpsertMany(ctx context.Context, players []models.Player) error {
+	if len(players) == 0 {
+		return nil
+	}
+	// Загрузка старого лога не должна откатывать данные назад: last_seen только растёт,
+	// first_seen только уменьшается, а ник берётся из более позднего события
+	greatest, least := "GREATEST", "LEAST"
+	if r.db.Dialector.Name() == "sqlite" {
+		greatest, least = "MAX", "MIN"
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"username":   gorm.Expr("CASE WHEN excluded.last_seen >= players.last_seen THEN excluded.username ELSE players.username END"),
+			"last_seen":  gorm.Expr(greatest + "(players.last_seen, excluded.last_seen)"),
+			"first_seen": gorm.Expr(least + "(players.first_seen, excluded.first_seen)"),
+		}),
+	}).CreateInBatches(players, batchInsertSize).Error
+}
+
+func (r *playerRepository) Search(ctx context.Context, filter PlayerFilter) ([]models.Player, error) {
+	query := r.db.WithContext(ctx).Model(&models.Player{})
+	if filter.Query != "" {
+		query = query.Where(`LOWER(username) LIKE ? ESCAPE '\'`, likePattern(strings.ToLower(filter.Query)))
+	}
+	if filter.After != nil {
+		query = query.Where("username > ? OR (username = ? AND id > ?)",
+			filter.After.Username, filter.After.Username, filter.After.ID)
+	}
+	query = query.Order("username").Order("id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var players []models.Player
+	err := query.Find(&players).Error
+	return players, err
+}
+
+func (r *playerRepository) ListPage(ctx context.Context, page PlayerPage) ([]PlayerPlayTime, error) {
+	query := r.db.WithContext(ctx).Model(&models.Player{}).Select("players.*")
+	switch page.Order {
+	case PlayersByLastSeen:
+		query = query.Order("players.last_seen DESC")
+	case PlayersByPlayTime:
+		totals := r.db.Model(&models.Session{}).
+			Select("player_id, SUM("+secondsBetween(r.db, "join_time", "COALESCE(leave_time, ?)")+") AS seconds", page.Now.UTC()).
+			Where("leave_time IS NULL OR leave_time > join_time").
+			Group("player_id")
+		query = query.Select("players.*, COALESCE(totals.seconds, 0) AS play_time").
+			Joins("LEFT JOIN (?) AS totals ON totals.player_id = players.id", totals).
+			Order("play_time DESC")
+	}
+	query = query.Order("LOWER(players.username)").Order("players.id").Offset(page.Offset)
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit)
+	}
+
+	var players []PlayerPlayTime
+	err := query.Scan(&players).Error
+	return players, err
+}
+
+func (r *playerRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Player{}).Count(&count).Error
+	return count, err
+}
+
+func (r *playerRepository) PositionByName(ctx context.Context, playerID string) (int64, error) {
+	var players []models.Player
+	if err := r.db.WithContext(ctx).Where("id = ?", playerID).Limit(1).Find(&players).Error; err != nil {
+		return 0, err
+	}
+	if len(players) == 0 {
+		return -1, nil
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Player{}).
+		Where("LOWER(username) < LOWER(?) OR (LOWER(username) = LOWER(?) AND id < ?)",
+			players[0].Username, players[0].Username, players[0].ID).
+		Count(&count).Error
+	return count, err
+}
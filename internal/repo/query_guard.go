@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/metrics"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// QueryOptions — ограничения на запросы к БД
+type QueryOptions struct {
+	Timeout       time.Duration // максимальное время одного запроса; 0 — без ограничения
+	SlowThreshold time.Duration // запросы дольше порога пишутся в лог вместе с местом вызова; 0 — не писать
+}
+
+const queryCancelKey = "query_guard:cancel"
+
+type queryGuard struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// Instrument ограничивает время каждого запроса и включает журнал медленных запросов.
+// Таймаут накладывается поверх контекста вызывающего: действует тот срок, что наступит раньше.
+// Ограничения запрашиваются у options перед каждым запросом, поэтому их можно менять на ходу.
+func Instrument(db *gorm.DB, options func() QueryOptions) error {
+	db.Logger = newSlowQueryLogger(options)
+
+	before := func(tx *gorm.DB) {
+		timeout := options().Timeout
+		if timeout <= 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(tx.Statement.Context, timeout)
+		tx.InstanceSet(queryCancelKey, queryGuard{parent: tx.Statement.Context, cancel: cancel})
+		tx.Statement.Context = ctx
+	}
+	after := func(tx *gorm.DB) {
+		if value, ok := tx.InstanceGet(queryCancelKey); ok {
+			guard := value.(queryGuard)
+			guard.cancel()
+			// Последующие колбэки (ассоциации, хуки) работают с исходным контекстом
+			tx.Statement.Context = guard.parent
+		}
+	}
+
+	// Row/Rows не оборачиваются: строки читаются уже после возврата из колбэков
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("query_guard:timeout_create", before),
+		cb.Create().After("gorm:create").Register("query_guard:cancel_create", after),
+		cb.Query().Before("gorm:query").Register("query_guard:timeout_query", before),
+		cb.Query().After("gorm:query").Register("query_guard:cancel_query", after),
+		cb.Update().Before("gorm:update").Register("query_guard:timeout_update", before),
+		cb.Update().After("gorm:update").Register("query_guard:cancel_update", after),
+		cb.Delete().Before("gorm:delete").Register("query_guard:timeout_delete", before),
+		cb.Delete().After("gorm:delete").Register("query_guard:cancel_delete", after),
+		cb.Raw().Before("gorm:raw").Register("query_guard:timeout_raw", before),
+		cb.Raw().After("gorm:raw").Register("query_guard:cancel_raw", after),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("не удалось зарегистрировать таймаут запросов: %w", err)
+		}
+	}
+	return nil
+}
+
+// slowQueryLogger — логгер GORM, сообщающий об ошибках и медленных запросах вместе с местом вызова
+type slowQueryLogger struct {
+	logger.Interface
+	options func() QueryOptions
+}
+
+func newSlowQueryLogger(options func() QueryOptions) logger.Interface {
+	// Базовый логгер используется только для служебных сообщений GORM, запросы пишет Trace
+	base := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		LogLevel: logger.Warn,
+		Colorful: true,
+	})
+	return &slowQueryLogger{Interface: base, options: options}
+}
+
+func (l *slowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &slowQueryLogger{Interface: l.Interface.LogMode(level), options: l.options}
+}
+
+// Trace учитывает время запроса в метриках и пишет в лог ошибки и медленные запросы,
+// а на уровне debug — все запросы.
+// Место вызова определяется самостоятельно: стандартный логгер GORM указал бы на эту обёртку.
+func (l *slowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	repository, method := queryMethod()
+	metrics.DBQueryDuration.WithLabelValues(repository, method).Observe(elapsed.Seconds())
+
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	threshold := l.options().SlowThreshold
+	slow := threshold > 0 && elapsed >= threshold
+	if !failed && !slow {
+		if logging.Enabled(logging.LevelDebug) {
+			sql, rows := fc()
+			logging.Debugf("Запрос (%s, строк: %d): %s", elapsed.Round(time.Millisecond), rows, sql)
+		}
+		return
+	}
+
+	sql, rows := fc()
+	if failed {
+		logging.Errorf("Ошибка запроса (%s) из %s: %v\n  %s", elapsed.Round(time.Millisecond), queryCaller(), err, sql)
+		return
+	}
+	logging.Warnf("Медленный запрос (%s, строк: %d) из %s: %s", elapsed.Round(time.Millisecond), rows, queryCaller(), sql)
+}
+
+// queryCaller возвращает место вызова запроса: код за пределами репозиториев
+// и метод репозитория, через который выполнен запрос
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var repoFrame string
+	for {
+		frame, more := frames.Next()
+		switch {
+		case strings.Contains(frame.File, "gorm.io/"), strings.HasSuffix(frame.File, "query_guard.go"):
+		case strings.Contains(frame.File, "/internal/repo/"):
+			if repoFrame == "" {
+				repoFrame = formatFrame(frame)
+			}
+		default:
+			if repoFrame == "" {
+				return formatFrame(frame)
+			}
+			return formatFrame(frame) + " → " + repoFrame
+		}
+		if !more {
+			break
+		}
+	}
+	if repoFrame == "" {
+		return "неизвестно"
+	}
+	return repoFrame
+}
+
+// queryMethod возвращает репозиторий и метод, через который выполнен запрос:
+// mine-parser/internal/repo.(*sessionRepository).List → session, List
+func queryMethod() (repository, method string) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if strings.Contains(frame.File, "/internal/repo/") && !strings.HasSuffix(frame.File, "query_guard.go") {
+			return splitRepoFunction(frame.Function)
+		}
+		if !more {
+			return "other", "other"
+		}
+	}
+}
+
+func splitRepoFunction(function string) (repository, method string) {
+	name := function[strings.LastIndex(function, "/")+1:]
+	name = strings.TrimPrefix(name, "repo.")
+	if receiver, rest, ok := strings.Cut(name, ")."); ok {
+		repository = strings.TrimSuffix(strings.TrimPrefix(receiver, "(*"), "Repository")
+		name = rest
+	} else {
+		repository = "repo"
+	}
+	// Замыкания внутри метода: List.func1 → List
+	method, _, _ = strings.Cut(name, ".")
+	return repository, method
+}
+
+func formatFrame(frame runtime.Frame) string {
+	function := frame.Function
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		function = function[i+1:]
+	}
+	return fmt.Sprintf("%s (%s:%d)", function, filepath.Base(frame.File), frame.Line)
+}
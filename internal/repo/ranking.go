@@ -0,0 +1,14 @@
+package repo
+
+// PlayerCount — число записей игрока (команд, достижений) для рейтинга
+type PlayerCount struct {
+	PlayerID string
+	Count    int64
+}
+
+// RankFilter — параметры рейтинга игроков
+type RankFilter struct {
+	Period TimeRange
+	Server string // пусто — все серверы
+	Limit  int
+}
@@ -0,0 +1,688 @@
+package repo_test
+
+import (
+	"context"
+	"mine-parser/internal/config"
+	"mine-parser/internal/migrations"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// testDatabaseEnv — строка подключения к отдельной БД Postgres для тестов.
+// Тесты очищают в ней все таблицы; без переменной они выполняются только на SQLite.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// dataTables — таблицы с данными, которые очищаются перед тестом на Postgres
+var dataTables = []string{
+	"telegram_links", "discord_blacklists", "discord_subscriptions",
+	"webhook_deliveries", "webhook_states", "server_runs", "outbox_messages", "processed_events",
+	"notification_blacklists", "notification_subscriptions",
+	"advancements", "commands", "sessions", "players",
+}
+
+// forEachDB выполняет test на пустой БД SQLite и, если задан testDatabaseEnv, Postgres
+func forEachDB(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestDB(t, config.SQLiteScheme+filepath.Join(t.TempDir(), "repo.db")))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(testDatabaseEnv)
+		if dsn == "" {
+			t.Skipf("%s не задан", testDatabaseEnv)
+		}
+		db := openTestDB(t, dsn)
+		if err := db.Exec("TRUNCATE " + strings.Join(dataTables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+			t.Fatal(err)
+		}
+		test(t, db)
+	})
+}
+
+func openTestDB(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := migrations.InitDB(dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// t0 — опорное время тестовых данных
+var t0 = time.Date(2026, 4, 10, 10, 0, 0, 0, time.UTC)
+
+const (
+	alice = "00000000-0000-0000-0000-000000000001"
+	bob   = "00000000-0000-0000-0000-000000000002"
+	carol = "00000000-0000-0000-0000-000000000003"
+	// bob2 — другой игрок с тем же ником: порядок внутри одного ника задаёт id
+	bob2    = "00000000-0000-0000-0000-000000000004"
+	unknown = "00000000-0000-0000-0000-0000000000ff"
+)
+
+// fixture — записи, созданные seed; sessions[i] — i-я сессия из описания в seed
+type fixture struct {
+	sessions []*models.Session
+}
+
+// seed заполняет БД:
+//
+//	0: alice, main, t0 … t0+1h — 3 команды
+//	1: bob, main, t0 … t0+30m — 1 команда (время входа совпадает с сессией 0)
+//	2: alice, creative, t0+2h, открыта — 2 команды
+//	3: carol, main, t0-48h … t0-47h — 1 команда
+//	4: bob, main, t0+3h, открыта
+//
+// Достижения: у alice два, у bob и carol по одному.
+func seed(t *testing.T, db *gorm.DB) fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	players := []models.Player{
+		{ID: alice, Username: "alice", FirstSeen: t0.Add(-72 * time.Hour), LastSeen: t0.Add(3 * time.Hour)},
+		{ID: bob, Username: "bob", FirstSeen: t0, LastSeen: t0.Add(3 * time.Hour)},
+		{ID: carol, Username: "carol", FirstSeen: t0.Add(-48 * time.Hour), LastSeen: t0.Add(-47 * time.Hour)},
+		{ID: bob2, Username: "bob", FirstSeen: t0.Add(-96 * time.Hour), LastSeen: t0.Add(-96 * time.Hour)},
+	}
+	if err := repo.NewPlayerRepository(db).UpsertMany(ctx, players); err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(d time.Duration) *time.Time {
+		v := t0.Add(d)
+		return &v
+	}
+	sessions := []*models.Session{
+		{PlayerID: alice, JoinTime: t0, LeaveTime: at(time.Hour), IPAddress: "10.0.0.1", Server: "main"},
+		{PlayerID: bob, JoinTime: t0, LeaveTime: at(30 * time.Minute), IPAddress: "10.0.0.2", Server: "main"},
+		{PlayerID: alice, JoinTime: t0.Add(2 * time.Hour), IPAddress: "10.0.0.1", Server: "creative"},
+		{PlayerID: carol, JoinTime: t0.Add(-48 * time.Hour), LeaveTime: at(-47 * time.Hour), IPAddress: "10.0.0.3", Server: "main"},
+		{PlayerID: bob, JoinTime: t0.Add(3 * time.Hour), IPAddress: "10.0.0.2", Server: "main"},
+	}
+	if err := repo.NewSessionRepository(db).CreateMany(ctx, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	command := func(session int, d time.Duration, text string) *models.Command {
+		return &models.Command{
+			SessionID:   sessions[session].ID,
+			Timestamp:   sessions[session].JoinTime.Add(d),
+			Command:     text,
+			CommandName: strings.Fields(strings.TrimPrefix(text, "/"))[0],
+		}
+	}
+	commands := []*models.Command{
+		command(0, time.Minute, "/home"),
+		command(0, 2*time.Minute, "/spawn"),
+		command(0, 3*time.Minute, "/home"),
+		command(1, 5*time.Minute, "/home"),
+		command(2, time.Minute, "/tp 0 64 0"),
+		command(2, time.Minute, "/home"), // то же время, что у предыдущей
+		command(3, 10*time.Minute, "/spawn"),
+	}
+	if err := repo.NewCommandRepository(db).CreateMany(ctx, commands); err != nil {
+		t.Fatal(err)
+	}
+
+	advancements := []*models.Advancement{
+		{PlayerID: alice, Timestamp: t0.Add(10 * time.Minute), AdvancementName: "Stone Age"},
+		{PlayerID: bob, Timestamp: t0.Add(15 * time.Minute), AdvancementName: "Stone Age"},
+		{PlayerID: alice, Timestamp: t0.Add(20 * time.Minute), AdvancementName: "Getting an Upgrade"},
+		{PlayerID: carol, Timestamp: t0.Add(-47*time.Hour - 30*time.Minute), AdvancementName: "Stone Age"},
+	}
+	if err := repo.NewAdvancementRepository(db).CreateMany(ctx, advancements); err != nil {
+		t.Fatal(err)
+	}
+
+	return fixture{sessions: sessions}
+}
+
+func TestInQueries(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		f := seed(t, db)
+		players := repo.NewPlayerRepository(db)
+		sessions := repo.NewSessionRepository(db)
+		advancements := repo.NewAdvancementRepository(db)
+
+		cases := []struct {
+			name          string
+			ids           []string
+			wantPlayers   []string
+			wantOpen      map[string][]int // игрок → номера сессий в fixture
+			wantCompleted []string
+		}{
+			{
+				name: "пустой список",
+			},
+			{
+				name:          "один игрок",
+				ids:           []string{alice},
+				wantPlayers:   []string{alice},
+				wantOpen:      map[string][]int{alice: {2}},
+				wantCompleted: []string{repo.AdvancementKey(alice, "Getting an Upgrade"), repo.AdvancementKey(alice, "Stone Age")},
+			},
+			{
+				name:          "неизвестный игрок пропускается",
+				ids:           []string{bob, carol, unknown},
+				wantPlayers:   []string{bob, carol},
+				wantOpen:      map[string][]int{bob: {4}},
+				wantCompleted: []string{repo.AdvancementKey(bob, "Stone Age"), repo.AdvancementKey(carol, "Stone Age")},
+			},
+			{
+				name:          "повторы в списке",
+				ids:           []string{carol, carol},
+				wantPlayers:   []string{carol},
+				wantCompleted: []string{repo.AdvancementKey(carol, "Stone Age")},
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				found, err := players.FindByIDs(ctx, tc.ids)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var gotPlayers []string
+				for _, p := range found {
+					gotPlayers = append(gotPlayers, p.ID)
+				}
+				assertSameSet(t, "FindByIDs", gotPlayers, tc.wantPlayers)
+
+				open, err := sessions.ListOpenByPlayers(ctx, tc.ids)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotOpen := make(map[string][]int)
+				for playerID, list := range open {
+					for _, session := range list {
+						gotOpen[playerID] = append(gotOpen[playerID], sessionIndex(f, session.ID))
+					}
+				}
+				if len(gotOpen) != len(tc.wantOpen) || (len(gotOpen) > 0 && !reflect.DeepEqual(gotOpen, tc.wantOpen)) {
+					t.Errorf("ListOpenByPlayers = %v, want %v", gotOpen, tc.wantOpen)
+				}
+
+				completed, err := advancements.ListCompletedNames(ctx, tc.ids)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var gotCompleted []string
+				for key := range completed {
+					gotCompleted = append(gotCompleted, key)
+				}
+				assertSameSet(t, "ListCompletedNames", gotCompleted, tc.wantCompleted)
+			})
+		}
+	})
+}
+
+func TestKeysetPagination(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		f := seed(t, db)
+		sessions := repo.NewSessionRepository(db)
+		commands := repo.NewCommandRepository(db)
+		advancements := repo.NewAdvancementRepository(db)
+		players := repo.NewPlayerRepository(db)
+
+		sessionPages := []struct {
+			name   string
+			filter repo.SessionFilter
+			want   []int // номера сессий fixture в порядке выдачи
+		}{
+			{"все сессии", repo.SessionFilter{Limit: 2}, []int{4, 2, 1, 0, 3}},
+			{"по одной на сервере main", repo.SessionFilter{Server: "main", Limit: 1}, []int{4, 1, 0, 3}},
+			{"пересекающиеся с периодом", repo.SessionFilter{
+				Period: repo.TimeRange{From: t0.Add(-time.Hour), To: t0.Add(150 * time.Minute)},
+				Limit:  2,
+			}, []int{2, 1, 0}},
+			{"игрока", repo.SessionFilter{PlayerID: alice, Limit: 1}, []int{2, 0}},
+		}
+		for _, tc := range sessionPages {
+			t.Run("sessions/"+tc.name, func(t *testing.T) {
+				got := collectPages(t, func(last *models.Session) ([]models.Session, error) {
+					filter := tc.filter
+					if last != nil {
+						filter.After = &repo.TimeKey{Time: last.JoinTime, ID: last.ID}
+					}
+					return sessions.List(ctx, filter)
+				})
+				var gotIdx []int
+				for _, s := range got {
+					gotIdx = append(gotIdx, sessionIndex(f, s.ID))
+				}
+				if !reflect.DeepEqual(gotIdx, tc.want) {
+					t.Errorf("сессии = %v, want %v", gotIdx, tc.want)
+				}
+			})
+		}
+
+		commandPages := []struct {
+			name   string
+			filter repo.CommandFilter
+			want   []string
+		}{
+			// Две команды с одинаковым временем упорядочены по убыванию id
+			{"все команды", repo.CommandFilter{Limit: 3}, []string{
+				"/home", "/tp 0 64 0", "/home", "/home", "/spawn", "/home", "/spawn",
+			}},
+			{"игрока с именем команды", repo.CommandFilter{PlayerID: alice, Name: "home", Limit: 1}, []string{
+				"/home", "/home", "/home",
+			}},
+			{"за период", repo.CommandFilter{Period: repo.TimeRange{From: t0, To: t0.Add(time.Hour)}, Limit: 2}, []string{
+				"/home", "/home", "/spawn", "/home",
+			}},
+		}
+		for _, tc := range commandPages {
+			t.Run("commands/"+tc.name, func(t *testing.T) {
+				got := collectPages(t, func(last *models.Command) ([]models.Command, error) {
+					filter := tc.filter
+					if last != nil {
+						filter.After = &repo.TimeKey{Time: last.Timestamp, ID: last.ID}
+					}
+					return commands.List(ctx, filter)
+				})
+				var gotText []string
+				for _, c := range got {
+					gotText = append(gotText, c.Command)
+				}
+				if !reflect.DeepEqual(gotText, tc.want) {
+					t.Errorf("команды = %v, want %v", gotText, tc.want)
+				}
+			})
+		}
+
+		t.Run("advancements", func(t *testing.T) {
+			got := collectPages(t, func(last *models.Advancement) ([]models.Advancement, error) {
+				filter := repo.AdvancementFilter{Limit: 1}
+				if last != nil {
+					filter.After = &repo.TimeKey{Time: last.Timestamp, ID: last.ID}
+				}
+				return advancements.List(ctx, filter)
+			})
+			var gotOrder []string
+			for _, a := range got {
+				gotOrder = append(gotOrder, a.PlayerID+" "+a.AdvancementName)
+			}
+			want := []string{alice + " Getting an Upgrade", bob + " Stone Age", alice + " Stone Age", carol + " Stone Age"}
+			if !reflect.DeepEqual(gotOrder, want) {
+				t.Errorf("достижения = %v, want %v", gotOrder, want)
+			}
+		})
+
+		playerPages := []struct {
+			name   string
+			filter repo.PlayerFilter
+			want   []string
+		}{
+			{"все игроки", repo.PlayerFilter{Limit: 2}, []string{alice, bob, bob2, carol}},
+			{"одинаковые ники", repo.PlayerFilter{Query: "BO", Limit: 1}, []string{bob, bob2}},
+			{"подстановочные символы не срабатывают", repo.PlayerFilter{Query: "_o", Limit: 1}, nil},
+		}
+		for _, tc := range playerPages {
+			t.Run("players/"+tc.name, func(t *testing.T) {
+				got := collectPages(t, func(last *models.Player) ([]models.Player, error) {
+					filter := tc.filter
+					if last != nil {
+						filter.After = &repo.PlayerKey{Username: last.Username, ID: last.ID}
+					}
+					return players.Search(ctx, filter)
+				})
+				var gotIDs []string
+				for _, p := range got {
+					gotIDs = append(gotIDs, p.ID)
+				}
+				if !reflect.DeepEqual(gotIDs, tc.want) {
+					t.Errorf("игроки = %v, want %v", gotIDs, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func TestPlayerPages(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		seed(t, db)
+		players := repo.NewPlayerRepository(db)
+		now := t0.Add(4 * time.Hour)
+
+		cases := []struct {
+			name         string
+			page         repo.PlayerPage
+			want         []string
+			wantPlayTime []int64 // nil — время в игре не проверяется
+		}{
+			{"по нику", repo.PlayerPage{Order: repo.PlayersByName}, []string{alice, bob, bob2, carol}, nil},
+			{"страница по нику", repo.PlayerPage{Order: repo.PlayersByName, Offset: 1, Limit: 2}, []string{bob, bob2}, nil},
+			// У alice и bob одинаковое время последнего входа: порядок по нику
+			{"по последнему входу", repo.PlayerPage{Order: repo.PlayersByLastSeen}, []string{alice, bob, carol, bob2}, nil},
+			{"по времени в игре", repo.PlayerPage{Order: repo.PlayersByPlayTime, Now: now},
+				[]string{alice, bob, carol, bob2}, []int64{10800, 5400, 3600, 0}},
+			{"последняя страница по времени в игре", repo.PlayerPage{Order: repo.PlayersByPlayTime, Now: now, Offset: 3, Limit: 2},
+				[]string{bob2}, []int64{0}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				page, err := players.ListPage(ctx, tc.page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var gotIDs []string
+				var gotPlayTime []int64
+				for _, p := range page {
+					gotIDs = append(gotIDs, p.ID)
+					gotPlayTime = append(gotPlayTime, p.PlayTime)
+				}
+				if !reflect.DeepEqual(gotIDs, tc.want) {
+					t.Errorf("игроки = %v, want %v", gotIDs, tc.want)
+				}
+				if tc.wantPlayTime != nil && !reflect.DeepEqual(gotPlayTime, tc.wantPlayTime) {
+					t.Errorf("время в игре = %v, want %v", gotPlayTime, tc.wantPlayTime)
+				}
+			})
+		}
+
+		count, err := players.Count(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 4 {
+			t.Errorf("Count = %d, want 4", count)
+		}
+		for playerID, want := range map[string]int64{alice: 0, bob2: 2, carol: 3, unknown: -1} {
+			position, err := players.PositionByName(ctx, playerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if position != want {
+				t.Errorf("PositionByName(%s) = %d, want %d", playerID, position, want)
+			}
+		}
+	})
+}
+
+func TestCountByPlayer(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		seed(t, db)
+		commands := repo.NewCommandRepository(db)
+		advancements := repo.NewAdvancementRepository(db)
+		sessions := repo.NewSessionRepository(db)
+		// Открытые сессии 2 и 4 к этому моменту длятся 2 и 1 час
+		now := t0.Add(4 * time.Hour)
+		playTime := func(ctx context.Context, filter repo.RankFilter) ([]repo.PlayerCount, error) {
+			return sessions.PlayTimeByPlayer(ctx, filter, now)
+		}
+		longest := func(ctx context.Context, filter repo.RankFilter) ([]repo.PlayerCount, error) {
+			return sessions.LongestByPlayer(ctx, filter, now)
+		}
+		// Граница периода в другом часовом поясе: сравнение не должно от него зависеть
+		msk := time.FixedZone("MSK", 3*60*60)
+		clip := repo.TimeRange{From: t0.Add(30 * time.Minute).In(msk), To: t0.Add(150 * time.Minute).In(msk)}
+
+		cases := []struct {
+			name   string
+			count  func(context.Context, repo.RankFilter) ([]repo.PlayerCount, error)
+			filter repo.RankFilter
+			want   []repo.PlayerCount
+		}{
+			{"команды", commands.CountByPlayer, repo.RankFilter{},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 5}, {PlayerID: bob, Count: 1}, {PlayerID: carol, Count: 1}}},
+			{"команды на сервере", commands.CountByPlayer, repo.RankFilter{Server: "main"},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 3}, {PlayerID: bob, Count: 1}, {PlayerID: carol, Count: 1}}},
+			{"команды за период", commands.CountByPlayer, repo.RankFilter{Period: repo.TimeRange{From: t0, To: t0.Add(time.Hour)}},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 3}, {PlayerID: bob, Count: 1}}},
+			{"первое место команд", commands.CountByPlayer, repo.RankFilter{Limit: 1},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 5}}},
+			{"достижения", advancements.CountByPlayer, repo.RankFilter{},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 2}, {PlayerID: bob, Count: 1}, {PlayerID: carol, Count: 1}}},
+			{"достижения с начала периода", advancements.CountByPlayer, repo.RankFilter{Period: repo.TimeRange{From: t0.Add(12 * time.Minute)}},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 1}, {PlayerID: bob, Count: 1}}},
+			{"пустой период", advancements.CountByPlayer, repo.RankFilter{Period: repo.TimeRange{From: t0.Add(24 * time.Hour)}},
+				nil},
+			{"сессии", sessions.CountByPlayer, repo.RankFilter{},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 2}, {PlayerID: bob, Count: 2}, {PlayerID: carol, Count: 1}}},
+			// Сессия 1 закончилась в начале периода: пересекается с ним, но времени в нём не имеет
+			{"сессии за период", sessions.CountByPlayer, repo.RankFilter{Period: clip},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 2}, {PlayerID: bob, Count: 1}}},
+			{"время в игре", playTime, repo.RankFilter{},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 10800}, {PlayerID: bob, Count: 5400}, {PlayerID: carol, Count: 3600}}},
+			{"время в игре на сервере", playTime, repo.RankFilter{Server: "main"},
+				[]repo.PlayerCount{{PlayerID: bob, Count: 5400}, {PlayerID: alice, Count: 3600}, {PlayerID: carol, Count: 3600}}},
+			{"время в игре, обрезанное периодом", playTime, repo.RankFilter{Period: clip},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 3600}}},
+			{"самая долгая сессия", longest, repo.RankFilter{},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 7200}, {PlayerID: bob, Count: 3600}, {PlayerID: carol, Count: 3600}}},
+			{"самая долгая сессия за период", longest, repo.RankFilter{Period: repo.TimeRange{From: t0.Add(150 * time.Minute)}, Limit: 1},
+				[]repo.PlayerCount{{PlayerID: alice, Count: 5400}}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				got, err := tc.count(ctx, tc.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(tc.want) || (len(got) > 0 && !reflect.DeepEqual(got, tc.want)) {
+					t.Errorf("CountByPlayer = %v, want %v", got, tc.want)
+				}
+			})
+		}
+	})
+}
+
+func TestOnConflict(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		seed(t, db)
+
+		cases := []struct {
+			name string
+			run  func(t *testing.T)
+		}{
+			{"UpsertMany обновляет ник и last_seen", func(t *testing.T) {
+				players := repo.NewPlayerRepository(db)
+				renamed := models.Player{ID: alice, Username: "alice_new", FirstSeen: t0.Add(5 * time.Hour), LastSeen: t0.Add(5 * time.Hour)}
+				added := models.Player{ID: unknown, Username: "dave", FirstSeen: t0, LastSeen: t0}
+				if err := players.UpsertMany(ctx, []models.Player{renamed, added}); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := players.FindByID(ctx, alice)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Username != "alice_new" || !got.LastSeen.Equal(renamed.LastSeen) {
+					t.Errorf("alice = %s %v, want alice_new %v", got.Username, got.LastSeen, renamed.LastSeen)
+				}
+				if want := t0.Add(-72 * time.Hour); !got.FirstSeen.Equal(want) {
+					t.Errorf("first_seen = %v, want %v: не должен меняться", got.FirstSeen, want)
+				}
+				if _, err := players.FindByID(ctx, unknown); err != nil {
+					t.Errorf("новый игрок не создан: %v", err)
+				}
+			}},
+			{"UpsertMany из старого лога не откатывает ник и last_seen", func(t *testing.T) {
+				players := repo.NewPlayerRepository(db)
+				old := models.Player{ID: carol, Username: "carol_old", FirstSeen: t0.Add(-100 * time.Hour), LastSeen: t0.Add(-99 * time.Hour)}
+				if err := players.UpsertMany(ctx, []models.Player{old}); err != nil {
+					t.Fatal(err)
+				}
+				if err := players.UpdateLastSeen(ctx, carol, t0.Add(-98*time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := players.FindByID(ctx, carol)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := t0.Add(-47 * time.Hour); got.Username != "carol" || !got.LastSeen.Equal(want) {
+					t.Errorf("carol = %s %v, want carol %v", got.Username, got.LastSeen, want)
+				}
+				if !got.FirstSeen.Equal(old.FirstSeen) {
+					t.Errorf("first_seen = %v, want %v", got.FirstSeen, old.FirstSeen)
+				}
+			}},
+			{"чёрный список Discord без повторов", func(t *testing.T) {
+				discord := repo.NewDiscordRepository(db)
+				for range 2 {
+					if err := discord.AddToBlacklist(ctx, "events", bob); err != nil {
+						t.Fatal(err)
+					}
+				}
+				blacklist, err := discord.GetBlacklist(ctx, "events")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(blacklist) != 1 {
+					t.Errorf("записей = %d, want 1", len(blacklist))
+				}
+			}},
+			{"чёрный список уведомлений без повторов", func(t *testing.T) {
+				notifications := repo.NewNotificationRepository(db)
+				for range 2 {
+					if err := notifications.AddToBlacklist(ctx, 42, bob); err != nil {
+						t.Fatal(err)
+					}
+				}
+				blacklist, err := notifications.GetBlacklist(ctx, 42)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(blacklist) != 1 {
+					t.Errorf("записей = %d, want 1", len(blacklist))
+				}
+			}},
+			{"привязка Telegram заменяется", func(t *testing.T) {
+				links := repo.NewTelegramLinkRepository(db)
+				if err := links.Save(ctx, 42, alice); err != nil {
+					t.Fatal(err)
+				}
+				if err := links.Save(ctx, 42, carol); err != nil {
+					t.Fatal(err)
+				}
+				link, err := links.Get(ctx, 42)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if link == nil || link.PlayerID != carol || link.Player.Username != "carol" {
+					t.Errorf("привязка = %+v, want carol", link)
+				}
+
+				// Игрока нельзя привязать ко второму пользователю
+				if err := links.Save(ctx, 43, carol); err == nil {
+					t.Error("игрок привязан ко второму пользователю без ошибки")
+				}
+				byPlayer, err := links.GetByPlayer(ctx, carol)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if byPlayer == nil || byPlayer.TelegramID != 42 {
+					t.Errorf("привязка игрока = %+v, want пользователь 42", byPlayer)
+				}
+			}},
+			{"уведомление получателю не повторяется", func(t *testing.T) {
+				outbox := repo.NewOutboxRepository(db)
+				message := models.OutboxMessage{
+					EventRef: "event-1", Channel: models.ChannelTelegram, ChatID: 42, Payload: "text",
+					Status: models.OutboxPending, NextAttemptAt: t0, ExpiresAt: t0.Add(time.Hour),
+				}
+				other := message
+				other.ChatID = 43
+				if err := outbox.CreateMany(ctx, []models.OutboxMessage{message}); err != nil {
+					t.Fatal(err)
+				}
+				if err := outbox.CreateMany(ctx, []models.OutboxMessage{message, other}); err != nil {
+					t.Fatal(err)
+				}
+				counts, err := outbox.CountByStatus(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if counts[models.OutboxPending] != 2 {
+					t.Errorf("ожидающих = %d, want 2", counts[models.OutboxPending])
+				}
+			}},
+			{"повторный отпечаток события — ошибка", func(t *testing.T) {
+				events := repo.NewEventRepository(db)
+				event := models.ProcessedEvent{Fingerprint: "fp-1", Rule: "join"}
+				if err := events.CreateMany(ctx, []models.ProcessedEvent{event}); err != nil {
+					t.Fatal(err)
+				}
+				if err := events.CreateMany(ctx, []models.ProcessedEvent{event}); err == nil {
+					t.Error("повторный отпечаток записан без ошибки")
+				}
+				existing, err := events.ListExisting(ctx, []string{"fp-1", "fp-2"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(existing, map[string]bool{"fp-1": true}) {
+					t.Errorf("ListExisting = %v, want fp-1", existing)
+				}
+			}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, tc.run)
+		}
+	})
+}
+
+// collectPages проходит выборку постранично, передавая page последнюю запись предыдущей страницы
+func collectPages[T any](t *testing.T, page func(last *T) ([]T, error)) []T {
+	t.Helper()
+	var all []T
+	var last *T
+	for range 100 {
+		items, err := page(last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) == 0 {
+			return all
+		}
+		all = append(all, items...)
+		last = &all[len(all)-1]
+	}
+	t.Fatal("выборка не закончилась за 100 страниц")
+	return nil
+}
+
+// sessionIndex возвращает номер сессии fixture по id; -1 — сессия не из fixture
+func sessionIndex(f fixture, id uint) int {
+	for i, s := range f.sessions {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func assertSameSet(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	set := make(map[string]bool, len(got))
+	for _, v := range got {
+		set[v] = true
+	}
+	if len(set) != len(got) || len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	for _, v := range want {
+		if !set[v] {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
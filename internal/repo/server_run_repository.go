@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ServerRunRepository interface {
+	// Start записывает запуск сервера. Незакрытый предыдущий запуск (сервер упал
+	// без строки остановки) закрывается временем нового запуска.
+	Start(ctx context.Context, server string, at time.Time) error
+	// Stop закрывает текущий запуск сервера; без открытого запуска ничего не делает
+	Stop(ctx context.Context, server string, at time.Time) error
+	// Latest возвращает последний запуск сервера; nil, если запусков не было
+	Latest(ctx context.Context, server string) (*models.ServerRun, error)
+}
+
+type serverRunRepository struct {
+	db *gorm.DB
+}
+
+func NewServerRunRepository(db *gorm.DB) ServerRunRepository {
+	return &serverRunRepository{db: db}
+}
+
+func (r *serverRunRepository) Start(ctx context.Context, server string, at time.Time) error {
+	if err := r.Stop(ctx, server, at); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(&models.ServerRun{Server: server, StartedAt: at}).Error
+}
+
+func (r *serverRunRepository) Stop(ctx context.Context, server string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ServerRun{}).
+		Where("server = ? AND stopped_at IS NULL AND started_at <= ?", server, at).
+		Update("stopped_at", at).Error
+}
+
+func (r *serverRunRepository) Latest(ctx context.Context, server string) (*models.ServerRun, error) {
+	var run models.ServerRun
+	err := r.db.WithContext(ctx).
+		Where("server = ?", server).
+		Order("started_at DESC").Order("id DESC").
+		First(&run).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &run, err
+}
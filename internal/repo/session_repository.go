@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"mine-parser/internal/models"
 	"time"
 
@@ -8,11 +9,35 @@ import (
 )
 
 type SessionRepository interface {
-	Create(session *models.Session) error
-	CloseSession(sessionID uint, leaveTime time.Time) error
-	GetActiveSessionByPlayer(playerID string) (*models.Session, error)
-	ListByPlayer(playerID string) ([]models.Session, error)
-	ListActive() ([]models.Session, error)
+	Create(ctx context.Context, session *models.Session) error
+	CloseSession(ctx context.Context, sessionID uint, leaveTime time.Time) error
+	GetActiveSessionByPlayer(ctx context.Context, playerID string) (*models.Session, error)
+	ListByPlayer(ctx context.Context, playerID string) ([]models.Session, error)
+	ListActive(ctx context.Context) ([]models.Session, error)
+	// ListOpenByPlayers возвращает открытые сессии каждого из игроков по возрастанию времени входа
+	ListOpenByPlayers(ctx context.Context, playerIDs []string) (map[string][]*models.Session, error)
+	CreateMany(ctx context.Context, sessions []*models.Session) error
+	// List возвращает страницу сессий от новых к старым
+	List(ctx context.Context, filter SessionFilter) ([]models.Session, error)
+	// CountByPlayer возвращает число сессий игроков, пересекающихся с периодом, по убыванию
+	CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error)
+	// PlayTimeByPlayer возвращает время в игре за период в секундах, по убыванию.
+	// Сессии обрезаются границами периода, открытые длятся до now.
+	PlayTimeByPlayer(ctx context.Context, filter RankFilter, now time.Time) ([]PlayerCount, error)
+	// LongestByPlayer возвращает самую долгую сессию игрока за период в секундах, по убыванию;
+	// длительность считается так же, как в PlayTimeByPlayer
+	LongestByPlayer(ctx context.Context, filter RankFilter, now time.Time) ([]PlayerCount, error)
+}
+
+// SessionFilter — параметры выборки сессий
+type SessionFilter struct {
+	PlayerID string // пусто — сессии всех игроков
+	Server   string // пусто — сессии на всех серверах
+	// Period выбирает сессии, пересекающиеся с интервалом (в том числе ещё открытые)
+	Period TimeRange
+	After  *TimeKey // ключ (join_time, id) последней сессии предыдущей страницы
+	Offset int      // пропустить записей — для списков с номерами страниц
+	Limit  int
 }
 
 type sessionRepository struct {
@@ -23,19 +48,19 @@ func NewSessionRepository(db *gorm.DB) SessionRepository {
 	return &sessionRepository{db: db}
 }
 
-func (r *sessionRepository) Create(session *models.Session) error {
-	return r.db.Create(session).Error
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
 }
 
-func (r *sessionRepository) CloseSession(sessionID uint, leaveTime time.Time) error {
-	return r.db.Model(&models.Session{}).
+func (r *sessionRepository) CloseSession(ctx context.Context, sessionID uint, leaveTime time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
 		Where("id = ?", sessionID).
 		Update("leave_time", leaveTime).Error
 }
 
-func (r *sessionRepository) GetActiveSessionByPlayer(playerID string) (*models.Session, error) {
+func (r *sessionRepository) GetActiveSessionByPlayer(ctx context.Context, playerID string) (*models.Session, error) {
 	var sessions []models.Session
-	err := r.db.Where("player_id = ? AND leave_time IS NULL", playerID).
+	err := r.db.WithContext(ctx).Where("player_id = ? AND leave_time IS NULL", playerID).
 		Order("join_time DESC").
 		Limit(1).
 		Find(&sessions).Error
@@ -48,18 +73,141 @@ func (r *sessionRepository) GetActiveSessionByPlayer(playerID string) (*models.S
 	return &sessions[0], nil
 }
 
-func (r *sessionRepository) ListByPlayer(playerID string) ([]models.Session, error) {
+func (r *sessionRepository) ListByPlayer(ctx context.Context, playerID string) ([]models.Session, error) {
 	var sessions []models.Session
-	err := r.db.Where("player_id = ?", playerID).
+	err := r.db.WithContext(ctx).Where("player_id = ?", playerID).
 		Order("join_time DESC").
 		Find(&sessions).Error
 	return sessions, err
 }
 
-func (r *sessionRepository) ListActive() ([]models.Session, error) {
+func (r *sessionRepository) ListActive(ctx context.Context) ([]models.Session, error) {
 	var sessions []models.Session
-	err := r.db.Where("leave_time IS NULL").
+	err := r.db.WithContext(ctx).Where("leave_time IS NULL").
 		Order("join_time DESC").
 		Find(&sessions).Error
 	return sessions, err
 }
+
+func (r *sessionRepository) ListOpenByPlayers(ctx context.Context, playerIDs []string) (map[string][]*models.Session, error) {
+	open := make(map[string][]*models.Session)
+	if len(playerIDs) == 0 {
+		return open, nil
+	}
+
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("player_id IN ? AND leave_time IS NULL", playerIDs).
+		Order("join_time ASC").Order("id ASC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		open[sessions[i].PlayerID] = append(open[sessions[i].PlayerID], &sessions[i])
+	}
+	return open, nil
+}
+
+func (r *sessionRepository) CreateMany(ctx context.Context, sessions []*models.Session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(sessions, batchInsertSize).Error
+}
+
+func (r *sessionRepository) List(ctx context.Context, filter SessionFilter) ([]models.Session, error) {
+	query := r.db.WithContext(ctx).Model(&models.Session{})
+	if filter.PlayerID != "" {
+		query = query.Where("player_id = ?", filter.PlayerID)
+	}
+	if filter.Server != "" {
+		query = query.Where("server = ?", filter.Server)
+	}
+	query = overlapping(query, filter.Period)
+
+	var sessions []models.Session
+	err := newestFirst(query, "join_time", "id", filter.After, filter.Limit).Offset(filter.Offset).Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) CountByPlayer(ctx context.Context, filter RankFilter) ([]PlayerCount, error) {
+	query := r.db.WithContext(ctx).Model(&models.Session{}).
+		Select("player_id, COUNT(*) AS count")
+	if filter.Server != "" {
+		query = query.Where("server = ?", filter.Server)
+	}
+	query = overlapping(query, filter.Period).
+		Group("player_id").
+		Order("count DESC").Order("player_id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var counts []PlayerCount
+	err := query.Scan(&counts).Error
+	return counts, err
+}
+
+func (r *sessionRepository) PlayTimeByPlayer(ctx context.Context, filter RankFilter, now time.Time) ([]PlayerCount, error) {
+	return r.durationByPlayer(ctx, "SUM", filter, now)
+}
+
+func (r *sessionRepository) LongestByPlayer(ctx context.Context, filter RankFilter, now time.Time) ([]PlayerCount, error) {
+	return r.durationByPlayer(ctx, "MAX", filter, now)
+}
+
+// durationByPlayer агрегирует функцией aggregate длительности сессий, обрезанные периодом
+func (r *sessionRepository) durationByPlayer(ctx context.Context, aggregate string, filter RankFilter, now time.Time) ([]PlayerCount, error) {
+	start, end := "join_time", "COALESCE(leave_time, ?)"
+	var startArgs []interface{}
+	endArgs := []interface{}{now.UTC()}
+	if from := filter.Period.From; !from.IsZero() {
+		start = "CASE WHEN join_time < ? THEN ? ELSE join_time END"
+		startArgs = []interface{}{from.UTC(), from.UTC()}
+	}
+	if to := filter.Period.To; !to.IsZero() {
+		end = "CASE WHEN " + end + " > ? THEN ? ELSE " + end + " END"
+		endArgs = []interface{}{now.UTC(), to.UTC(), to.UTC(), now.UTC()}
+	}
+
+	clipped := r.db.Model(&models.Session{}).
+		Select("player_id, "+secondsBetween(r.db, start, end)+" AS seconds", append(endArgs, startArgs...)...)
+	if filter.Server != "" {
+		clipped = clipped.Where("server = ?", filter.Server)
+	}
+	clipped = overlapping(clipped, filter.Period)
+
+	query := r.db.WithContext(ctx).Table("(?) AS clipped", clipped).
+		Select("player_id, " + aggregate + "(seconds) AS count").
+		Where("seconds > 0").
+		Group("player_id").
+		Order("count DESC").Order("player_id")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var counts []PlayerCount
+	err := query.Scan(&counts).Error
+	return counts, err
+}
+
+// overlapping выбирает сессии, пересекающиеся с интервалом r, в том числе ещё открытые
+func overlapping(query *gorm.DB, r TimeRange) *gorm.DB {
+	if !r.From.IsZero() {
+		query = query.Where("leave_time IS NULL OR leave_time >= ?", r.From.UTC())
+	}
+	if !r.To.IsZero() {
+		query = query.Where("join_time < ?", r.To.UTC())
+	}
+	return query
+}
+
+// secondsBetween — выражение SQL для целого числа секунд от start до end.
+// SQLite хранит время текстом, и разность считается через julianday (в сутках).
+func secondsBetween(db *gorm.DB, start, end string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "CAST(ROUND((julianday(" + end + ") - julianday(" + start + ")) * 86400) AS INTEGER)"
+	}
+	return "CAST(EXTRACT(EPOCH FROM (" + end + ") - (" + start + ")) AS BIGINT)"
+}
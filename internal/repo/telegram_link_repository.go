@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TelegramLinkRepository interface {
+	// Get возвращает привязку пользователя вместе с игроком или nil, если её нет
+	Get(ctx context.Context, telegramID int64) (*models.TelegramLink, error)
+	// GetByPlayer возвращает привязку игрока или nil, если его не привязал никто
+	GetByPlayer(ctx context.Context, playerID string) (*models.TelegramLink, error)
+	// Save привязывает пользователя к игроку, заменяя прежнюю привязку пользователя.
+	// Игрок, привязанный к другому пользователю, — ошибка уникальности.
+	Save(ctx context.Context, telegramID int64, playerID string) error
+	Delete(ctx context.Context, telegramID int64) error
+}
+
+type telegramLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewTelegramLinkRepository(db *gorm.DB) TelegramLinkRepository {
+	return &telegramLinkRepository{db: db}
+}
+
+func (r *telegramLinkRepository) Get(ctx context.Context, telegramID int64) (*models.TelegramLink, error) {
+	return r.find(r.db.WithContext(ctx).Preload("Player").Where("telegram_id = ?", telegramID))
+}
+
+func (r *telegramLinkRepository) GetByPlayer(ctx context.Context, playerID string) (*models.TelegramLink, error) {
+	return r.find(r.db.WithContext(ctx).Where("player_id = ?", playerID))
+}
+
+// find возвращает первую привязку из выборки query или nil
+func (r *telegramLinkRepository) find(query *gorm.DB) (*models.TelegramLink, error) {
+	// Find вместо First: отсутствие привязки — обычный случай, а не ошибка в журнале запросов
+	var links []models.TelegramLink
+	err := query.Limit(1).Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+	return &links[0], nil
+}
+
+func (r *telegramLinkRepository) Save(ctx context.Context, telegramID int64, playerID string) error {
+	link := models.TelegramLink{TelegramID: telegramID, PlayerID: playerID}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"player_id", "updated_at"}),
+	}).Create(&link).Error
+}
+
+func (r *telegramLinkRepository) Delete(ctx context.Context, telegramID int64) error {
+	return r.db.WithContext(ctx).Where("telegram_id = ?", telegramID).Delete(&models.TelegramLink{}).Error
+}
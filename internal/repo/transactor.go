@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// batchInsertSize — максимальное число строк в одном INSERT при массовой вставке
+const batchInsertSize = 500
+
+// TxRepositories — набор репозиториев, работающих внутри одной транзакции
+type TxRepositories struct {
+	Players       PlayerRepository
+	Sessions      SessionRepository
+	Commands      CommandRepository
+	Advancements  AdvancementRepository
+	Events        EventRepository
+	Notifications NotificationRepository
+	Outbox        OutboxRepository
+	ServerRuns    ServerRunRepository
+	Discord       DiscordRepository
+}
+
+type Transactor interface {
+	// InTransaction выполняет fn в транзакции; при ошибке все изменения откатываются
+	InTransaction(ctx context.Context, fn func(r TxRepositories) error) error
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) InTransaction(ctx context.Context, fn func(r TxRepositories) error) error {
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(TxRepositories{
+			Players:       NewPlayerRepository(tx),
+			Sessions:      NewSessionRepository(tx),
+			Commands:      NewCommandRepository(tx),
+			Advancements:  NewAdvancementRepository(tx),
+			Events:        NewEventRepository(tx),
+			Notifications: NewNotificationRepository(tx),
+			Outbox:        NewOutboxRepository(tx),
+			ServerRuns:    NewServerRunRepository(tx),
+			Discord:       NewDiscordRepository(tx),
+		})
+	})
+}
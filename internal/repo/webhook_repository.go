@@ -0,0 +1,209 @@
+package repo
+
+import (
+	"context"
+	"mine-parser/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeliveryAttempt — результат попытки доставки для журнала
+type DeliveryAttempt struct {
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+}
+
+type WebhookRepository interface {
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ListDue возвращает доставки, которые пора выполнить, от старых к новым
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// BeginAttempt увеличивает счётчик попыток и откладывает следующую попытку до nextAttempt
+	BeginAttempt(ctx context.Context, id uint, nextAttempt time.Time) error
+	MarkDelivered(ctx context.Context, id uint, deliveredAt time.Time, attempt DeliveryAttempt) error
+	// MarkRetry оставляет доставку в очереди с результатом последней попытки
+	MarkRetry(ctx context.Context, id uint, nextAttempt time.Time, attempt DeliveryAttempt) error
+	MarkFailed(ctx context.Context, id uint, attempt DeliveryAttempt) error
+	// Postpone откладывает до until ожидающие доставки вебхука, назначенные раньше
+	Postpone(ctx context.Context, webhook string, until time.Time) error
+	// FailPending завершает ошибкой все ожидающие доставки вебхука; reason
+	// дописывается перед ошибкой их последней попытки
+	FailPending(ctx context.Context, webhook, reason string) (int64, error)
+	// ListDeliveries возвращает последние доставки вебхука, от новых к старым
+	ListDeliveries(ctx context.Context, webhook string, limit int) ([]models.WebhookDelivery, error)
+	// CountPending возвращает число ожидающих доставок по вебхукам
+	CountPending(ctx context.Context) (map[string]int64, error)
+	// DeleteFinishedBefore удаляет из журнала завершённые доставки, созданные до before
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+
+	ListStates(ctx context.Context) ([]models.WebhookState, error)
+	// RecordFailure продлевает серию неудач вебхука и возвращает её длину
+	RecordFailure(ctx context.Context, webhook string) (int, error)
+	// RecordSuccess обрывает серию неудач вебхука
+	RecordSuccess(ctx context.Context, webhook string) error
+	Disable(ctx context.Context, webhook, reason string, at time.Time) error
+	// Enable снимает отключение и обнуляет серию неудач
+	Enable(ctx context.Context, webhook string) error
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(deliveries, batchInsertSize).Error
+}
+
+func (r *webhookRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) BeginAttempt(ctx context.Context, id uint, nextAttempt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttempt,
+		}).Error
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id uint, deliveredAt time.Time, attempt DeliveryAttempt) error {
+	updates := attemptUpdates(attempt)
+	updates["status"] = models.WebhookDelivered
+	updates["delivered_at"] = deliveredAt
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *webhookRepository) MarkRetry(ctx context.Context, id uint, nextAttempt time.Time, attempt DeliveryAttempt) error {
+	updates := attemptUpdates(attempt)
+	updates["next_attempt_at"] = nextAttempt
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *webhookRepository) MarkFailed(ctx context.Context, id uint, attempt DeliveryAttempt) error {
+	updates := attemptUpdates(attempt)
+	updates["status"] = models.WebhookFailed
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *webhookRepository) Postpone(ctx context.Context, webhook string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("webhook = ? AND status = ? AND next_attempt_at < ?", webhook, models.WebhookPending, until).
+		Update("next_attempt_at", until).Error
+}
+
+func attemptUpdates(attempt DeliveryAttempt) map[string]interface{} {
+	return map[string]interface{}{
+		"status_code": attempt.StatusCode,
+		"last_error":  attempt.Error,
+		"duration_ms": attempt.Duration.Milliseconds(),
+	}
+}
+
+func (r *webhookRepository) FailPending(ctx context.Context, webhook, reason string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("webhook = ? AND status = ?", webhook, models.WebhookPending).
+		Updates(map[string]interface{}{
+			"status": models.WebhookFailed,
+			// Ошибка последней попытки остаётся в журнале после причины отмены
+			"last_error": gorm.Expr("CASE WHEN COALESCE(last_error, '') = '' THEN ? ELSE CAST(? AS TEXT) || ': ' || last_error END", reason, reason),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhook string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook = ?", webhook).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) CountPending(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Webhook string
+		Count   int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Select("webhook, COUNT(*) AS count").
+		Where("status = ?", models.WebhookPending).
+		Group("webhook").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Webhook] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *webhookRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", models.WebhookPending, before).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *webhookRepository) ListStates(ctx context.Context) ([]models.WebhookState, error) {
+	var states []models.WebhookState
+	err := r.db.WithContext(ctx).Order("webhook ASC").Find(&states).Error
+	return states, err
+}
+
+func (r *webhookRepository) RecordFailure(ctx context.Context, webhook string) (int, error) {
+	state := models.WebhookState{Webhook: webhook, ConsecutiveFailures: 1}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "webhook"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"consecutive_failures": gorm.Expr("webhook_states.consecutive_failures + 1"),
+			"updated_at":           time.Now().UTC(),
+		}),
+	}).Create(&state).Error
+	if err != nil {
+		return 0, err
+	}
+	err = r.db.WithContext(ctx).Where("webhook = ?", webhook).First(&state).Error
+	return state.ConsecutiveFailures, err
+}
+
+func (r *webhookRepository) RecordSuccess(ctx context.Context, webhook string) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookState{}).
+		Where("webhook = ? AND consecutive_failures > 0", webhook).
+		Update("consecutive_failures", 0).Error
+}
+
+func (r *webhookRepository) Disable(ctx context.Context, webhook, reason string, at time.Time) error {
+	state := models.WebhookState{Webhook: webhook, DisabledAt: &at, DisabledReason: reason}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "webhook"}},
+		DoUpdates: clause.AssignmentColumns([]string{"disabled_at", "disabled_reason", "updated_at"}),
+	}).Create(&state).Error
+}
+
+func (r *webhookRepository) Enable(ctx context.Context, webhook string) error {
+	return r.db.WithContext(ctx).Model(&models.WebhookState{}).
+		Where("webhook = ?", webhook).
+		Updates(map[string]interface{}{
+			"consecutive_failures": 0,
+			"disabled_at":          nil,
+			"disabled_reason":      "",
+		}).Error
+}
@@ -1,15 +1,15 @@
 package service
 
 import (
+	"context"
 	"mine-parser/internal/models"
 	"mine-parser/internal/repo"
-	"time"
 )
 
 type AdvancementService interface {
-	GrantAdvancement(playerID, advancementName string, timestamp time.Time) error
-	GetPlayerAdvancements(playerID string) ([]models.Advancement, error)
-	IsAdvancementUnlocked(playerID, advancementName string) (bool, error)
+	GetPlayerAdvancements(ctx context.Context, playerID string) ([]models.Advancement, error)
+	IsAdvancementUnlocked(ctx context.Context, playerID, advancementName string) (bool, error)
+	ListAdvancements(ctx context.Context, filter repo.AdvancementFilter) ([]models.Advancement, error)
 }
 
 type advancementService struct {
@@ -22,32 +22,14 @@ func NewAdvancementService(advanceRepo repo.AdvancementRepository) AdvancementSe
 	}
 }
 
-func (s *advancementService) GrantAdvancement(playerID, advancementName string, timestamp time.Time) error {
-	// Проверяем, не получено ли уже это достижение
-	hasCompleted, err := s.advanceRepo.HasPlayerCompleted(advancementName, playerID)
-	if err != nil {
-		return err
-	}
-
-	// Если достижение уже получено, не создаем дубликат
-	if hasCompleted {
-		return nil
-	}
-
-	// Создаем новое достижение
-	advancement := &models.Advancement{
-		PlayerID:        playerID,
-		Timestamp:       timestamp,
-		AdvancementName: advancementName,
-	}
-
-	return s.advanceRepo.Create(advancement)
+func (s *advancementService) GetPlayerAdvancements(ctx context.Context, playerID string) ([]models.Advancement, error) {
+	return s.advanceRepo.ListByPlayer(ctx, playerID)
 }
 
-func (s *advancementService) GetPlayerAdvancements(playerID string) ([]models.Advancement, error) {
-	return s.advanceRepo.ListByPlayer(playerID)
+func (s *advancementService) IsAdvancementUnlocked(ctx context.Context, playerID, advancementName string) (bool, error) {
+	return s.advanceRepo.HasPlayerCompleted(ctx, advancementName, playerID)
 }
 
-func (s *advancementService) IsAdvancementUnlocked(playerID, advancementName string) (bool, error) {
-	return s.advanceRepo.HasPlayerCompleted(advancementName, playerID)
+func (s *advancementService) ListAdvancements(ctx context.Context, filter repo.AdvancementFilter) ([]models.Advancement, error) {
+	return s.advanceRepo.List(ctx, filter)
 }
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"slices"
+	"strings"
+)
+
+// DiscordChannel — канал Discord из конфигурации с действующими настройками уведомлений
+type DiscordChannel struct {
+	Name    string
+	Enabled bool
+	Events  []string // типы событий в порядке config.DiscordEventTypes
+}
+
+// Notifies сообщает, уведомляется ли канал о событиях типа eventType
+func (c DiscordChannel) Notifies(eventType string) bool {
+	return c.Enabled && slices.Contains(c.Events, eventType)
+}
+
+// DiscordNotificationService — подписки каналов Discord: то же, что NotificationService
+// для чатов Telegram, и выбор событий, о которых канал уведомляется
+type DiscordNotificationService interface {
+	// Channels возвращает каналы в порядке конфигурации
+	Channels(ctx context.Context) ([]DiscordChannel, error)
+	GetChannel(ctx context.Context, name string) (DiscordChannel, error)
+	ToggleSubscription(ctx context.Context, name string) (bool, error)
+	ToggleEvent(ctx context.Context, name, eventType string) (bool, error) // возвращает true если событие включено
+	// Методы для черного списка
+	ToggleBlacklist(ctx context.Context, name, playerID string) (bool, error) // возвращает true если добавлен в черный список
+	GetBlacklist(ctx context.Context, name string) ([]models.DiscordBlacklist, error)
+}
+
+type discordNotificationService struct {
+	discordRepo repo.DiscordRepository
+	cfg         *config.Store
+}
+
+func NewDiscordNotificationService(discordRepo repo.DiscordRepository, cfg *config.Store) DiscordNotificationService {
+	return &discordNotificationService{
+		discordRepo: discordRepo,
+		cfg:         cfg,
+	}
+}
+
+func (s *discordNotificationService) Channels(ctx context.Context) ([]DiscordChannel, error) {
+	subscriptions, err := s.discordRepo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return discordChannels(s.cfg.Get().Discord, subscriptions), nil
+}
+
+func (s *discordNotificationService) GetChannel(ctx context.Context, name string) (DiscordChannel, error) {
+	channelCfg, ok := s.cfg.Get().Discord.Channel(name)
+	if !ok {
+		return DiscordChannel{}, fmt.Errorf("канал Discord %q не найден в конфигурации", name)
+	}
+	subscription, err := s.discordRepo.GetSubscription(ctx, name)
+	if err != nil {
+		return DiscordChannel{}, err
+	}
+	return resolveDiscordChannel(channelCfg, subscription), nil
+}
+
+func (s *discordNotificationService) ToggleSubscription(ctx context.Context, name string) (bool, error) {
+	channel, err := s.GetChannel(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	channel.Enabled = !channel.Enabled
+	return channel.Enabled, s.save(ctx, channel)
+}
+
+func (s *discordNotificationService) ToggleEvent(ctx context.Context, name, eventType string) (bool, error) {
+	if !slices.Contains(config.DiscordEventTypes, events.Type(eventType)) {
+		return false, fmt.Errorf("тип события %q не поддерживается", eventType)
+	}
+	channel, err := s.GetChannel(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	enabled := !slices.Contains(channel.Events, eventType)
+	if enabled {
+		channel.Events = append(channel.Events, eventType)
+	} else {
+		channel.Events = slices.DeleteFunc(channel.Events, func(e string) bool { return e == eventType })
+	}
+	return enabled, s.save(ctx, channel)
+}
+
+func (s *discordNotificationService) save(ctx context.Context, channel DiscordChannel) error {
+	return s.discordRepo.SaveSubscription(ctx, models.DiscordSubscription{
+		Channel: channel.Name,
+		Enabled: channel.Enabled,
+		Events:  strings.Join(sortDiscordEvents(channel.Events), ","),
+	})
+}
+
+func (s *discordNotificationService) ToggleBlacklist(ctx context.Context, name, playerID string) (bool, error) {
+	if _, ok := s.cfg.Get().Discord.Channel(name); !ok {
+		return false, fmt.Errorf("канал Discord %q не найден в конфигурации", name)
+	}
+	isInBlacklist, err := s.discordRepo.IsInBlacklist(ctx, name, playerID)
+	if err != nil {
+		return false, err
+	}
+
+	if isInBlacklist {
+		return false, s.discordRepo.RemoveFromBlacklist(ctx, name, playerID)
+	}
+	return true, s.discordRepo.AddToBlacklist(ctx, name, playerID)
+}
+
+func (s *discordNotificationService) GetBlacklist(ctx context.Context, name string) ([]models.DiscordBlacklist, error) {
+	return s.discordRepo.GetBlacklist(ctx, name)
+}
+
+// discordChannels сопоставляет каналы из конфигурации с их сохранёнными настройками
+func discordChannels(cfg config.DiscordConfig, subscriptions []models.DiscordSubscription) []DiscordChannel {
+	byName := make(map[string]*models.DiscordSubscription, len(subscriptions))
+	for i := range subscriptions {
+		byName[subscriptions[i].Channel] = &subscriptions[i]
+	}
+	channels := make([]DiscordChannel, 0, len(cfg.Channels))
+	for _, channelCfg := range cfg.Channels {
+		channels = append(channels, resolveDiscordChannel(channelCfg, byName[channelCfg.Name]))
+	}
+	return channels
+}
+
+// resolveDiscordChannel возвращает действующие настройки канала: сохранённые администратором,
+// а если их нет — из конфигурации. Канал из конфигурации включён сразу, в отличие от чатов
+// Telegram: его добавил администратор, а не случайный собеседник бота.
+func resolveDiscordChannel(cfg config.DiscordChannelConfig, subscription *models.DiscordSubscription) DiscordChannel {
+	if subscription == nil {
+		return DiscordChannel{Name: cfg.Name, Enabled: true, Events: sortDiscordEvents(cfg.DefaultEvents())}
+	}
+	var eventTypes []string
+	for _, eventType := range strings.Split(subscription.Events, ",") {
+		if eventType != "" {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	return DiscordChannel{Name: cfg.Name, Enabled: subscription.Enabled, Events: sortDiscordEvents(eventTypes)}
+}
+
+// sortDiscordEvents упорядочивает типы событий как в config.DiscordEventTypes
+func sortDiscordEvents(eventTypes []string) []string {
+	sorted := slices.Clone(eventTypes)
+	slices.SortFunc(sorted, func(a, b string) int {
+		return slices.Index(config.DiscordEventTypes, events.Type(a)) - slices.Index(config.DiscordEventTypes, events.Type(b))
+	})
+	return sorted
+}
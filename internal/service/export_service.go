@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"io"
+	"mine-parser/internal/export"
+	"mine-parser/internal/repo"
+)
+
+type ExportService interface {
+	// Export выгружает таблицу в w потоком и возвращает число записей
+	Export(ctx context.Context, w io.Writer, table export.Table, format export.Format, filter export.Filter) (int64, error)
+}
+
+type exportService struct {
+	exportRepo repo.ExportRepository
+}
+
+func NewExportService(exportRepo repo.ExportRepository) ExportService {
+	return &exportService{exportRepo: exportRepo}
+}
+
+func (s *exportService) Export(ctx context.Context, w io.Writer, table export.Table, format export.Format, filter export.Filter) (int64, error) {
+	return export.Write(ctx, w, s.exportRepo, table, format, filter)
+}
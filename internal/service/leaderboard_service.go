@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"time"
+)
+
+// LeaderboardPeriod — календарный период рейтинга
+type LeaderboardPeriod string
+
+const (
+	PeriodWeek  LeaderboardPeriod = "week"  // с понедельника текущей недели
+	PeriodMonth LeaderboardPeriod = "month" // с первого числа текущего месяца
+	PeriodAll   LeaderboardPeriod = "all"   // всё время
+)
+
+// PeriodRange — границы периода на момент now; недели и месяцы отсчитываются в часовом поясе loc
+func PeriodRange(period LeaderboardPeriod, now time.Time, loc *time.Location) repo.TimeRange {
+	now = now.In(loc)
+	switch period {
+	case PeriodWeek:
+		// time.Weekday считает с воскресенья, неделя начинается с понедельника
+		days := (int(now.Weekday()) + 6) % 7
+		return repo.TimeRange{From: time.Date(now.Year(), now.Month(), now.Day()-days, 0, 0, 0, 0, loc), To: now}
+	case PeriodMonth:
+		return repo.TimeRange{From: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc), To: now}
+	default:
+		return repo.TimeRange{}
+	}
+}
+
+// LeaderboardQuery — какой рейтинг построить и для кого
+type LeaderboardQuery struct {
+	Metric     string
+	Period     repo.TimeRange
+	Server     string // пусто — все серверы
+	Limit      int    // мест в выдаче
+	TelegramID int64  // пользователь, которому показать его место; 0 — не показывать
+}
+
+// Leaderboard — первые места рейтинга и место пользователя, запросившего его
+type Leaderboard struct {
+	Entries []LeaderboardEntry
+	Total   int            // игроков в рейтинге за период
+	Viewer  *models.Player // игрок, привязанный к пользователю; nil, если привязки нет
+	// ViewerEntry — место игрока Viewer; nil, если за период у него нет показателя
+	ViewerEntry *LeaderboardEntry
+}
+
+// ErrPlayerLinked — игрока уже привязал другой пользователь Telegram
+var ErrPlayerLinked = errors.New("игрок привязан к другому аккаунту Telegram")
+
+type LeaderboardService interface {
+	Leaderboard(ctx context.Context, query LeaderboardQuery) (*Leaderboard, error)
+	// LinkedPlayer возвращает игрока, привязанного к пользователю Telegram, или nil
+	LinkedPlayer(ctx context.Context, telegramID int64) (*models.Player, error)
+	// Link привязывает пользователя к игроку; ErrPlayerLinked, если игрока привязал другой
+	Link(ctx context.Context, telegramID int64, playerID string) error
+	Unlink(ctx context.Context, telegramID int64) error
+}
+
+type leaderboardService struct {
+	stats    StatsService
+	linkRepo repo.TelegramLinkRepository
+}
+
+func NewLeaderboardService(stats StatsService, linkRepo repo.TelegramLinkRepository) LeaderboardService {
+	return &leaderboardService{stats: stats, linkRepo: linkRepo}
+}
+
+func (s *leaderboardService) Leaderboard(ctx context.Context, query LeaderboardQuery) (*Leaderboard, error) {
+	// Рейтинг строится целиком: место пользователя может быть за пределами первых Limit
+	entries, err := s.stats.Leaderboard(ctx, query.Metric, repo.RankFilter{Period: query.Period, Server: query.Server})
+	if err != nil {
+		return nil, err
+	}
+
+	board := &Leaderboard{Entries: entries, Total: len(entries)}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		board.Entries = entries[:query.Limit]
+	}
+	if query.TelegramID == 0 {
+		return board, nil
+	}
+
+	if board.Viewer, err = s.LinkedPlayer(ctx, query.TelegramID); err != nil {
+		return nil, err
+	}
+	if board.Viewer != nil {
+		for i := range entries {
+			if entries[i].Player.ID == board.Viewer.ID {
+				board.ViewerEntry = &entries[i]
+				break
+			}
+		}
+	}
+	return board, nil
+}
+
+func (s *leaderboardService) LinkedPlayer(ctx context.Context, telegramID int64) (*models.Player, error) {
+	link, err := s.linkRepo.Get(ctx, telegramID)
+	if err != nil || link == nil {
+		return nil, err
+	}
+	return &link.Player, nil
+}
+
+func (s *leaderboardService) Link(ctx context.Context, telegramID int64, playerID string) error {
+	link, err := s.linkRepo.GetByPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if link != nil && link.TelegramID != telegramID {
+		return ErrPlayerLinked
+	}
+	return s.linkRepo.Save(ctx, telegramID, playerID)
+}
+
+func (s *leaderboardService) Unlink(ctx context.Context, telegramID int64) error {
+	return s.linkRepo.Delete(ctx, telegramID)
+}
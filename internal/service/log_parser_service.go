@@ -2,48 +2,78 @@
 package service
 
 import (
-	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"mine-parser/internal/config"
+	"mine-parser/internal/logging"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // LogParserService описывает сервис парсинга логов
 type LogParserService interface {
-	ProcessLogFile() error
-	ProcessLogLine(line string) error
+	ProcessLogFile(ctx context.Context, path string) error
+	ProcessLogLine(ctx context.Context, line string, origin LineOrigin) error
 }
 
+var (
+	logLineRe  = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^\]]+)\]: (.+)$`)
+	loginIPRe  = regexp.MustCompile(`^([^\[]+)\[/([0-9.:]+)\]`)
+	playerIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// Пример: `Done (12.345s)! For help, type "help"`
+	serverDoneRe = regexp.MustCompile(`^Done \(\d+(?:[.,]\d+)?s\)! For help, type "help"`)
+	// Пример: `<vadkvad> всем привет` или `[Not Secure] <vadkvad> всем привет`
+	chatLineRe = regexp.MustCompile(`^(?:\[Not Secure\] )?<([^>\s]+)> (.*)$`)
+)
+
+// deathPhrases — начала сообщений о смерти после ника игрока (death.attack.* в языковом файле игры).
+// Сообщения проверяются только для известных игроков, иначе фразы вроде «was» давали бы ложные срабатывания.
+var deathPhrases = []string{
+	"was ", "walked into ", "drowned", "died", "experienced kinetic energy", "blew up",
+	"hit the ground too hard", "fell ", "went up in flames", "went off with a bang", "burned to death",
+	"tried to swim in lava", "discovered the floor was lava", "suffocated", "starved to death",
+	"froze to death", "withered away", "didn't want to live", "left the confines of this world",
+}
+
+// notDeathPhrases — сообщения об игроке, которые начинаются как смерть, но ею не являются
+var notDeathPhrases = []string{"was kicked"}
+
 type logParserService struct {
 	cfg              *config.Config
 	playerSvc        PlayerService
-	commandSvc       CommandService
-	advancementSvc   AdvancementService
+	pipeline         WritePipeline
+	diagnostics      ParseDiagnostics
 	usernameToUUID   map[string]string // кэш username → UUID
 	currentSessionIP map[string]string // кэш username → IP (для момента входа)
+	// instance и unanchored — для отпечатков строк без часов или смещения, см. unanchoredFingerprint
+	instance   string
+	unanchored int64
 }
 
 // NewLogParserService создаёт новый парсер
 func NewLogParserService(
+	ctx context.Context,
 	cfg *config.Config,
 	playerSvc PlayerService,
-	commandSvc CommandService,
-	advancementSvc AdvancementService,
+	pipeline WritePipeline,
+	diagnostics ParseDiagnostics,
 ) LogParserService {
 	s := &logParserService{
 		cfg:              cfg,
 		playerSvc:        playerSvc,
-		commandSvc:       commandSvc,
-		advancementSvc:   advancementSvc,
+		pipeline:         pipeline,
+		diagnostics:      diagnostics,
 		usernameToUUID:   make(map[string]string),
 		currentSessionIP: make(map[string]string),
+		instance:         strconv.FormatInt(time.Now().UnixNano(), 36),
 	}
 
-	players, err := s.playerSvc.ListAllPlayers()
+	players, err := s.playerSvc.ListAllPlayers(ctx)
 	if err == nil {
 		for _, p := range players {
 			if p.Username != "" && p.ID != "" {
@@ -52,108 +82,207 @@ func NewLogParserService(
 		}
 		log.Printf("Загружено %d известных username → UUID в кэш", len(players))
 	} else {
-		log.Printf("Не удалось загрузить игроков в кэш: %v", err)
+		logging.Errorf("Не удалось загрузить игроков в кэш: %v", err)
 	}
+
+	// Известные ники заменяются на <player> при нормализации нераспознанных строк.
+	// Кэш наполняется только из горутины парсера, поэтому матчер вызывается оттуда же.
+	s.diagnostics.SetPlayerMatcher(func(name string) bool {
+		_, ok := s.usernameToUUID[name]
+		return ok
+	})
 	return s
 }
 
-// ProcessLogFile читает файл по пути из конфига и парсит его
-func (s *logParserService) ProcessLogFile() error {
-	path := s.cfg.App.ParsePath
-	if path == "" {
-		return fmt.Errorf("parse_path не задан в конфигурации")
-	}
-
+// ProcessLogFile читает файл целиком и парсит его; чтение прерывается при отмене ctx.
+// Архивы .log.gz распаковываются. Дата строк берётся из имени архива (2024-05-01-1.log.gz),
+// а для остальных файлов (latest.log) — из времени изменения файла.
+func (s *logParserService) ProcessLogFile(ctx context.Context, path string) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("не удалось открыть лог-файл %s: %w", path, err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("не удалось получить информацию о файле %s: %w", path, err)
+	}
+	clock, err := s.fileClock(ctx, file, info)
+	if err != nil {
+		return fmt.Errorf("ошибка при чтении файла: %w", err)
+	}
+	reader, err := openLogReader(file)
+	if err != nil {
+		return fmt.Errorf("не удалось распаковать лог-файл %s: %w", path, err)
+	}
+	var sourceID string // вычисляется по первой строке
+	statsBefore := s.pipeline.Stats()
+	started := time.Now()
+
 	lineNum := 0
-	for scanner.Scan() {
+	_, err = ReadLines(contextReader{ctx: ctx, r: reader}, 0, true, func(line string, offset int64) {
 		lineNum++
-		line := scanner.Text()
-		if err := s.ProcessLogLine(line); err != nil {
-			log.Printf("Ошибка на строке %d: %v\n  Строка: %s", lineNum, err, line)
+		if offset == 0 {
+			sourceID = SourceIdentity(line, clock.Start())
 		}
-	}
-
-	if err := scanner.Err(); err != nil {
+		if err := s.ProcessLogLine(ctx, line, LineOrigin{SourceID: sourceID, Offset: offset, Clock: clock}); err != nil {
+			logging.Errorf("Ошибка на строке %d: %v\n  Строка: %s", lineNum, err, line)
+		}
+	})
+	if err != nil {
 		return fmt.Errorf("ошибка при чтении файла: %w", err)
 	}
+	if err := s.pipeline.Flush(); err != nil {
+		return fmt.Errorf("ошибка при записи событий: %w", err)
+	}
 
-	log.Printf("Файл %s успешно обработан (%d строк)", path, lineNum)
+	elapsed := time.Since(started)
+	stats := s.pipeline.Stats()
+	log.Printf("Файл %s успешно обработан (%d строк за %s, %.0f строк/с): записано событий %d, пропущено дубликатов %d, ошибок %d",
+		path, lineNum, elapsed.Round(time.Millisecond), float64(lineNum)/elapsed.Seconds(),
+		stats.Events-statsBefore.Events, stats.Duplicates-statsBefore.Duplicates, stats.Failed-statsBefore.Failed)
 	return nil
 }
 
+// fileClock — часы для чтения файла с начала. Без даты в имени файл прочитывается
+// заранее: день первой строки — дата изменения файла минус переходы через полночь.
+// После вызова file снова стоит в начале.
+func (s *logParserService) fileClock(ctx context.Context, file *os.File, info os.FileInfo) (*LogClock, error) {
+	if date, ok := archiveDate(info.Name(), s.cfg.App.Location); ok {
+		return NewLogClock(date), nil
+	}
+
+	reader, err := openLogReader(file)
+	if err != nil {
+		return nil, err
+	}
+	clock, err := ScanLogClock(contextReader{ctx: ctx, r: reader}, info.ModTime().In(s.cfg.App.Location))
+	if err != nil {
+		return nil, err
+	}
+	clock.Rewind()
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return clock, nil
+}
+
 // ProcessLogLine парсит одну строку лога
-func (s *logParserService) ProcessLogLine(line string) error {
-	// Регулярка для извлечения времени и сообщения
-	re := regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^\]]+)\]: (.+)$`)
-	matches := re.FindStringSubmatch(line)
+func (s *logParserService) ProcessLogLine(ctx context.Context, line string, origin LineOrigin) error {
+	s.diagnostics.RecordLine()
+
+	// Извлекаем время, компонент (logger/thread) и сообщение
+	matches := logLineRe.FindStringSubmatch(line)
 	if len(matches) != 4 {
+		s.diagnostics.RecordUnmatched("", line)
 		return nil // игнорируем нераспознанные строки
 	}
 
-	//timestampStr := matches[1]
+	// Время события — время строки с датой, восстановленной часами файла.
+	// Без часов — момент чтения строки с точностью до секунды, как в логе.
+	// Хранится в UTC (SQLite сравнивает время как текст), часовой пояс применяется только при отображении.
+	timestamp := time.Now().UTC().Truncate(time.Second)
+	if origin.Clock != nil {
+		var err error
+		if timestamp, err = origin.Clock.Resolve(matches[1]); err != nil {
+			s.diagnostics.RecordUnmatched("", line)
+			return err
+		}
+	}
+
+	ev := rawEvent{
+		origin:    origin,
+		line:      line,
+		timestamp: timestamp,
+	}
 	component := matches[2]
 	message := matches[3]
 
-	// Парсим время (предполагаем текущую дату; для продакшена — лучше использовать ротацию логов с датой)
-	location, err := time.LoadLocation("Europe/Moscow")
+	rule, err := s.processMessage(ctx, ev, component, message)
+	if rule == "" {
+		s.diagnostics.RecordUnmatched(component, message)
+		return nil
+	}
+
+	s.diagnostics.RecordMatched(rule)
 	if err != nil {
-		return fmt.Errorf("не удалось загрузить локацию: %w", err)
-	}
-	now := time.Now().In(location)
-
-	// // Парсим только время из лога
-	// timeOnly, err := time.Parse("15:04:05", timestampStr)
-	// if err != nil {
-	// 	return fmt.Errorf("неверный формат времени: %w", err)
-	// }
-
-	// Создаём полное время на основе текущей даты + время из лога
-	timestamp := time.Date(
-		now.Year(),
-		now.Month(),
-		now.Day(),
-		now.Hour()+3,
-		now.Minute(),
-		0,
-		0,
-		now.Location(),
-	)
+		s.diagnostics.RecordParseError(rule, err)
+		return fmt.Errorf("правило %s: %w", rule, err)
+	}
+	return nil
+}
+
+// rawEvent — исходные данные строки, из которых строится отпечаток события
+type rawEvent struct {
+	origin    LineOrigin
+	line      string
+	timestamp time.Time // полное время события в UTC
+}
+
+// submit дополняет событие отпечатком и передаёт его в конвейер записи.
+// Повторная обработка того же участка лога ничего не меняет: конвейер пропускает известные отпечатки.
+// Строки без часов или смещения не дедуплицируются: каждая получает свой отпечаток.
+func (s *logParserService) submit(ev rawEvent, event LogEvent) error {
+	if !isServerRule(event.Rule) && !playerIDRe.MatchString(event.PlayerID) {
+		return fmt.Errorf("неизвестен UUID игрока %s", event.Username)
+	}
+	event.Server = s.cfg.App.ServerName
+	if ev.origin.Clock != nil && ev.origin.Offset >= 0 {
+		event.Fingerprint = eventFingerprint(ev.origin, ev.timestamp)
+	} else {
+		s.unanchored++
+		event.Fingerprint = unanchoredFingerprint(s.instance, s.unanchored, ev.line)
+	}
+	return s.pipeline.Submit(event)
+}
+
+// resolvePlayerID возвращает UUID игрока из кэша, при необходимости восстанавливая его из БД
+func (s *logParserService) resolvePlayerID(ctx context.Context, username string) string {
+	if playerID := s.usernameToUUID[username]; playerID != "" {
+		return playerID
+	}
+
+	player, err := s.playerSvc.GetPlayerByUsername(ctx, username)
+	if err == nil && player != nil && player.ID != "" {
+		s.usernameToUUID[username] = player.ID // кэшируем на будущее
+		log.Printf("Восстановили UUID из БД для %s → %s", username, player.ID)
+		return player.ID
+	}
+	return ""
+}
+
+// processMessage применяет правила к сообщению и возвращает имя сработавшего правила.
+// Пустое имя означает, что сообщение не распознано.
+func (s *logParserService) processMessage(ctx context.Context, ev rawEvent, component, message string) (string, error) {
+	timestamp := ev.timestamp
 
 	// 1. Обработка UUID
 	if strings.Contains(component, "User Authenticator") && strings.Contains(message, "UUID of player") {
-		return s.handleUUIDLine(message, timestamp)
+		return RuleUUID, s.handleUUIDLine(message, timestamp)
 	}
 
 	// 2. Обработка входа
 	if strings.HasSuffix(message, " joined the game") {
 		username := strings.TrimSuffix(message, " joined the game")
-		return s.playerSvc.RegisterLogin(s.usernameToUUID[username], username, s.currentSessionIP[username], 0, timestamp)
+		return RuleJoin, s.submit(ev, LogEvent{
+			Rule:      RuleJoin,
+			PlayerID:  s.usernameToUUID[username],
+			Username:  username,
+			IP:        s.currentSessionIP[username],
+			Timestamp: timestamp,
+		})
 	}
 
 	// 3. Обработка выхода
 	if strings.HasSuffix(message, " left the game") {
 		username := strings.TrimSuffix(message, " left the game")
-		playerID := s.usernameToUUID[username]
-		if playerID == "" {
-			// Пытаемся восстановить из БД
-			player, err := s.playerSvc.GetPlayerByUsername(username) // новый метод
-			if err == nil && player != nil && player.ID != "" {
-				playerID = player.ID
-				s.usernameToUUID[username] = playerID // кэшируем на будущее
-				log.Printf("Восстановили UUID из БД для %s → %s", username, playerID)
-			} else {
-				log.Printf("WARN: Нет UUID для %s даже в БД → используем username временно", username)
-				playerID = username
-			}
-		}
-		return s.playerSvc.RegisterLogout(playerID, timestamp)
+		return RuleLeave, s.submit(ev, LogEvent{
+			Rule:      RuleLeave,
+			PlayerID:  s.resolvePlayerID(ctx, username),
+			Username:  username,
+			Timestamp: timestamp,
+		})
 	}
 
 	// 4. Обработка команды
@@ -161,12 +290,13 @@ func (s *logParserService) ProcessLogLine(line string) error {
 		parts := strings.SplitN(message, " issued server command: ", 2)
 		if len(parts) == 2 {
 			username := parts[0]
-			fullCmd := parts[1]
-			playerID := s.usernameToUUID[username]
-			if playerID == "" {
-				playerID = username
-			}
-			return s.commandSvc.LogCommand(playerID, fullCmd, timestamp)
+			return RuleCommand, s.submit(ev, LogEvent{
+				Rule:      RuleCommand,
+				PlayerID:  s.resolvePlayerID(ctx, username),
+				Username:  username,
+				Command:   parts[1],
+				Timestamp: timestamp,
+			})
 		}
 	}
 
@@ -175,30 +305,84 @@ func (s *logParserService) ProcessLogLine(line string) error {
 		parts := strings.SplitN(message, " has made the advancement [", 2)
 		if len(parts) == 2 {
 			username := parts[0]
-			advName := strings.TrimSuffix(parts[1], "]")
-			playerID := s.usernameToUUID[username]
-			if playerID == "" {
-				playerID = username
-			}
-			return s.advancementSvc.GrantAdvancement(playerID, advName, timestamp)
+			return RuleAdvancement, s.submit(ev, LogEvent{
+				Rule:        RuleAdvancement,
+				PlayerID:    s.resolvePlayerID(ctx, username),
+				Username:    username,
+				Advancement: strings.TrimSuffix(parts[1], "]"),
+				Timestamp:   timestamp,
+			})
 		}
 	}
 
-	// 6. Извлечение IP при входе (из строки вида "vadkvad[/109.173.122.70:34284] logged in...")
+	// 6. Сообщение в чате
+	if strings.HasPrefix(component, "Server thread") || strings.HasPrefix(component, "Async Chat Thread") {
+		if chat := chatLineRe.FindStringSubmatch(message); chat != nil {
+			username := chat[1]
+			return RuleChat, s.submit(ev, LogEvent{
+				Rule:      RuleChat,
+				PlayerID:  s.resolvePlayerID(ctx, username),
+				Username:  username,
+				Message:   chat[2],
+				Timestamp: timestamp,
+			})
+		}
+	}
+
+	// 7. Смерть игрока
+	if username, ok := s.deathOf(message); ok {
+		return RuleDeath, s.submit(ev, LogEvent{
+			Rule:      RuleDeath,
+			PlayerID:  s.usernameToUUID[username],
+			Username:  username,
+			Message:   message,
+			Timestamp: timestamp,
+		})
+	}
+
+	// 8. Запуск и остановка сервера
+	if serverDoneRe.MatchString(message) {
+		return RuleServerStart, s.submit(ev, LogEvent{Rule: RuleServerStart, Timestamp: timestamp})
+	}
+	if message == "Stopping server" || message == "Stopping the server" {
+		return RuleServerStop, s.submit(ev, LogEvent{Rule: RuleServerStop, Timestamp: timestamp})
+	}
+
+	// 9. Извлечение IP при входе (из строки вида "vadkvad[/109.173.122.70:34284] logged in...")
 	if strings.Contains(message, " logged in with entity id ") {
 		// Пример: "vadkvad[/109.173.122.70:34284] logged in with entity id 46 at ..."
-		ipRe := regexp.MustCompile(`^([^\[]+)\[/([0-9.:]+)\]`)
-		ipMatch := ipRe.FindStringSubmatch(message)
-		if len(ipMatch) == 3 {
-			username := ipMatch[1]
-			ipPort := ipMatch[2]
-			// Обрезаем порт
-			ip := strings.Split(ipPort, ":")[0]
-			s.currentSessionIP[username] = ip
+		ipMatch := loginIPRe.FindStringSubmatch(message)
+		if len(ipMatch) != 3 {
+			return RuleLoginIP, fmt.Errorf("не удалось извлечь IP из строки входа")
 		}
+		username := ipMatch[1]
+		ipPort := ipMatch[2]
+		// Обрезаем порт
+		ip := strings.Split(ipPort, ":")[0]
+		s.currentSessionIP[username] = ip
+		return RuleLoginIP, nil
 	}
 
-	return nil
+	return "", nil
+}
+
+// deathOf распознаёт сообщение о смерти известного игрока и возвращает его ник
+func (s *logParserService) deathOf(message string) (string, bool) {
+	username, rest, ok := strings.Cut(message, " ")
+	if !ok || s.usernameToUUID[username] == "" {
+		return "", false
+	}
+	for _, phrase := range notDeathPhrases {
+		if strings.HasPrefix(rest, phrase) {
+			return "", false
+		}
+	}
+	for _, phrase := range deathPhrases {
+		if strings.HasPrefix(rest, phrase) {
+			return username, true
+		}
+	}
+	return "", false
 }
 
 // handleUUIDLine обрабатывает строку с UUID
@@ -212,5 +396,5 @@ func (s *logParserService) handleUUIDLine(message string, timestamp time.Time) e
 		// Просто сохраняем UUID в кэш, игрок создастся при входе через RegisterLogin
 		return nil
 	}
-	return nil
+	return fmt.Errorf("неожиданный формат строки UUID")
 }
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineOrigin описывает, откуда прочитана строка лога
+type LineOrigin struct {
+	SourceID string // идентичность лога по содержимому, см. SourceIdentity
+	Offset   int64  // смещение начала строки в байтах; отрицательное, если неизвестно
+	// Clock восстанавливает дату строк файла; nil — время события берётся по моменту чтения
+	Clock *LogClock
+}
+
+// UnknownOrigin используется для строк, источник которых неизвестен.
+// Повторы таких строк не распознаются: каждая записывается как новое событие.
+var UnknownOrigin = LineOrigin{Offset: -1}
+
+// SourceIdentity возвращает идентичность лога по содержимому: хэш первой строки и день,
+// в который она записана. Копия лога или распакованный архив получают ту же идентичность,
+// что и отслеживавшийся файл, а переиспользование inode после ротации на неё не влияет.
+func SourceIdentity(firstLine string, start time.Time) string {
+	sum := sha256.Sum256([]byte(firstLine))
+	return hex.EncodeToString(sum[:8]) + ":" + start.Format(time.DateOnly)
+}
+
+// ReadFirstLine возвращает первую завершённую строку r; пустую строку, если её ещё нет
+func ReadFirstLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err == io.EOF {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// archiveNameRe — имя архива лога Minecraft: 2024-05-01-1.log.gz
+var archiveNameRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-\d+\.log(?:\.gz)?$`)
+
+// dayWrapTolerance — насколько время строки может отступить назад без перехода через полночь
+// (например, при подводке системных часов)
+const dayWrapTolerance = time.Hour
+
+// LogClock переводит время строки лога (ЧЧ:ММ:СС, без даты) в полное время.
+// Строки читаются по порядку: если время суток уменьшилось, значит, наступил следующий день.
+type LogClock struct {
+	start time.Time     // полночь первого дня лога
+	day   time.Time     // полночь дня последней строки
+	last  time.Duration // время суток последней строки; отрицательное до первой строки
+}
+
+// NewLogClock создаёт часы для лога, первая строка которого записана в день start.
+// Время строк считается в часовом поясе start.
+func NewLogClock(start time.Time) *LogClock {
+	day := midnight(start)
+	return &LogClock{start: day, day: day, last: -1}
+}
+
+// ScanLogClock читает строки r и возвращает часы, стоящие на последней строке:
+// её дата — дата modTime, последней записи в файл. Start() таких часов — день первой строки.
+func ScanLogClock(r io.Reader, modTime time.Time) (*LogClock, error) {
+	wraps, last := 0, time.Duration(-1)
+	_, err := ReadLines(r, 0, true, func(line string, offset int64) {
+		matches := logLineRe.FindStringSubmatch(line)
+		if matches == nil {
+			return
+		}
+		clock, err := parseClock(matches[1])
+		if err != nil {
+			return
+		}
+		if last >= 0 && clock < last-dayWrapTolerance {
+			wraps++
+		}
+		last = clock
+	})
+	if err != nil {
+		return nil, err
+	}
+	day := midnight(modTime)
+	return &LogClock{start: day.AddDate(0, 0, -wraps), day: day, last: last}, nil
+}
+
+// Start — полночь дня первой строки лога
+func (c *LogClock) Start() time.Time {
+	return c.start
+}
+
+// Rewind возвращает часы к началу лога, чтобы прочитать его заново
+func (c *LogClock) Rewind() {
+	c.day, c.last = c.start, -1
+}
+
+// Resolve возвращает полное время строки со временем logTime (в UTC) и сдвигает часы на неё
+func (c *LogClock) Resolve(logTime string) (time.Time, error) {
+	clock, err := parseClock(logTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if c.last >= 0 && clock < c.last-dayWrapTolerance {
+		c.day = c.day.AddDate(0, 0, 1)
+	}
+	c.last = clock
+	// time.Date, а не сложение длительностей: в дни перевода часов сутки короче или длиннее 24 часов
+	hours, minutes, seconds := int(clock/time.Hour), int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second)
+	return time.Date(c.day.Year(), c.day.Month(), c.day.Day(), hours, minutes, seconds, 0, c.day.Location()).UTC(), nil
+}
+
+// parseClock разбирает время суток ЧЧ:ММ:СС
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse(time.TimeOnly, value)
+	if err != nil {
+		return 0, fmt.Errorf("неверное время строки %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// archiveDate — дата из имени архива лога; ok = false, если имя не в формате архива
+func archiveDate(name string, loc *time.Location) (time.Time, bool) {
+	matches := archiveNameRe.FindStringSubmatch(name)
+	if matches == nil {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(time.DateOnly, matches[1], loc)
+	return date, err == nil
+}
+
+// openLogReader открывает лог для чтения; архивы .gz распаковываются на лету
+func openLogReader(file *os.File) (io.Reader, error) {
+	if !strings.HasSuffix(file.Name(), ".gz") {
+		return file, nil
+	}
+	return gzip.NewReader(file)
+}
+
+// contextReader прекращает чтение после отмены ctx
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// ReadLines читает строки начиная с offset и передаёт их в fn вместе со смещением.
+// Незавершённая последняя строка (без перевода строки) читается, только если includePartial.
+// Возвращает смещение, с которого нужно продолжить чтение.
+func ReadLines(r io.Reader, offset int64, includePartial bool, fn func(line string, offset int64)) (int64, error) {
+	reader := bufio.NewReader(r)
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if includePartial && len(raw) > 0 {
+				fn(string(bytes.TrimRight(raw, "\r")), offset)
+				offset += int64(len(raw))
+			}
+			// Иначе строка ещё дописывается — вернёмся к ней на следующем проходе
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		line := bytes.TrimRight(raw, "\r\n")
+		fn(string(line), offset)
+		offset += int64(len(raw))
+	}
+}
+
+// eventFingerprint строит стабильный отпечаток события: источник + смещение
+// + полное время события с датой
+func eventFingerprint(origin LineOrigin, at time.Time) string {
+	sum := sha256.Sum256([]byte(origin.SourceID + "|" + strconv.FormatInt(origin.Offset, 10) + "|" + at.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:])
+}
+
+// unanchoredFingerprint — отпечаток строки, место которой в логе неизвестно (нет часов или смещения).
+// Одинаковые строки в такой ситуации не отличить от повторной загрузки, поэтому отпечаток
+// уникален для каждого вызова: instance различает запуски, sequence — строки одного запуска.
+func unanchoredFingerprint(instance string, sequence int64, line string) string {
+	sum := sha256.Sum256([]byte(instance + "|" + strconv.FormatInt(sequence, 10) + "|" + line))
+	return hex.EncodeToString(sum[:])
+}
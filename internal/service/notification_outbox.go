@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/events"
+	"mine-parser/internal/models"
+	"mine-parser/internal/notify"
+	"mine-parser/internal/repo"
+	"slices"
+	"time"
+)
+
+// NotificationOutbox превращает записанные события в уведомления для очереди исходящих сообщений:
+// о входе игроков — для чатов Telegram, о выбранных событиях — для каналов Discord.
+// Enqueue вызывается конвейером внутри транзакции, в которой сохраняются сами события:
+// уведомление появляется в очереди тогда и только тогда, когда событие записано.
+type NotificationOutbox interface {
+	Enqueue(ctx context.Context, r repo.TxRepositories, events []LogEvent) error
+}
+
+type notificationOutbox struct {
+	presence PresenceTracker
+	cfg      *config.Store // тексты уведомлений, каналы Discord и срок актуальности уведомления
+}
+
+func NewNotificationOutbox(presence PresenceTracker, cfg *config.Store) NotificationOutbox {
+	return &notificationOutbox{
+		presence: presence,
+		cfg:      cfg,
+	}
+}
+
+// notificationEventTypes — события лога, о которых могут уведомлять каналы Discord
+var notificationEventTypes = map[string]events.Type{
+	RuleJoin:        events.TypePlayerLogin,
+	RuleLeave:       events.TypePlayerLogout,
+	RuleDeath:       events.TypePlayerDied,
+	RuleAdvancement: events.TypeAdvancementEarned,
+	RuleChat:        events.TypeChatMessage,
+	RuleServerStart: events.TypeServerStarted,
+	RuleServerStop:  events.TypeServerStopping,
+}
+
+func (o *notificationOutbox) Enqueue(ctx context.Context, r repo.TxRepositories, logEvents []LogEvent) error {
+	cfg := o.cfg.Get()
+	online := o.onlineCounts(logEvents)
+
+	var messages []models.OutboxMessage
+	if cfg.Tg.Token != "" {
+		telegram, err := o.telegramMessages(ctx, r, cfg, logEvents, online)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, telegram...)
+	}
+	if len(cfg.Discord.Channels) > 0 {
+		discord, err := o.discordMessages(ctx, r, cfg, logEvents, online)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, discord...)
+	}
+	return r.Outbox.CreateMany(ctx, messages)
+}
+
+// onlineCounts считает число игроков онлайн после каждого события входа и выхода: трекер
+// присутствия обновится только после коммита, поэтому события батча применяем к копии
+func (o *notificationOutbox) onlineCounts(logEvents []LogEvent) []int {
+	if !slices.ContainsFunc(logEvents, func(e LogEvent) bool { return e.Rule == RuleJoin || e.Rule == RuleLeave }) {
+		return nil
+	}
+	online := make(map[string]bool)
+	for _, presence := range o.presence.Online() {
+		online[presence.PlayerID] = true
+	}
+	counts := make([]int, len(logEvents))
+	for i, event := range logEvents {
+		switch event.Rule {
+		case RuleJoin:
+			online[event.PlayerID] = true
+		case RuleLeave:
+			delete(online, event.PlayerID)
+		}
+		counts[i] = len(online)
+	}
+	return counts
+}
+
+// telegramMessages готовит уведомления о входе игроков для подписанных чатов Telegram
+func (o *notificationOutbox) telegramMessages(ctx context.Context, r repo.TxRepositories, cfg *config.Config, logEvents []LogEvent, online []int) ([]models.OutboxMessage, error) {
+	var joinPlayerIDs []string
+	for _, event := range logEvents {
+		if event.Rule == RuleJoin {
+			joinPlayerIDs = append(joinPlayerIDs, event.PlayerID)
+		}
+	}
+	if len(joinPlayerIDs) == 0 {
+		return nil, nil
+	}
+
+	subscribers, err := r.Notifications.GetAllEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscribers) == 0 {
+		return nil, nil
+	}
+	blacklist, err := r.Notifications.ListBlacklistByPlayers(ctx, joinPlayerIDs)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]bool, len(blacklist))
+	for _, entry := range blacklist {
+		blocked[blacklistKey(entry.ChatID, entry.PlayerID)] = true
+	}
+
+	now := time.Now().UTC()
+	var messages []models.OutboxMessage
+	for i, event := range logEvents {
+		if event.Rule != RuleJoin {
+			continue
+		}
+
+		payload, err := config.Render(cfg.Texts.JoinNotification, config.JoinNotificationView{
+			Username: event.Username,
+			Server:   event.Server,
+			Online:   online[i],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("не удалось подготовить текст уведомления: %w", err)
+		}
+		for _, subscriber := range subscribers {
+			if blocked[blacklistKey(subscriber.ChatID, event.PlayerID)] {
+				continue
+			}
+			messages = append(messages, models.OutboxMessage{
+				EventRef:      event.Fingerprint,
+				Channel:       models.ChannelTelegram,
+				ChatID:        subscriber.ChatID,
+				Payload:       payload,
+				Status:        models.OutboxPending,
+				NextAttemptAt: now,
+				ExpiresAt:     now.Add(cfg.Tg.NotifyTTL),
+			})
+		}
+	}
+	return messages, nil
+}
+
+// discordMessages готовит уведомления для каналов Discord, включивших тип события
+func (o *notificationOutbox) discordMessages(ctx context.Context, r repo.TxRepositories, cfg *config.Config, logEvents []LogEvent, online []int) ([]models.OutboxMessage, error) {
+	var playerIDs []string
+	for _, event := range logEvents {
+		if _, ok := notificationEventTypes[event.Rule]; ok && event.PlayerID != "" {
+			playerIDs = append(playerIDs, event.PlayerID)
+		}
+	}
+
+	subscriptions, err := r.Discord.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channels := discordChannels(cfg.Discord, subscriptions)
+	blacklist, err := r.Discord.ListBlacklistByPlayers(ctx, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]bool, len(blacklist))
+	for _, entry := range blacklist {
+		blocked[entry.Channel+"|"+entry.PlayerID] = true
+	}
+
+	now := time.Now().UTC()
+	var messages []models.OutboxMessage
+	for i, event := range logEvents {
+		eventType, ok := notificationEventTypes[event.Rule]
+		if !ok {
+			continue
+		}
+		var payload string
+		for _, channel := range channels {
+			if !channel.Notifies(string(eventType)) || blocked[channel.Name+"|"+event.PlayerID] {
+				continue
+			}
+			if payload == "" {
+				notification := notify.Notification{
+					Type:      eventType,
+					Server:    event.Server,
+					PlayerID:  event.PlayerID,
+					Username:  event.Username,
+					Timestamp: event.Timestamp,
+				}
+				switch event.Rule {
+				case RuleAdvancement:
+					notification.Text = event.Advancement
+				case RuleDeath, RuleChat:
+					notification.Text = event.Message
+				}
+				if online != nil {
+					notification.Online = online[i]
+				}
+				if payload, err = notify.DiscordPayload(notification, cfg.Discord); err != nil {
+					return nil, fmt.Errorf("не удалось подготовить уведомление для Discord: %w", err)
+				}
+			}
+			messages = append(messages, models.OutboxMessage{
+				EventRef:      event.Fingerprint,
+				Channel:       models.ChannelDiscord,
+				Target:        channel.Name,
+				Payload:       payload,
+				Status:        models.OutboxPending,
+				NextAttemptAt: now,
+				ExpiresAt:     now.Add(cfg.Tg.NotifyTTL),
+			})
+		}
+	}
+	return messages, nil
+}
+
+func blacklistKey(chatID int64, playerID string) string {
+	return fmt.Sprintf("%d|%s", chatID, playerID)
+}
@@ -1,33 +1,37 @@
 package service
 
 import (
+	"context"
 	"mine-parser/internal/models"
 	"mine-parser/internal/repo"
 )
 
 type NotificationService interface {
-	ToggleSubscription(chatID int64) (bool, error)
-	GetSubscriptionStatus(chatID int64) (bool, error)
-	GetAllSubscribers() ([]models.NotificationSubscription, error)
+	ToggleSubscription(ctx context.Context, chatID int64) (bool, error)
+	GetSubscriptionStatus(ctx context.Context, chatID int64) (bool, error)
+	GetAllSubscribers(ctx context.Context) ([]models.NotificationSubscription, error)
 	// Методы для черного списка
-	ToggleBlacklist(chatID int64, playerID string) (bool, error) // возвращает true если добавлен в черный список
-	IsInBlacklist(chatID int64, playerID string) (bool, error)
-	GetBlacklist(chatID int64) ([]models.NotificationBlacklist, error)
-	ShouldNotify(chatID int64, playerID string) (bool, error) // проверяет, нужно ли отправлять уведомление
+	ToggleBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error) // возвращает true если добавлен в черный список
+	IsInBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error)
+	GetBlacklist(ctx context.Context, chatID int64) ([]models.NotificationBlacklist, error)
+	ShouldNotify(ctx context.Context, chatID int64, playerID string) (bool, error) // проверяет, нужно ли отправлять уведомление
+	OutboxStats(ctx context.Context) (map[string]int64, error)                     // число уведомлений в очереди по статусам
 }
 
 type notificationService struct {
 	notificationRepo repo.NotificationRepository
+	outboxRepo       repo.OutboxRepository
 }
 
-func NewNotificationService(notificationRepo repo.NotificationRepository) NotificationService {
+func NewNotificationService(notificationRepo repo.NotificationRepository, outboxRepo repo.OutboxRepository) NotificationService {
 	return &notificationService{
 		notificationRepo: notificationRepo,
+		outboxRepo:       outboxRepo,
 	}
 }
 
-func (s *notificationService) ToggleSubscription(chatID int64) (bool, error) {
-	subscription, err := s.notificationRepo.GetByChatID(chatID)
+func (s *notificationService) ToggleSubscription(ctx context.Context, chatID int64) (bool, error) {
+	subscription, err := s.notificationRepo.GetByChatID(ctx, chatID)
 	if err != nil {
 		return false, err
 	}
@@ -35,7 +39,7 @@ func (s *notificationService) ToggleSubscription(chatID int64) (bool, error) {
 	// Если подписки нет, создаем с enabled=true
 	if subscription == nil {
 		newEnabled := true
-		err = s.notificationRepo.CreateOrUpdate(chatID, newEnabled)
+		err = s.notificationRepo.CreateOrUpdate(ctx, chatID, newEnabled)
 		if err != nil {
 			return false, err
 		}
@@ -44,15 +48,15 @@ func (s *notificationService) ToggleSubscription(chatID int64) (bool, error) {
 
 	// Переключаем статус
 	newEnabled := !subscription.Enabled
-	err = s.notificationRepo.CreateOrUpdate(chatID, newEnabled)
+	err = s.notificationRepo.CreateOrUpdate(ctx, chatID, newEnabled)
 	if err != nil {
 		return false, err
 	}
 	return newEnabled, nil
 }
 
-func (s *notificationService) GetSubscriptionStatus(chatID int64) (bool, error) {
-	subscription, err := s.notificationRepo.GetByChatID(chatID)
+func (s *notificationService) GetSubscriptionStatus(ctx context.Context, chatID int64) (bool, error) {
+	subscription, err := s.notificationRepo.GetByChatID(ctx, chatID)
 	if err != nil {
 		return false, err
 	}
@@ -63,47 +67,51 @@ func (s *notificationService) GetSubscriptionStatus(chatID int64) (bool, error)
 	return subscription.Enabled, nil
 }
 
-func (s *notificationService) GetAllSubscribers() ([]models.NotificationSubscription, error) {
-	return s.notificationRepo.GetAllEnabled()
+func (s *notificationService) GetAllSubscribers(ctx context.Context) ([]models.NotificationSubscription, error) {
+	return s.notificationRepo.GetAllEnabled(ctx)
 }
 
-func (s *notificationService) ToggleBlacklist(chatID int64, playerID string) (bool, error) {
-	isInBlacklist, err := s.notificationRepo.IsInBlacklist(chatID, playerID)
+func (s *notificationService) ToggleBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error) {
+	isInBlacklist, err := s.notificationRepo.IsInBlacklist(ctx, chatID, playerID)
 	if err != nil {
 		return false, err
 	}
 
 	if isInBlacklist {
 		// Удаляем из черного списка
-		err = s.notificationRepo.RemoveFromBlacklist(chatID, playerID)
+		err = s.notificationRepo.RemoveFromBlacklist(ctx, chatID, playerID)
 		return false, err
 	} else {
 		// Добавляем в черный список
-		err = s.notificationRepo.AddToBlacklist(chatID, playerID)
+		err = s.notificationRepo.AddToBlacklist(ctx, chatID, playerID)
 		return true, err
 	}
 }
 
-func (s *notificationService) IsInBlacklist(chatID int64, playerID string) (bool, error) {
-	return s.notificationRepo.IsInBlacklist(chatID, playerID)
+func (s *notificationService) IsInBlacklist(ctx context.Context, chatID int64, playerID string) (bool, error) {
+	return s.notificationRepo.IsInBlacklist(ctx, chatID, playerID)
 }
 
-func (s *notificationService) GetBlacklist(chatID int64) ([]models.NotificationBlacklist, error) {
-	return s.notificationRepo.GetBlacklist(chatID)
+func (s *notificationService) GetBlacklist(ctx context.Context, chatID int64) ([]models.NotificationBlacklist, error) {
+	return s.notificationRepo.GetBlacklist(ctx, chatID)
 }
 
-func (s *notificationService) ShouldNotify(chatID int64, playerID string) (bool, error) {
+func (s *notificationService) ShouldNotify(ctx context.Context, chatID int64, playerID string) (bool, error) {
 	// Проверяем, включены ли уведомления
-	enabled, err := s.GetSubscriptionStatus(chatID)
+	enabled, err := s.GetSubscriptionStatus(ctx, chatID)
 	if err != nil || !enabled {
 		return false, err
 	}
 
 	// Проверяем, не в черном списке ли игрок
-	isInBlacklist, err := s.IsInBlacklist(chatID, playerID)
+	isInBlacklist, err := s.IsInBlacklist(ctx, chatID, playerID)
 	if err != nil {
 		return false, err
 	}
 
 	return !isInBlacklist, nil
 }
+
+func (s *notificationService) OutboxStats(ctx context.Context) (map[string]int64, error) {
+	return s.outboxRepo.CountByStatus(ctx)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Имена правил парсера (используются в счётчиках ошибок)
+const (
+	RuleUUID        = "uuid"
+	RuleJoin        = "join"
+	RuleLeave       = "leave"
+	RuleCommand     = "command"
+	RuleAdvancement = "advancement"
+	RuleLoginIP     = "login_ip"
+	RuleServerStart = "server_start"
+	RuleServerStop  = "server_stop"
+	RuleChat        = "chat"
+	RuleDeath       = "death"
+)
+
+const (
+	defaultSamplesPerShape = 5
+	defaultMaxShapes       = 1000
+	maxShapeWords          = 8
+	noLoggerComponent      = "-"
+)
+
+var (
+	uuidRe   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	ipRe     = regexp.MustCompile(`/?\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?`)
+	numberRe = regexp.MustCompile(`-?\d+(?:[.,]\d+)*`)
+	digitsRe = regexp.MustCompile(`\d+`)
+	quotedRe = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+	chatRe   = regexp.MustCompile(`^<[^>]+> .*$`)
+)
+
+// ShapeStats — статистика по одной нормализованной форме нераспознанных строк
+type ShapeStats struct {
+	ID       string
+	Logger   string
+	Shape    string
+	Count    int64
+	LastSeen time.Time
+	Samples  []string // последние примеры, от старых к новым
+}
+
+// RuleStats — статистика срабатываний и ошибок одного правила
+type RuleStats struct {
+	Rule       string
+	Matched    int64
+	Errors     int64
+	Duplicates int64 // события, пропущенные как уже обработанные
+	LastError  string
+}
+
+// DiagnosticsSummary — общие счётчики диагностики
+type DiagnosticsSummary struct {
+	TotalLines      int64
+	UnmatchedLines  int64
+	ShapeCount      int
+	DroppedShapes   int64 // строки, не попавшие в статистику из-за лимита форм
+	DuplicateEvents int64
+	LastMatchedAt   time.Time // когда последний раз сработало правило; нулевое, если ещё не было
+}
+
+// ParseDiagnostics собирает статистику нераспознанных строк и ошибок правил
+type ParseDiagnostics interface {
+	RecordLine()
+	RecordUnmatched(logger, message string)
+	RecordMatched(rule string)
+	RecordParseError(rule string, err error)
+	RecordDuplicate(rule string)
+	SetPlayerMatcher(isPlayer func(name string) bool)
+	TopUnknownShapes(limit int) []ShapeStats
+	GetShape(id string) (*ShapeStats, bool)
+	RuleStats() []RuleStats
+	Summary() DiagnosticsSummary
+}
+
+type shapeEntry struct {
+	stats ShapeStats
+	next  int // позиция для следующего примера в кольцевом буфере
+}
+
+type parseDiagnostics struct {
+	mu              sync.RWMutex
+	samplesPerShape int
+	maxShapes       int
+	shapes          map[string]*shapeEntry
+	rules           map[string]*RuleStats
+	summary         DiagnosticsSummary
+	isPlayer        func(name string) bool
+}
+
+// NewParseDiagnostics создаёт сборщик диагностики.
+// samplesPerShape — сколько примеров хранить на форму, maxShapes — максимум отслеживаемых форм.
+func NewParseDiagnostics(samplesPerShape, maxShapes int) ParseDiagnostics {
+	if samplesPerShape <= 0 {
+		samplesPerShape = defaultSamplesPerShape
+	}
+	if maxShapes <= 0 {
+		maxShapes = defaultMaxShapes
+	}
+	return &parseDiagnostics{
+		samplesPerShape: samplesPerShape,
+		maxShapes:       maxShapes,
+		shapes:          make(map[string]*shapeEntry),
+		rules:           make(map[string]*RuleStats),
+	}
+}
+
+func (d *parseDiagnostics) SetPlayerMatcher(isPlayer func(name string) bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.isPlayer = isPlayer
+}
+
+func (d *parseDiagnostics) RecordLine() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.summary.TotalLines++
+}
+
+func (d *parseDiagnostics) RecordUnmatched(logger, message string) {
+	d.mu.RLock()
+	isPlayer := d.isPlayer
+	d.mu.RUnlock()
+
+	if logger == "" {
+		logger = noLoggerComponent
+	}
+	logger = digitsRe.ReplaceAllString(logger, "<n>")
+	shape := normalizeShape(message, isPlayer)
+	id := shapeID(logger, shape)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.summary.UnmatchedLines++
+
+	entry, ok := d.shapes[id]
+	if !ok {
+		if len(d.shapes) >= d.maxShapes {
+			d.summary.DroppedShapes++
+			return
+		}
+		entry = &shapeEntry{stats: ShapeStats{ID: id, Logger: logger, Shape: shape}}
+		d.shapes[id] = entry
+	}
+
+	entry.stats.Count++
+	entry.stats.LastSeen = time.Now()
+
+	// Кольцевой буфер примеров: перезаписываем самый старый
+	if len(entry.stats.Samples) < d.samplesPerShape {
+		entry.stats.Samples = append(entry.stats.Samples, message)
+	} else {
+		entry.stats.Samples[entry.next] = message
+	}
+	entry.next = (entry.next + 1) % d.samplesPerShape
+}
+
+func (d *parseDiagnostics) RecordMatched(rule string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rule(rule).Matched++
+	d.summary.LastMatchedAt = time.Now()
+}
+
+func (d *parseDiagnostics) RecordParseError(rule string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := d.rule(rule)
+	stats.Errors++
+	if err != nil {
+		stats.LastError = err.Error()
+	}
+}
+
+func (d *parseDiagnostics) RecordDuplicate(rule string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rule(rule).Duplicates++
+	d.summary.DuplicateEvents++
+}
+
+// rule возвращает статистику правила, создавая её при необходимости (вызывать под блокировкой)
+func (d *parseDiagnostics) rule(name string) *RuleStats {
+	stats, ok := d.rules[name]
+	if !ok {
+		stats = &RuleStats{Rule: name}
+		d.rules[name] = stats
+	}
+	return stats
+}
+
+func (d *parseDiagnostics) TopUnknownShapes(limit int) []ShapeStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]ShapeStats, 0, len(d.shapes))
+	for _, entry := range d.shapes {
+		result = append(result, entry.copyStats())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Shape < result[j].Shape
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (d *parseDiagnostics) GetShape(id string) (*ShapeStats, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.shapes[id]
+	if !ok {
+		return nil, false
+	}
+	stats := entry.copyStats()
+	return &stats, true
+}
+
+func (d *parseDiagnostics) RuleStats() []RuleStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]RuleStats, 0, len(d.rules))
+	for _, stats := range d.rules {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Rule < result[j].Rule })
+	return result
+}
+
+func (d *parseDiagnostics) Summary() DiagnosticsSummary {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	summary := d.summary
+	summary.ShapeCount = len(d.shapes)
+	return summary
+}
+
+// copyStats возвращает копию статистики с примерами в хронологическом порядке
+func (e *shapeEntry) copyStats() ShapeStats {
+	stats := e.stats
+	// Пока буфер не заполнен, next совпадает с его длиной и сдвиг ничего не меняет
+	samples := make([]string, 0, len(e.stats.Samples))
+	samples = append(samples, e.stats.Samples[e.next:]...)
+	samples = append(samples, e.stats.Samples[:e.next]...)
+	stats.Samples = samples
+	return stats
+}
+
+// normalizeShape приводит сообщение к обобщённой форме:
+// UUID, IP-адреса, числа, строки в кавычках и известные ники заменяются плейсхолдерами.
+func normalizeShape(message string, isPlayer func(name string) bool) string {
+	if chatRe.MatchString(message) {
+		return "<<player>> <text>"
+	}
+
+	shape := uuidRe.ReplaceAllString(message, "<uuid>")
+	shape = ipRe.ReplaceAllString(shape, "<ip>")
+	shape = quotedRe.ReplaceAllString(shape, "<str>")
+	shape = numberRe.ReplaceAllString(shape, "<n>")
+
+	words := strings.Fields(shape)
+	for i, word := range words {
+		if isPlayer != nil && isPlayer(strings.Trim(word, "[]():,")) {
+			words[i] = "<player>"
+		}
+	}
+	if len(words) > maxShapeWords {
+		words = append(words[:maxShapeWords], "…")
+	}
+	return strings.Join(words, " ")
+}
+
+func shapeID(logger, shape string) string {
+	sum := sha1.Sum([]byte(logger + "\x00" + shape))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"mine-parser/internal/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// PlayerMatch — результат поиска игрока по нику, который ввёл пользователь
+type PlayerMatch struct {
+	Player      *models.Player  // найденный игрок; nil, если совпадение не найдено или неоднозначно
+	Suggestions []models.Player // похожие ники, из которых нужно выбрать, от ближайших к дальним
+}
+
+// Степень сходства ника с запросом: чем меньше, тем ближе
+const (
+	matchPrefix    = iota // ник начинается с запроса
+	matchSubstring        // ник содержит запрос
+	matchTypo             // ник отличается опечаткой; к степени прибавляется число правок
+)
+
+// MatchPlayer ищет игрока по UUID или нику без учёта регистра. Если точного совпадения нет,
+// подходят ники, которые начинаются с запроса, содержат его или отличаются опечаткой.
+// Единственный подходящий игрок возвращается как найденный, несколько — как подсказки.
+func (s *playerService) MatchPlayer(ctx context.Context, name string, limit int) (PlayerMatch, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return PlayerMatch{}, nil
+	}
+	if playerIDRe.MatchString(name) {
+		player, err := s.FindPlayer(ctx, name)
+		return PlayerMatch{Player: player}, err
+	}
+
+	players, err := s.playerRepo.ListAll(ctx)
+	if err != nil {
+		return PlayerMatch{}, err
+	}
+	query := strings.ToLower(name)
+
+	type candidate struct {
+		player models.Player
+		score  int
+	}
+	var candidates []candidate
+	for _, player := range players {
+		username := strings.ToLower(player.Username)
+		if username == query {
+			return PlayerMatch{Player: &player}, nil
+		}
+		if score, ok := matchScore(username, query); ok {
+			candidates = append(candidates, candidate{player: player, score: score})
+		}
+	}
+
+	if len(candidates) == 1 {
+		return PlayerMatch{Player: &candidates[0].player}, nil
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.score, b.score), cmp.Compare(strings.ToLower(a.player.Username), strings.ToLower(b.player.Username)))
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	match := PlayerMatch{Suggestions: make([]models.Player, len(candidates))}
+	for i, c := range candidates {
+		match.Suggestions[i] = c.player
+	}
+	return match, nil
+}
+
+// matchScore оценивает сходство ника с запросом; false, если они не похожи
+func matchScore(username, query string) (int, bool) {
+	switch {
+	case strings.HasPrefix(username, query):
+		return matchPrefix, true
+	case strings.Contains(username, query):
+		return matchSubstring, true
+	}
+	// Допускается правка на каждые три символа запроса, но не больше двух:
+	// иначе короткий запрос похож на любой ник
+	allowed := min(2, max(1, utf8.RuneCountInString(query)/3))
+	if distance := editDistance(username, query); distance <= allowed {
+		return matchTypo + distance, true
+	}
+	return 0, false
+}
+
+// editDistance — расстояние Левенштейна между строками в символах
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
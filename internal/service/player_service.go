@@ -1,28 +1,31 @@
 package service
 
 import (
+	"context"
 	"mine-parser/internal/models"
 	"mine-parser/internal/repo"
 	"time"
 )
 
-// Глобальная функция для отправки событий (устанавливается из app/notifications.go)
-var globalLoginEventSender func(playerID, username string)
-
-// SetGlobalLoginEventSender устанавливает глобальную функцию отправки событий
-func SetGlobalLoginEventSender(sender func(playerID, username string)) {
-	globalLoginEventSender = sender
-}
-
 type PlayerService interface {
-	RegisterLogin(playerID, username string, ip string, entityID int, timestamp time.Time) error
-	RegisterLogout(playerID string, timestamp time.Time) error
-	GetPlayerStats(playerID string) (*PlayerStats, error)
-	ListOnlinePlayers() ([]models.Player, error)
-	ListAllPlayers() ([]models.Player, error)
-	IsPlayerOnline(playerID string) (bool, error)
-	GetLastSession(playerID string) (*models.Session, error)
-	GetPlayerByUsername(username string) (*models.Player, error)
+	GetPlayerStats(ctx context.Context, playerID string) (*PlayerStats, error)
+	ListOnlinePlayers() []Presence
+	ListAllPlayers(ctx context.Context) ([]models.Player, error)
+	// ListPlayers возвращает страницу всех игроков; открытые сессии считаются до текущего момента
+	ListPlayers(ctx context.Context, page repo.PlayerPage) ([]repo.PlayerPlayTime, error)
+	CountPlayers(ctx context.Context) (int64, error)
+	// PlayerPositionByName возвращает место игрока в списке по нику, считая с нуля; -1, если игрока нет
+	PlayerPositionByName(ctx context.Context, playerID string) (int64, error)
+	IsPlayerOnline(playerID string) bool
+	GetPresence(playerID string) (Presence, bool)
+	GetLastSession(ctx context.Context, playerID string) (*models.Session, error)
+	GetPlayerByUsername(ctx context.Context, username string) (*models.Player, error)
+	// FindPlayer ищет игрока по UUID или username; nil, если такого нет
+	FindPlayer(ctx context.Context, ref string) (*models.Player, error)
+	// MatchPlayer ищет игрока по нику без учёта регистра и с опечатками; limit ограничивает подсказки
+	MatchPlayer(ctx context.Context, name string, limit int) (PlayerMatch, error)
+	SearchPlayers(ctx context.Context, filter repo.PlayerFilter) ([]models.Player, error)
+	ListSessions(ctx context.Context, filter repo.SessionFilter) ([]models.Session, error)
 }
 
 // PlayerStats — DTO для агрегированных данных
@@ -39,6 +42,7 @@ type playerService struct {
 	sessionRepo repo.SessionRepository
 	commandRepo repo.CommandRepository
 	advanceRepo repo.AdvancementRepository
+	presence    PresenceTracker
 }
 
 func NewPlayerService(
@@ -46,103 +50,49 @@ func NewPlayerService(
 	sessionRepo repo.SessionRepository,
 	commandRepo repo.CommandRepository,
 	advanceRepo repo.AdvancementRepository,
+	presence PresenceTracker,
 ) PlayerService {
 	return &playerService{
 		playerRepo:  playerRepo,
 		sessionRepo: sessionRepo,
 		commandRepo: commandRepo,
 		advanceRepo: advanceRepo,
+		presence:    presence,
 	}
 }
 
-func (s *playerService) RegisterLogin(playerID, username string, ip string, entityID int, timestamp time.Time) error {
-	// Создаем или обновляем игрока
-	_, err := s.playerRepo.GetOrCreate(playerID, username, timestamp)
-	if err != nil {
-		return err
-	}
-
-	// Проверяем, нет ли уже активной сессии
-	activeSession, err := s.sessionRepo.GetActiveSessionByPlayer(playerID)
-	if err == nil && activeSession != nil {
-		// Если есть активная сессия, закрываем её перед созданием новой
-		_ = s.sessionRepo.CloseSession(activeSession.ID, timestamp)
-	}
-
-	// Создаем новую сессию
-	session := &models.Session{
-		PlayerID:  playerID,
-		JoinTime:  timestamp,
-		LeaveTime: nil,
-		IPAddress: ip,
-		EntityID:  entityID,
-	}
-
-	err = s.sessionRepo.Create(session)
-	if err != nil {
-		return err
-	}
-
-	// Отправляем событие входа игрока (не блокируем основной поток)
-	if globalLoginEventSender != nil {
-		go globalLoginEventSender(playerID, username)
-	}
-
-	return nil
-}
-
-func (s *playerService) RegisterLogout(playerID string, timestamp time.Time) error {
-	// Обновляем last_seen игрока
-	if err := s.playerRepo.UpdateLastSeen(playerID, timestamp); err != nil {
-		return err
-	}
-
-	// Находим активную сессию и закрываем её
-	activeSession, err := s.sessionRepo.GetActiveSessionByPlayer(playerID)
-	if err != nil {
-		// Если активной сессии нет, это не критично (может быть уже закрыта)
-		return nil
-	}
-
-	return s.sessionRepo.CloseSession(activeSession.ID, timestamp)
-}
-
-func (s *playerService) GetPlayerStats(playerID string) (*PlayerStats, error) {
+func (s *playerService) GetPlayerStats(ctx context.Context, playerID string) (*PlayerStats, error) {
 	// Получаем игрока
-	player, err := s.playerRepo.FindByID(playerID)
+	player, err := s.playerRepo.FindByID(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Получаем все сессии игрока
-	sessions, err := s.sessionRepo.ListByPlayer(playerID)
+	sessions, err := s.sessionRepo.ListByPlayer(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Вычисляем общее время игры
 	var totalPlayTime time.Duration
-	// Коррекция часового пояса: сервер на -3 часа, нужно добавить 3 часа
-	timeZoneCorrection := 3 * time.Hour
 	for _, session := range sessions {
 		if session.LeaveTime != nil {
 			totalPlayTime += session.LeaveTime.Sub(session.JoinTime)
 		} else {
 			// Если сессия еще активна, считаем до текущего времени
-			// Сначала добавляем 3 часа к текущему времени, потом вычитаем время входа
-			currentTimeCorrected := time.Now().Add(timeZoneCorrection)
-			totalPlayTime += currentTimeCorrected.Sub(session.JoinTime)
+			totalPlayTime += time.Since(session.JoinTime)
 		}
 	}
 
 	// Получаем количество команд
-	commandsCount, err := s.commandRepo.CountCommandsByPlayer(playerID)
+	commandsCount, err := s.commandRepo.CountCommandsByPlayer(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Получаем достижения
-	advancements, err := s.advanceRepo.ListByPlayer(playerID)
+	advancements, err := s.advanceRepo.ListByPlayer(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
@@ -156,55 +106,42 @@ func (s *playerService) GetPlayerStats(playerID string) (*PlayerStats, error) {
 	}, nil
 }
 
-func (s *playerService) ListOnlinePlayers() ([]models.Player, error) {
-	// Получаем все активные сессии
-	activeSessions, err := s.sessionRepo.ListActive()
-	if err != nil {
-		return nil, err
-	}
+// ListOnlinePlayers возвращает игроков онлайн из трекера присутствия, без запросов к БД
+func (s *playerService) ListOnlinePlayers() []Presence {
+	return s.presence.Online()
+}
 
-	// Собираем уникальных игроков
-	playerMap := make(map[string]bool)
-	var playerIDs []string
-	for _, session := range activeSessions {
-		if !playerMap[session.PlayerID] {
-			playerMap[session.PlayerID] = true
-			playerIDs = append(playerIDs, session.PlayerID)
-		}
-	}
+func (s *playerService) GetPlayerByUsername(ctx context.Context, username string) (*models.Player, error) {
+	return s.playerRepo.FindByUsername(ctx, username)
+}
 
-	// Получаем данные игроков
-	var players []models.Player
-	for _, playerID := range playerIDs {
-		player, err := s.playerRepo.FindByID(playerID)
-		if err != nil {
-			// Пропускаем игроков, которых не удалось найти
-			continue
-		}
-		players = append(players, *player)
-	}
+func (s *playerService) ListAllPlayers(ctx context.Context) ([]models.Player, error) {
+	return s.playerRepo.ListAll(ctx)
+}
 
-	return players, nil
+func (s *playerService) ListPlayers(ctx context.Context, page repo.PlayerPage) ([]repo.PlayerPlayTime, error) {
+	page.Now = time.Now()
+	return s.playerRepo.ListPage(ctx, page)
 }
 
-func (s *playerService) GetPlayerByUsername(username string) (*models.Player, error) {
-	return s.playerRepo.FindByUsername(username)
+func (s *playerService) CountPlayers(ctx context.Context) (int64, error) {
+	return s.playerRepo.Count(ctx)
 }
 
-func (s *playerService) ListAllPlayers() ([]models.Player, error) {
-	return s.playerRepo.ListAll()
+func (s *playerService) PlayerPositionByName(ctx context.Context, playerID string) (int64, error) {
+	return s.playerRepo.PositionByName(ctx, playerID)
 }
 
-func (s *playerService) IsPlayerOnline(playerID string) (bool, error) {
-	activeSession, err := s.sessionRepo.GetActiveSessionByPlayer(playerID)
-	if err != nil {
-		return false, err
-	}
-	return activeSession != nil, nil
+func (s *playerService) IsPlayerOnline(playerID string) bool {
+	return s.presence.IsOnline(playerID)
 }
 
-func (s *playerService) GetLastSession(playerID string) (*models.Session, error) {
-	sessions, err := s.sessionRepo.ListByPlayer(playerID)
+func (s *playerService) GetPresence(playerID string) (Presence, bool) {
+	return s.presence.Get(playerID)
+}
+
+func (s *playerService) GetLastSession(ctx context.Context, playerID string) (*models.Session, error) {
+	sessions, err := s.sessionRepo.ListByPlayer(ctx, playerID)
 	if err != nil {
 		return nil, err
 	}
@@ -213,3 +150,22 @@ func (s *playerService) GetLastSession(playerID string) (*models.Session, error)
 	}
 	return &sessions[0], nil
 }
+
+func (s *playerService) FindPlayer(ctx context.Context, ref string) (*models.Player, error) {
+	if !playerIDRe.MatchString(ref) {
+		return s.playerRepo.FindByUsername(ctx, ref)
+	}
+	players, err := s.playerRepo.FindByIDs(ctx, []string{ref})
+	if err != nil || len(players) == 0 {
+		return nil, err
+	}
+	return &players[0], nil
+}
+
+func (s *playerService) SearchPlayers(ctx context.Context, filter repo.PlayerFilter) ([]models.Player, error) {
+	return s.playerRepo.Search(ctx, filter)
+}
+
+func (s *playerService) ListSessions(ctx context.Context, filter repo.SessionFilter) ([]models.Session, error) {
+	return s.sessionRepo.List(ctx, filter)
+}
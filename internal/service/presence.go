@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"mine-parser/internal/repo"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Presence — игрок, находящийся на сервере
+type Presence struct {
+	PlayerID  string
+	Username  string
+	SessionID uint
+	Server    string
+	Since     time.Time
+}
+
+// PresenceTracker хранит в памяти, кто сейчас онлайн.
+// Обновляется событиями входа/выхода и безопасен для чтения из любых горутин.
+type PresenceTracker interface {
+	// Rebuild восстанавливает состояние по открытым сессиям в БД
+	Rebuild(ctx context.Context, sessionRepo repo.SessionRepository, playerRepo repo.PlayerRepository) error
+	// Apply учитывает записанные события входа и выхода
+	Apply(events []LogEvent)
+	Get(playerID string) (Presence, bool)
+	IsOnline(playerID string) bool
+	Online() []Presence
+	Count() int
+}
+
+type presenceTracker struct {
+	mu     sync.RWMutex
+	online map[string]Presence // playerID → присутствие
+}
+
+func NewPresenceTracker() PresenceTracker {
+	return &presenceTracker{
+		online: make(map[string]Presence),
+	}
+}
+
+func (t *presenceTracker) Rebuild(ctx context.Context, sessionRepo repo.SessionRepository, playerRepo repo.PlayerRepository) error {
+	sessions, err := sessionRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	playerIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		playerIDs = append(playerIDs, session.PlayerID)
+	}
+	players, err := playerRepo.FindByIDs(ctx, playerIDs)
+	if err != nil {
+		return err
+	}
+	usernames := make(map[string]string, len(players))
+	for _, player := range players {
+		usernames[player.ID] = player.Username
+	}
+
+	online := make(map[string]Presence, len(sessions))
+	for _, session := range sessions {
+		// Сессии отсортированы от новых к старым: у игрока учитываем самую свежую
+		if _, ok := online[session.PlayerID]; ok {
+			continue
+		}
+		online[session.PlayerID] = Presence{
+			PlayerID:  session.PlayerID,
+			Username:  usernames[session.PlayerID],
+			SessionID: session.ID,
+			Server:    session.Server,
+			Since:     session.JoinTime,
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.online = online
+	return nil
+}
+
+func (t *presenceTracker) Apply(events []LogEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, event := range events {
+		switch event.Rule {
+		case RuleJoin:
+			t.online[event.PlayerID] = Presence{
+				PlayerID:  event.PlayerID,
+				Username:  event.Username,
+				SessionID: event.SessionID,
+				Server:    event.Server,
+				Since:     event.Timestamp,
+			}
+		case RuleLeave:
+			delete(t.online, event.PlayerID)
+		}
+	}
+}
+
+func (t *presenceTracker) Get(playerID string) (Presence, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	presence, ok := t.online[playerID]
+	return presence, ok
+}
+
+func (t *presenceTracker) IsOnline(playerID string) bool {
+	_, ok := t.Get(playerID)
+	return ok
+}
+
+// Online возвращает игроков онлайн в порядке входа
+func (t *presenceTracker) Online() []Presence {
+	t.mu.RLock()
+	result := make([]Presence, 0, len(t.online))
+	for _, presence := range t.online {
+		result = append(result, presence)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Since.Equal(result[j].Since) {
+			return result[i].Since.Before(result[j].Since)
+		}
+		return result[i].Username < result[j].Username
+	})
+	return result
+}
+
+func (t *presenceTracker) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.online)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"time"
+)
+
+// ServerStatus — текущее состояние сервера по присутствию игроков и запускам
+type ServerStatus struct {
+	Server string
+	Online []Presence        // игроки онлайн в порядке входа
+	Run    *models.ServerRun // последний запуск; nil, если запусков в логе не было
+}
+
+// Running сообщает, работает ли сервер. Если запуск не попадал в лог,
+// сервер считается работающим, пока на нём есть игроки.
+func (s ServerStatus) Running() bool {
+	if s.Run != nil {
+		return s.Run.StoppedAt == nil
+	}
+	return len(s.Online) > 0
+}
+
+// Uptime возвращает время с последнего запуска; 0, если сервер остановлен или запуск неизвестен
+func (s ServerStatus) Uptime(now time.Time) time.Duration {
+	if s.Run == nil || s.Run.StoppedAt != nil || now.Before(s.Run.StartedAt) {
+		return 0
+	}
+	return now.Sub(s.Run.StartedAt)
+}
+
+type ServerStatusService interface {
+	// Status возвращает состояние сервера server
+	Status(ctx context.Context, server string) (ServerStatus, error)
+}
+
+type serverStatusService struct {
+	presence      PresenceTracker
+	serverRunRepo repo.ServerRunRepository
+}
+
+func NewServerStatusService(presence PresenceTracker, serverRunRepo repo.ServerRunRepository) ServerStatusService {
+	return &serverStatusService{presence: presence, serverRunRepo: serverRunRepo}
+}
+
+func (s *serverStatusService) Status(ctx context.Context, server string) (ServerStatus, error) {
+	run, err := s.serverRunRepo.Latest(ctx, server)
+	if err != nil {
+		return ServerStatus{}, err
+	}
+	status := ServerStatus{Server: server, Run: run}
+	for _, presence := range s.presence.Online() {
+		if presence.Server == server {
+			status.Online = append(status.Online, presence)
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Показатели рейтинга игроков
+const (
+	LeaderboardPlayTime     = "playtime"     // время в игре, секунды
+	LeaderboardSessions     = "sessions"     // число сессий
+	LeaderboardCommands     = "commands"     // выполнено команд
+	LeaderboardAdvancements = "advancements" // получено достижений
+	LeaderboardLongest      = "longest"      // самая долгая сессия, секунды
+)
+
+// LeaderboardMetrics — все показатели рейтинга
+var LeaderboardMetrics = []string{LeaderboardPlayTime, LeaderboardSessions, LeaderboardCommands, LeaderboardAdvancements, LeaderboardLongest}
+
+// LeaderboardEntry — место игрока в рейтинге
+type LeaderboardEntry struct {
+	Rank   int
+	Player models.Player
+	Value  int64 // секунды для playtime и longest, иначе количество
+}
+
+// HistoryDay — активность на сервере за календарный день
+type HistoryDay struct {
+	Date       time.Time // начало дня в часовом поясе запроса
+	Players    int       // разных игроков заходило
+	Sessions   int       // сессий началось
+	PlayTime   time.Duration
+	PeakOnline int // наибольшее число игроков одновременно
+}
+
+type StatsService interface {
+	// Leaderboard возвращает первые filter.Limit игроков по показателю metric за период
+	Leaderboard(ctx context.Context, metric string, filter repo.RankFilter) ([]LeaderboardEntry, error)
+	// History возвращает активность по дням периода; дни отсчитываются в часовом поясе loc
+	History(ctx context.Context, server string, period repo.TimeRange, loc *time.Location) ([]HistoryDay, error)
+}
+
+type statsService struct {
+	playerRepo  repo.PlayerRepository
+	sessionRepo repo.SessionRepository
+	commandRepo repo.CommandRepository
+	advanceRepo repo.AdvancementRepository
+}
+
+func NewStatsService(
+	playerRepo repo.PlayerRepository,
+	sessionRepo repo.SessionRepository,
+	commandRepo repo.CommandRepository,
+	advanceRepo repo.AdvancementRepository,
+) StatsService {
+	return &statsService{
+		playerRepo:  playerRepo,
+		sessionRepo: sessionRepo,
+		commandRepo: commandRepo,
+		advanceRepo: advanceRepo,
+	}
+}
+
+func (s *statsService) Leaderboard(ctx context.Context, metric string, filter repo.RankFilter) ([]LeaderboardEntry, error) {
+	var counts []repo.PlayerCount
+	var err error
+	switch metric {
+	case LeaderboardPlayTime, LeaderboardSessions, LeaderboardLongest:
+		counts, err = s.rankSessions(ctx, metric, filter)
+	case LeaderboardCommands:
+		counts, err = s.commandRepo.CountByPlayer(ctx, filter)
+	case LeaderboardAdvancements:
+		counts, err = s.advanceRepo.CountByPlayer(ctx, filter)
+	default:
+		return nil, fmt.Errorf("неизвестный показатель рейтинга %q", metric)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(counts))
+	for i, count := range counts {
+		ids[i] = count.PlayerID
+	}
+	players, err := s.playerRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Player, len(players))
+	for _, player := range players {
+		byID[player.ID] = player
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(counts))
+	for _, count := range counts {
+		player, ok := byID[count.PlayerID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, LeaderboardEntry{Rank: len(entries) + 1, Player: player, Value: count.Count})
+	}
+	return entries, nil
+}
+
+// rankSessions считает время в игре, число сессий или самую долгую сессию по сессиям,
+// пересекающимся с периодом; агрегирует БД
+func (s *statsService) rankSessions(ctx context.Context, metric string, filter repo.RankFilter) ([]repo.PlayerCount, error) {
+	switch metric {
+	case LeaderboardSessions:
+		return s.sessionRepo.CountByPlayer(ctx, filter)
+	case LeaderboardLongest:
+		return s.sessionRepo.LongestByPlayer(ctx, filter, time.Now())
+	default:
+		return s.sessionRepo.PlayTimeByPlayer(ctx, filter, time.Now())
+	}
+}
+
+func (s *statsService) History(ctx context.Context, server string, period repo.TimeRange, loc *time.Location) ([]HistoryDay, error) {
+	if period.From.IsZero() || period.To.IsZero() {
+		return nil, fmt.Errorf("для истории нужны обе границы периода")
+	}
+
+	// Границы дней: bounds[i] — начало i-го дня, последняя — конец последнего дня
+	from := period.From.In(loc)
+	var bounds []time.Time
+	for i := 0; ; i++ {
+		day := time.Date(from.Year(), from.Month(), from.Day()+i, 0, 0, 0, 0, loc)
+		bounds = append(bounds, day)
+		if !day.Before(period.To) {
+			break
+		}
+	}
+	history := make([]HistoryDay, len(bounds)-1)
+	for i := range history {
+		history[i].Date = bounds[i]
+	}
+	if len(history) == 0 {
+		return history, nil
+	}
+
+	full := repo.TimeRange{From: bounds[0], To: bounds[len(bounds)-1]}
+	sessions, err := s.sessionRepo.List(ctx, repo.SessionFilter{Server: server, Period: full})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	players := make([]map[string]bool, len(history))
+	spans := make([][]span, len(history))
+	for _, session := range sessions {
+		start, end := clipSession(session, full, now)
+		if end.Before(start) {
+			continue
+		}
+		// День начала сессии; мгновенная сессия (вход и выход в одну секунду) тоже считается
+		first := sort.Search(len(history), func(i int) bool { return bounds[i+1].After(start) })
+		if first < len(history) && !session.JoinTime.Before(bounds[first]) {
+			history[first].Sessions++
+		}
+		for i := first; i < len(history) && (i == first || bounds[i].Before(end)); i++ {
+			spanStart, spanEnd := later(start, bounds[i]), earlier(end, bounds[i+1])
+			history[i].PlayTime += spanEnd.Sub(spanStart)
+			spans[i] = append(spans[i], span{spanStart, spanEnd})
+			if players[i] == nil {
+				players[i] = make(map[string]bool)
+			}
+			players[i][session.PlayerID] = true
+		}
+	}
+	for i := range history {
+		history[i].Players = len(players[i])
+		history[i].PeakOnline = peakOverlap(spans[i])
+	}
+	return history, nil
+}
+
+// span — отрезок времени, который игрок провёл на сервере
+type span struct {
+	start, end time.Time
+}
+
+// peakOverlap возвращает наибольшее число одновременно открытых отрезков
+func peakOverlap(spans []span) int {
+	type edge struct {
+		at    time.Time
+		delta int
+	}
+	edges := make([]edge, 0, 2*len(spans))
+	for _, s := range spans {
+		edges = append(edges, edge{s.start, 1}, edge{s.end, -1})
+	}
+	// При совпадении времени выход учитывается раньше входа: перезаход не удваивает онлайн
+	slices.SortFunc(edges, func(a, b edge) int {
+		return cmp.Or(a.at.Compare(b.at), cmp.Compare(a.delta, b.delta))
+	})
+
+	current, peak := 0, 0
+	for _, e := range edges {
+		current += e.delta
+		peak = max(peak, current)
+	}
+	return peak
+}
+
+// clipSession обрезает сессию периодом; открытая сессия длится до now
+func clipSession(session models.Session, period repo.TimeRange, now time.Time) (time.Time, time.Time) {
+	start, end := session.JoinTime, now
+	if session.LeaveTime != nil {
+		end = *session.LeaveTime
+	}
+	if !period.From.IsZero() {
+		start = later(start, period.From)
+	}
+	if !period.To.IsZero() {
+		end = earlier(end, period.To)
+	}
+	return start, end
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
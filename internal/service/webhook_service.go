@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"time"
+)
+
+// WebhookStatus — вебхук из конфигурации вместе с его состоянием доставки
+type WebhookStatus struct {
+	Name                string
+	URL                 string
+	Events              []string
+	Servers             []string
+	ConsecutiveFailures int
+	DisabledAt          *time.Time // nil, если вебхук работает
+	DisabledReason      string
+	Pending             int64 // доставок в очереди
+}
+
+type WebhookService interface {
+	// List возвращает вебхуки в порядке конфигурации
+	List(ctx context.Context) ([]WebhookStatus, error)
+	// Deliveries возвращает журнал последних доставок вебхука, от новых к старым
+	Deliveries(ctx context.Context, name string, limit int) ([]models.WebhookDelivery, error)
+	// Enable включает отключённый вебхук; доставка продолжится с новых событий
+	Enable(ctx context.Context, name string) error
+}
+
+type webhookService struct {
+	webhookRepo repo.WebhookRepository
+	cfg         *config.Store
+}
+
+func NewWebhookService(webhookRepo repo.WebhookRepository, cfg *config.Store) WebhookService {
+	return &webhookService{webhookRepo: webhookRepo, cfg: cfg}
+}
+
+func (s *webhookService) List(ctx context.Context) ([]WebhookStatus, error) {
+	states, err := s.webhookRepo.ListStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.WebhookState, len(states))
+	for _, state := range states {
+		byName[state.Webhook] = state
+	}
+	pending, err := s.webhookRepo.CountPending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := s.cfg.Get().Webhooks.Endpoints
+	result := make([]WebhookStatus, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		state := byName[endpoint.Name]
+		result = append(result, WebhookStatus{
+			Name:                endpoint.Name,
+			URL:                 endpoint.URL,
+			Events:              endpoint.Events,
+			Servers:             endpoint.Servers,
+			ConsecutiveFailures: state.ConsecutiveFailures,
+			DisabledAt:          state.DisabledAt,
+			DisabledReason:      state.DisabledReason,
+			Pending:             pending[endpoint.Name],
+		})
+	}
+	return result, nil
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, name string, limit int) ([]models.WebhookDelivery, error) {
+	return s.webhookRepo.ListDeliveries(ctx, name, limit)
+}
+
+func (s *webhookService) Enable(ctx context.Context, name string) error {
+	if _, ok := s.cfg.Get().Webhooks.Endpoint(name); !ok {
+		return fmt.Errorf("вебхук %q не найден в конфигурации", name)
+	}
+	return s.webhookRepo.Enable(ctx, name)
+}
@@ -0,0 +1,556 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mine-parser/internal/config"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogEvent — распознанное событие лога, ожидающее записи в БД
+type LogEvent struct {
+	Rule        string // одно из правил Rule*, кроме RuleUUID и RuleLoginIP
+	Fingerprint string
+	Server      string
+	PlayerID    string
+	Username    string
+	IP          string
+	EntityID    int
+	Command     string
+	Advancement string
+	Message     string // текст сообщения в чате или сообщения о смерти
+	Timestamp   time.Time
+	SessionID   uint // заполняется конвейером для событий входа после записи сессии
+}
+
+// PipelineOptions — параметры буферизации записи
+type PipelineOptions struct {
+	MaxBatchSize  int           // при достижении размера батч записывается сразу
+	FlushInterval time.Duration // максимальное время ожидания события в буфере
+	WriteTimeout  time.Duration // максимальное время записи одного батча
+}
+
+// LivePipelineOptions — параметры для отслеживания лога в реальном времени
+func LivePipelineOptions(cfg *config.Config) PipelineOptions {
+	return PipelineOptions{
+		MaxBatchSize:  cfg.App.IngestBatchSize,
+		FlushInterval: cfg.App.IngestFlushInterval,
+		WriteTimeout:  cfg.App.IngestWriteTimeout,
+	}
+}
+
+// BackfillPipelineOptions — параметры для разовой загрузки больших логов
+func BackfillPipelineOptions(cfg *config.Config) PipelineOptions {
+	return PipelineOptions{
+		MaxBatchSize:  cfg.App.BackfillBatchSize,
+		FlushInterval: cfg.App.IngestFlushInterval,
+		WriteTimeout:  cfg.App.IngestWriteTimeout,
+	}
+}
+
+// PipelineStats — счётчики конвейера записи
+type PipelineStats struct {
+	Events     int64         // записанные события
+	Duplicates int64         // события, пропущенные как уже обработанные
+	Failed     int64         // события, которые не удалось записать
+	Batches    int64         // выполненные транзакции
+	WriteTime  time.Duration // суммарное время записи
+}
+
+// WritePipeline буферизует события и записывает их упорядоченными батчами в транзакции
+type WritePipeline interface {
+	Submit(event LogEvent) error
+	Flush() error
+	Close() error
+	Stats() PipelineStats
+}
+
+var errPipelineClosed = errors.New("конвейер записи остановлен")
+
+type writePipeline struct {
+	transactor  repo.Transactor
+	diagnostics ParseDiagnostics
+	opts        PipelineOptions
+	outbox      NotificationOutbox
+	onCommitted func(events []LogEvent)
+
+	mu      sync.Mutex // защищает buffer и closed
+	buffer  []LogEvent
+	closed  bool
+	flushMu sync.Mutex // гарантирует, что батчи пишутся строго по очереди
+
+	statsMu sync.Mutex
+	stats   PipelineStats
+
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+}
+
+// NewWritePipeline создаёт конвейер и запускает периодическую запись буфера.
+// Уведомления ставятся в очередь outbox в той же транзакции (outbox может быть nil).
+// onCommitted вызывается после успешной транзакции с новыми (не дублирующимися) событиями.
+func NewWritePipeline(
+	transactor repo.Transactor,
+	diagnostics ParseDiagnostics,
+	opts PipelineOptions,
+	outbox NotificationOutbox,
+	onCommitted func(events []LogEvent),
+) WritePipeline {
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = time.Minute
+	}
+
+	p := &writePipeline{
+		transactor:  transactor,
+		diagnostics: diagnostics,
+		opts:        opts,
+		outbox:      outbox,
+		onCommitted: onCommitted,
+		buffer:      make([]LogEvent, 0, opts.MaxBatchSize),
+		stopChan:    make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.flushLoop()
+	return p
+}
+
+func (p *writePipeline) Submit(event LogEvent) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errPipelineClosed
+	}
+	p.buffer = append(p.buffer, event)
+	full := len(p.buffer) >= p.opts.MaxBatchSize
+	p.mu.Unlock()
+
+	// Полный батч пишем сразу в вызывающей горутине — это даёт естественное ограничение скорости парсера
+	if full {
+		return p.Flush()
+	}
+	return nil
+}
+
+func (p *writePipeline) Flush() error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	batch := p.buffer
+	p.buffer = make([]LogEvent, 0, p.opts.MaxBatchSize)
+	p.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return p.write(batch)
+}
+
+func (p *writePipeline) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stopChan)
+	p.wg.Wait()
+	return p.Flush()
+}
+
+func (p *writePipeline) Stats() PipelineStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	return p.stats
+}
+
+// flushLoop записывает буфер по таймеру, чтобы события не задерживались при низкой активности
+func (p *writePipeline) flushLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Flush(); err != nil {
+				logging.Errorf("Ошибка при записи батча событий: %v", err)
+			}
+		case <-p.stopChan:
+			return
+		}
+	}
+}
+
+// write записывает батч одной транзакцией. Если транзакция не удалась,
+// события записываются по одному, чтобы одно некорректное событие не блокировало остальные.
+func (p *writePipeline) write(batch []LogEvent) error {
+	started := time.Now()
+	applied, duplicates, err := p.applyBatch(batch)
+	if err != nil && len(batch) > 1 {
+		logging.Warnf("Батч из %d событий не записан (%v), записываем по одному", len(batch), err)
+		applied, duplicates, err = nil, nil, nil
+		for _, event := range batch {
+			eventApplied, eventDuplicates, eventErr := p.applyBatch([]LogEvent{event})
+			if eventErr != nil {
+				logging.Warnf("Событие %s игрока %s пропущено: %v", event.Rule, event.PlayerID, eventErr)
+				p.diagnostics.RecordParseError(event.Rule, eventErr)
+				p.recordStats(0, 0, 1, 0)
+				err = eventErr
+				continue
+			}
+			applied = append(applied, eventApplied...)
+			duplicates = append(duplicates, eventDuplicates...)
+		}
+	} else if err != nil {
+		p.diagnostics.RecordParseError(batch[0].Rule, err)
+		p.recordStats(0, 0, int64(len(batch)), time.Since(started))
+		return err
+	}
+
+	for _, event := range duplicates {
+		p.diagnostics.RecordDuplicate(event.Rule)
+	}
+	p.recordStats(int64(len(applied)), int64(len(duplicates)), 0, time.Since(started))
+
+	if p.onCommitted != nil && len(applied) > 0 {
+		p.onCommitted(applied)
+	}
+	return err
+}
+
+func (p *writePipeline) recordStats(events, duplicates, failed int64, writeTime time.Duration) {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+	p.stats.Events += events
+	p.stats.Duplicates += duplicates
+	p.stats.Failed += failed
+	p.stats.WriteTime += writeTime
+	if events > 0 || duplicates > 0 {
+		p.stats.Batches++
+	}
+}
+
+// pendingCommand — команда, сессия которой может быть ещё не записана
+type pendingCommand struct {
+	command *models.Command
+	session *models.Session
+}
+
+// openSessions — открытые сессии игроков по возрастанию времени входа.
+// При загрузке старого лога у игрока может быть более поздняя открытая сессия
+// из живого лога: события применяются к сессии, открытой на момент события.
+type openSessions map[string][]*models.Session
+
+// at возвращает последнюю сессию игрока, начатую не позже момента at, и её индекс
+func (o openSessions) at(playerID string, at time.Time) (int, *models.Session) {
+	sessions := o[playerID]
+	for i := len(sessions) - 1; i >= 0; i-- {
+		if !sessions[i].JoinTime.After(at) {
+			return i, sessions[i]
+		}
+	}
+	return -1, nil
+}
+
+// remove убирает закрытую сессию из открытых
+func (o openSessions) remove(playerID string, i int) {
+	o[playerID] = slices.Delete(o[playerID], i, i+1)
+}
+
+// add добавляет сессию, сохраняя порядок по времени входа
+func (o openSessions) add(session *models.Session) {
+	sessions := o[session.PlayerID]
+	i := len(sessions)
+	for i > 0 && sessions[i-1].JoinTime.After(session.JoinTime) {
+		i--
+	}
+	o[session.PlayerID] = slices.Insert(sessions, i, session)
+}
+
+// applyBatch применяет события по порядку в одной транзакции.
+// Состояние (активные сессии, полученные достижения) загружается один раз на батч,
+// а вставки выполняются массово в конце.
+func (p *writePipeline) applyBatch(batch []LogEvent) (applied, duplicates []LogEvent, err error) {
+	// Запись не привязана к контексту приложения: при остановке буфер должен быть записан до конца
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.WriteTimeout)
+	defer cancel()
+
+	err = p.transactor.InTransaction(ctx, func(r repo.TxRepositories) error {
+		applied, duplicates = nil, nil
+
+		// 1. Отбрасываем уже обработанные события (в том числе повторы внутри батча)
+		fingerprints := make([]string, 0, len(batch))
+		for _, event := range batch {
+			fingerprints = append(fingerprints, event.Fingerprint)
+		}
+		existing, err := r.Events.ListExisting(ctx, fingerprints)
+		if err != nil {
+			return err
+		}
+
+		var fresh []LogEvent
+		seen := make(map[string]bool, len(batch))
+		for _, event := range batch {
+			if existing[event.Fingerprint] || seen[event.Fingerprint] {
+				duplicates = append(duplicates, event)
+				continue
+			}
+			seen[event.Fingerprint] = true
+			fresh = append(fresh, event)
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+
+		// 2. Загружаем состояние игроков из батча
+		var playerIDs, advancementPlayerIDs []string
+		playerSeen := make(map[string]bool)
+		// Достижения игрока проверяются, даже если первое его событие в батче — не достижение
+		advancementSeen := make(map[string]bool)
+		for _, event := range fresh {
+			if isServerRule(event.Rule) || isFeedOnlyRule(event.Rule) {
+				continue
+			}
+			if !playerSeen[event.PlayerID] {
+				playerSeen[event.PlayerID] = true
+				playerIDs = append(playerIDs, event.PlayerID)
+			}
+			if event.Rule == RuleAdvancement && !advancementSeen[event.PlayerID] {
+				advancementSeen[event.PlayerID] = true
+				advancementPlayerIDs = append(advancementPlayerIDs, event.PlayerID)
+			}
+		}
+
+		loaded, err := r.Sessions.ListOpenByPlayers(ctx, playerIDs)
+		if err != nil {
+			return err
+		}
+		open := openSessions(loaded)
+		completed, err := r.Advancements.ListCompletedNames(ctx, advancementPlayerIDs)
+		if err != nil {
+			return err
+		}
+
+		// 3. Применяем события в исходном порядке, накапливая изменения
+		var (
+			players      []*models.Player
+			playerByID   = make(map[string]*models.Player)
+			lastSeenOnly = make(map[string]time.Time)
+			closed       = make(map[uint]time.Time)
+			newSessions  []*models.Session
+			joinSessions = make(map[int]*models.Session) // индекс события входа → его сессия
+			commands     []pendingCommand
+			advancements []*models.Advancement
+			processed    = make([]models.ProcessedEvent, 0, len(fresh))
+			serverRuns   []LogEvent // запуски и остановки серверов в исходном порядке
+			closeSession = func(session *models.Session, at time.Time) {
+				if session.ID == 0 {
+					// Сессия создана в этом же батче — просто проставляем время выхода
+					leaveTime := at
+					session.LeaveTime = &leaveTime
+				} else {
+					closed[session.ID] = at
+				}
+			}
+		)
+
+		for i, event := range fresh {
+			processed = append(processed, models.ProcessedEvent{
+				Fingerprint: event.Fingerprint,
+				Rule:        event.Rule,
+			})
+
+			switch event.Rule {
+			case RuleJoin:
+				if player, ok := playerByID[event.PlayerID]; ok {
+					player.Username = event.Username
+					player.LastSeen = event.Timestamp
+				} else {
+					player = &models.Player{
+						ID:        event.PlayerID,
+						Username:  event.Username,
+						FirstSeen: event.Timestamp,
+						LastSeen:  event.Timestamp,
+					}
+					playerByID[event.PlayerID] = player
+					players = append(players, player)
+					delete(lastSeenOnly, event.PlayerID)
+				}
+
+				// Если есть сессия, открытая на момент входа, закрываем её перед созданием новой.
+				// Более поздние сессии не трогаем: вход из старого лога относится только к своей.
+				if i, session := open.at(event.PlayerID, event.Timestamp); session != nil {
+					closeSession(session, event.Timestamp)
+					open.remove(event.PlayerID, i)
+				}
+				session := &models.Session{
+					PlayerID:  event.PlayerID,
+					JoinTime:  event.Timestamp,
+					IPAddress: event.IP,
+					EntityID:  event.EntityID,
+					Server:    event.Server,
+				}
+				newSessions = append(newSessions, session)
+				joinSessions[i] = session
+				open.add(session)
+
+			case RuleLeave:
+				if player, ok := playerByID[event.PlayerID]; ok {
+					player.LastSeen = event.Timestamp
+				} else {
+					lastSeenOnly[event.PlayerID] = event.Timestamp
+				}
+				// Если открытой сессии нет, это не критично (может быть уже закрыта)
+				if i, session := open.at(event.PlayerID, event.Timestamp); session != nil {
+					closeSession(session, event.Timestamp)
+					open.remove(event.PlayerID, i)
+				}
+
+			case RuleCommand:
+				// Без открытой на момент команды сессии её не к чему привязать — пропускаем
+				_, session := open.at(event.PlayerID, event.Timestamp)
+				if session == nil {
+					continue
+				}
+				commandName, args := splitCommand(event.Command)
+				if commandName == "" {
+					continue
+				}
+				commands = append(commands, pendingCommand{
+					command: &models.Command{
+						Timestamp:   event.Timestamp,
+						Command:     event.Command,
+						CommandName: commandName,
+						Args:        args,
+					},
+					session: session,
+				})
+
+			case RuleServerStart, RuleServerStop:
+				serverRuns = append(serverRuns, event)
+
+			case RuleAdvancement:
+				// Не создаём дубликат уже полученного достижения
+				key := repo.AdvancementKey(event.PlayerID, event.Advancement)
+				if completed[key] {
+					continue
+				}
+				completed[key] = true
+				advancements = append(advancements, &models.Advancement{
+					PlayerID:        event.PlayerID,
+					Timestamp:       event.Timestamp,
+					AdvancementName: event.Advancement,
+				})
+			}
+		}
+
+		// 4. Записываем изменения: игроки до сессий и достижений из-за внешних ключей
+		upserts := make([]models.Player, 0, len(players))
+		for _, player := range players {
+			upserts = append(upserts, *player)
+		}
+		if err := r.Players.UpsertMany(ctx, upserts); err != nil {
+			return err
+		}
+		for playerID, lastSeen := range lastSeenOnly {
+			if err := r.Players.UpdateLastSeen(ctx, playerID, lastSeen); err != nil {
+				return err
+			}
+		}
+		for sessionID, leaveTime := range closed {
+			if err := r.Sessions.CloseSession(ctx, sessionID, leaveTime); err != nil {
+				return err
+			}
+		}
+		if err := r.Sessions.CreateMany(ctx, newSessions); err != nil {
+			return err
+		}
+
+		newCommands := make([]*models.Command, 0, len(commands))
+		for _, pending := range commands {
+			pending.command.SessionID = pending.session.ID
+			newCommands = append(newCommands, pending.command)
+		}
+		if err := r.Commands.CreateMany(ctx, newCommands); err != nil {
+			return err
+		}
+		if err := r.Advancements.CreateMany(ctx, advancements); err != nil {
+			return err
+		}
+		for _, event := range serverRuns {
+			if event.Rule == RuleServerStart {
+				err = r.ServerRuns.Start(ctx, event.Server, event.Timestamp)
+			} else {
+				err = r.ServerRuns.Stop(ctx, event.Server, event.Timestamp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// Отпечатки пишутся в той же транзакции: при гонке двух экземпляров
+		// уникальный индекс откатит весь батч
+		if err := r.Events.CreateMany(ctx, processed); err != nil {
+			return err
+		}
+
+		for i, session := range joinSessions {
+			fresh[i].SessionID = session.ID
+		}
+
+		if p.outbox != nil {
+			if err := p.outbox.Enqueue(ctx, r, fresh); err != nil {
+				return err
+			}
+		}
+		applied = fresh
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return applied, duplicates, nil
+}
+
+// isServerRule сообщает, относится ли правило к серверу целиком, а не к игроку.
+// Такие события не меняют данные игроков, а отмечают запуски сервера.
+func isServerRule(rule string) bool {
+	return rule == RuleServerStart || rule == RuleServerStop
+}
+
+// isFeedOnlyRule сообщает, что событие только публикуется в шину: чат и смерти
+// не хранятся в БД, отпечаток нужен лишь для того, чтобы не опубликовать их повторно.
+func isFeedOnlyRule(rule string) bool {
+	return rule == RuleChat || rule == RuleDeath
+}
+
+// splitCommand извлекает имя команды и аргументы
+func splitCommand(fullCommand string) (string, string) {
+	parts := strings.Fields(fullCommand)
+	if len(parts) == 0 {
+		return "", ""
+	}
+
+	args := ""
+	if len(parts) > 1 {
+		args = strings.Join(parts[1:], " ")
+	}
+	return parts[0], args
+}
@@ -0,0 +1,492 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mine-parser/internal/config"
+	"mine-parser/internal/migrations"
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// benchmarkPlayers и benchmarkRounds задают объём синтетического лога:
+// каждый игрок за раунд заходит, выполняет команды, получает достижение, пишет в чат и выходит
+const (
+	benchmarkPlayers = 20
+	benchmarkRounds  = 25
+)
+
+// benchmarkLog строит лог в формате сервера Minecraft и возвращает его строки
+func benchmarkLog() []string {
+	var lines []string
+	clock := 8 * time.Hour
+	add := func(component, message string) {
+		clock += time.Second
+		lines = append(lines, fmt.Sprintf("[%02d:%02d:%02d] [%s]: %s",
+			int(clock/time.Hour)%24, int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second), component, message))
+	}
+
+	add("Server thread/INFO", `Done (3.210s)! For help, type "help"`)
+	for round := range benchmarkRounds {
+		for player := range benchmarkPlayers {
+			name := fmt.Sprintf("Player%02d", player)
+			add("User Authenticator #1/INFO", fmt.Sprintf("UUID of player %s is 00000000-0000-0000-0000-%012d", name, player))
+			add("Server thread/INFO", fmt.Sprintf("%s[/10.0.0.%d:5555] logged in with entity id %d at (0, 64, 0)", name, player, round*100+player))
+			add("Server thread/INFO", name+" joined the game")
+			add("Server thread/INFO", name+" issued server command: /home")
+			add("Server thread/INFO", fmt.Sprintf("%s issued server command: /tp %s 0 64 0", name, name))
+			// Половина достижений повторяется, чтобы конвейер проверял уже полученные
+			add("Server thread/INFO", fmt.Sprintf("%s has made the advancement [Advancement %d]", name, round/2))
+			add("Server thread/INFO", fmt.Sprintf("<%s> привет", name))
+			add("Server thread/INFO", name+" left the game")
+		}
+	}
+	add("Server thread/INFO", "Stopping server")
+	return lines
+}
+
+// testConfig — конфигурация по умолчанию без чтения окружения
+func testConfig() *config.Config {
+	return &config.Config{App: config.AppConfig{
+		ServerName:          "main",
+		Location:            time.UTC,
+		IngestBatchSize:     100,
+		IngestFlushInterval: time.Second,
+		BackfillBatchSize:   2000,
+		IngestWriteTimeout:  time.Minute,
+	}}
+}
+
+// openTestDB создаёт новую БД SQLite со схемой из миграций
+func openTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	db, err := migrations.InitDB(config.SQLiteScheme+filepath.Join(tb.TempDir(), "parser.db"), true)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		tb.Cleanup(func() { sqlDB.Close() })
+	}
+	return db
+}
+
+// newTestPipeline создаёт конвейер записи в db; он останавливается в конце теста
+func newTestPipeline(tb testing.TB, db *gorm.DB, diagnostics ParseDiagnostics, opts PipelineOptions) WritePipeline {
+	pipeline := NewWritePipeline(repo.NewTransactor(db), diagnostics, opts, nil, nil)
+	tb.Cleanup(func() { pipeline.Close() })
+	return pipeline
+}
+
+// newTestParser создаёт парсер с конвейером поверх новой БД SQLite
+func newTestParser(tb testing.TB, cfg *config.Config, opts PipelineOptions) (LogParserService, WritePipeline, *gorm.DB) {
+	tb.Helper()
+	db := openTestDB(tb)
+	playerRepo, sessionRepo := repo.NewPlayerRepository(db), repo.NewSessionRepository(db)
+	commandRepo, advanceRepo := repo.NewCommandRepository(db), repo.NewAdvancementRepository(db)
+	playerSvc := NewPlayerService(playerRepo, sessionRepo, commandRepo, advanceRepo, NewPresenceTracker())
+	diagnostics := NewParseDiagnostics(5, 1000)
+	pipeline := newTestPipeline(tb, db, diagnostics, opts)
+
+	return NewLogParserService(context.Background(), cfg, playerSvc, pipeline, diagnostics), pipeline, db
+}
+
+const (
+	steveID = "00000000-0000-0000-0000-000000000001"
+	alexID  = "00000000-0000-0000-0000-000000000002"
+)
+
+// testLine — строка лога сервера со временем clock
+func testLine(clock, component, message string) string {
+	return "[" + clock + "] [" + component + "]: " + message
+}
+
+// testJoin — строки входа игрока: UUID, IP и сообщение о входе
+func testJoin(clock, name, id string) []string {
+	return []string{
+		testLine(clock, "User Authenticator #1/INFO", "UUID of player "+name+" is "+id),
+		testLine(clock, "Server thread/INFO", name+"[/10.0.0.1:5555] logged in with entity id 1 at (0, 64, 0)"),
+		testLine(clock, "Server thread/INFO", name+" joined the game"),
+	}
+}
+
+func testLeave(clock, name string) string {
+	return testLine(clock, "Server thread/INFO", name+" left the game")
+}
+
+func testCommand(clock, name, command string) string {
+	return testLine(clock, "Server thread/INFO", name+" issued server command: "+command)
+}
+
+// writeTestLog записывает архив лога за 10 апреля 2026 года
+func writeTestLog(t *testing.T, lines ...[]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "2026-04-10-1.log")
+	if err := os.WriteFile(path, []byte(strings.Join(slices.Concat(lines...), "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pipelineRows — записанные строки в виде, удобном для сравнения
+type pipelineRows struct {
+	sessions     []string // «игрок вход–выход», открытая сессия — «игрок вход–»
+	commands     []string // «игрок время команда»
+	advancements []string // «игрок время достижение»
+}
+
+func readPipelineRows(t *testing.T, db *gorm.DB) pipelineRows {
+	t.Helper()
+	const layout = "01-02 15:04"
+	var (
+		rows         pipelineRows
+		sessions     []models.Session
+		commands     []models.Command
+		advancements []models.Advancement
+	)
+	if err := db.Order("join_time").Order("id").Find(&sessions).Error; err != nil {
+		t.Fatal(err)
+	}
+	sessionPlayer := make(map[uint]string)
+	for _, session := range sessions {
+		sessionPlayer[session.ID] = session.PlayerID[len(session.PlayerID)-1:]
+		leave := ""
+		if session.LeaveTime != nil {
+			leave = session.LeaveTime.UTC().Format(layout)
+		}
+		rows.sessions = append(rows.sessions, sessionPlayer[session.ID]+" "+session.JoinTime.UTC().Format(layout)+"–"+leave)
+	}
+	if err := db.Order("timestamp").Order("id").Find(&commands).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range commands {
+		rows.commands = append(rows.commands, sessionPlayer[command.SessionID]+" "+command.Timestamp.UTC().Format(layout)+" "+command.Command)
+	}
+	if err := db.Order("timestamp").Order("id").Find(&advancements).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, advancement := range advancements {
+		rows.advancements = append(rows.advancements,
+			advancement.PlayerID[len(advancement.PlayerID)-1:]+" "+advancement.Timestamp.UTC().Format(layout)+" "+advancement.AdvancementName)
+	}
+	return rows
+}
+
+// testDay — начало событий в тестах конвейера
+var testDay = time.Date(2026, 4, 10, 10, 0, 0, 0, time.UTC)
+
+var testUsernames = map[string]string{steveID: "Steve", alexID: "Alex"}
+
+// testEvent — событие игрока через minute минут после testDay
+func testEvent(rule, playerID string, minute int) LogEvent {
+	return LogEvent{
+		Rule:      rule,
+		PlayerID:  playerID,
+		Username:  testUsernames[playerID],
+		Timestamp: testDay.Add(time.Duration(minute) * time.Minute),
+	}
+}
+
+func joinEvent(playerID string, minute int) LogEvent {
+	event := testEvent(RuleJoin, playerID, minute)
+	event.IP = "10.0.0.1"
+	return event
+}
+
+func commandEvent(playerID string, minute int, command string) LogEvent {
+	event := testEvent(RuleCommand, playerID, minute)
+	event.Command = command
+	return event
+}
+
+func advancementEvent(playerID string, minute int, advancement string) LogEvent {
+	event := testEvent(RuleAdvancement, playerID, minute)
+	event.Advancement = advancement
+	return event
+}
+
+func TestPipelineWritesEvents(t *testing.T) {
+	// Игроки в ожидаемых строках обозначены последней цифрой UUID: 1 — Steve, 2 — Alex
+	cases := []struct {
+		name      string
+		events    []LogEvent
+		passes    int // сколько раз отправить события; отпечаток события — его номер
+		want      pipelineRows
+		wantStats PipelineStats
+	}{
+		{
+			name: "сессии, команды и достижения",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				commandEvent(steveID, 1, "/home"),
+				advancementEvent(steveID, 2, "Stone Age"),
+				joinEvent(alexID, 3),
+				commandEvent(alexID, 4, "/tp 0 64 0"),
+				testEvent(RuleLeave, steveID, 5),
+			},
+			passes: 1,
+			want: pipelineRows{
+				sessions:     []string{"1 04-10 10:00–04-10 10:05", "2 04-10 10:03–"},
+				commands:     []string{"1 04-10 10:01 /home", "2 04-10 10:04 /tp 0 64 0"},
+				advancements: []string{"1 04-10 10:02 Stone Age"},
+			},
+			wantStats: PipelineStats{Events: 6},
+		},
+		{
+			name: "повторный вход закрывает прежнюю сессию",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				joinEvent(steveID, 60),
+				testEvent(RuleLeave, steveID, 120),
+			},
+			passes: 1,
+			want: pipelineRows{
+				sessions: []string{"1 04-10 10:00–04-10 11:00", "1 04-10 11:00–04-10 12:00"},
+			},
+			wantStats: PipelineStats{Events: 3},
+		},
+		{
+			name: "команда без сессии пропускается",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				testEvent(RuleLeave, steveID, 1),
+				commandEvent(steveID, 2, "/home"),
+			},
+			passes: 1,
+			want: pipelineRows{
+				sessions: []string{"1 04-10 10:00–04-10 10:01"},
+			},
+			wantStats: PipelineStats{Events: 3},
+		},
+		{
+			name: "достижение записывается один раз",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				advancementEvent(steveID, 1, "Stone Age"),
+				advancementEvent(steveID, 2, "Stone Age"),
+			},
+			passes: 1,
+			want: pipelineRows{
+				sessions:     []string{"1 04-10 10:00–"},
+				advancements: []string{"1 04-10 10:01 Stone Age"},
+			},
+			wantStats: PipelineStats{Events: 3},
+		},
+		{
+			name: "повторная отправка пропускается по отпечаткам",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				commandEvent(steveID, 1, "/home"),
+				commandEvent(steveID, 1, "/home"), // то же событие, но другой отпечаток
+				testEvent(RuleLeave, steveID, 2),
+			},
+			passes: 2,
+			want: pipelineRows{
+				sessions: []string{"1 04-10 10:00–04-10 10:02"},
+				commands: []string{"1 04-10 10:01 /home", "1 04-10 10:01 /home"},
+			},
+			wantStats: PipelineStats{Events: 4, Duplicates: 4},
+		},
+		{
+			// Alex ни разу не входил, и достижение нарушает внешний ключ: батч откатывается
+			// и записывается по одному событию, остальные события не теряются
+			name: "ошибочное событие не блокирует батч",
+			events: []LogEvent{
+				joinEvent(steveID, 0),
+				advancementEvent(alexID, 1, "Stone Age"),
+				advancementEvent(steveID, 2, "Stone Age"),
+				testEvent(RuleLeave, steveID, 3),
+			},
+			passes: 1,
+			want: pipelineRows{
+				sessions:     []string{"1 04-10 10:00–04-10 10:03"},
+				advancements: []string{"1 04-10 10:02 Stone Age"},
+			},
+			wantStats: PipelineStats{Events: 3, Failed: 1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := openTestDB(t)
+			pipeline := newTestPipeline(t, db, NewParseDiagnostics(5, 1000), BackfillPipelineOptions(testConfig()))
+			for range tc.passes {
+				for i, event := range tc.events {
+					event.Fingerprint = strconv.Itoa(i)
+					if err := pipeline.Submit(event); err != nil {
+						t.Fatal(err)
+					}
+				}
+				// Flush сообщает о незаписанном событии
+				if err := pipeline.Flush(); (err != nil) != (tc.wantStats.Failed > 0) {
+					t.Fatalf("Flush: %v", err)
+				}
+			}
+
+			if got := readPipelineRows(t, db); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("записано:\n%+v\nwant:\n%+v", got, tc.want)
+			}
+			stats := pipeline.Stats()
+			if stats.Events != tc.wantStats.Events || stats.Duplicates != tc.wantStats.Duplicates || stats.Failed != tc.wantStats.Failed {
+				t.Errorf("статистика %+v, want %+v", stats, tc.wantStats)
+			}
+		})
+	}
+}
+
+// TestPipelineBackfillKeepsLiveSession — загрузка старого лога, пока у игрока открыта сессия
+// из живого лога: события старого лога создают и закрывают только свои сессии
+func TestPipelineBackfillKeepsLiveSession(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	parser, pipeline, db := newTestParser(t, cfg, BackfillPipelineOptions(cfg))
+
+	// Живой лог 11 апреля: Steve, сменивший ник, сейчас в игре
+	live := testJoin("12:00:00", "Steve", steveID)
+	clock := NewLogClock(time.Date(2026, 4, 11, 0, 0, 0, 0, time.UTC))
+	sourceID := SourceIdentity(live[0], clock.Start())
+	var offset int64
+	for _, line := range live {
+		if err := parser.ProcessLogLine(ctx, line, LineOrigin{SourceID: sourceID, Offset: offset, Clock: clock}); err != nil {
+			t.Fatal(err)
+		}
+		offset += int64(len(line)) + 1
+	}
+	if err := pipeline.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Архив за 10 апреля, где у Steve ещё старый ник
+	path := writeTestLog(t,
+		testJoin("10:00:00", "Steve_old", steveID),
+		[]string{
+			testCommand("10:01:00", "Steve_old", "/home"),
+			testLeave("11:00:00", "Steve_old"),
+			testCommand("11:30:00", "Steve_old", "/spawn"),
+		},
+	)
+	if err := parser.ProcessLogFile(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := pipelineRows{
+		sessions: []string{"1 04-10 10:00–04-10 11:00", "1 04-11 12:00–"},
+		commands: []string{"1 04-10 10:01 /home"},
+	}
+	if got := readPipelineRows(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("записано:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	var player models.Player
+	if err := db.First(&player, "id = ?", steveID).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantFirst, wantLast := time.Date(2026, 4, 10, 10, 0, 0, 0, time.UTC), time.Date(2026, 4, 11, 12, 0, 0, 0, time.UTC)
+	if player.Username != "Steve" || !player.FirstSeen.Equal(wantFirst) || !player.LastSeen.Equal(wantLast) {
+		t.Errorf("игрок %s %v–%v, want Steve %v–%v", player.Username, player.FirstSeen, player.LastSeen, wantFirst, wantLast)
+	}
+}
+
+// TestPipelineUnknownOrigin — без места в логе одинаковые строки не считаются повторами
+func TestPipelineUnknownOrigin(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	parser, pipeline, db := newTestParser(t, cfg, LivePipelineOptions(cfg))
+
+	lines := append(testJoin("10:00:00", "Steve", steveID),
+		testCommand("10:01:00", "Steve", "/home"),
+		testCommand("10:01:00", "Steve", "/home"),
+	)
+	for _, line := range lines {
+		if err := parser.ProcessLogLine(ctx, line, UnknownOrigin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pipeline.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows := readPipelineRows(t, db); len(rows.sessions) != 1 || len(rows.commands) != 2 {
+		t.Errorf("записано %+v, want одну сессию и две команды", rows)
+	}
+	if stats := pipeline.Stats(); stats.Duplicates != 0 {
+		t.Errorf("дубликатов %d, want 0", stats.Duplicates)
+	}
+}
+
+// reportLines сообщает пропускную способность в строках лога в секунду
+func reportLines(b *testing.B, lines int, elapsed time.Duration) {
+	b.ReportMetric(float64(lines*b.N)/elapsed.Seconds(), "lines/s")
+}
+
+// BenchmarkPipelineLive — отслеживание лога: строки поступают по одной, батчи небольшие
+func BenchmarkPipelineLive(b *testing.B) {
+	cfg := testConfig()
+	lines := benchmarkLog()
+	ctx := context.Background()
+
+	var elapsed time.Duration
+	for b.Loop() {
+		b.StopTimer()
+		parser, pipeline, _ := newTestParser(b, cfg, LivePipelineOptions(cfg))
+		clock := NewLogClock(time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC))
+		sourceID := SourceIdentity(lines[0], clock.Start())
+		b.StartTimer()
+
+		started := time.Now()
+		var offset int64
+		for _, line := range lines {
+			if err := parser.ProcessLogLine(ctx, line, LineOrigin{SourceID: sourceID, Offset: offset, Clock: clock}); err != nil {
+				b.Fatal(err)
+			}
+			offset += int64(len(line)) + 1
+		}
+		if err := pipeline.Flush(); err != nil {
+			b.Fatal(err)
+		}
+		elapsed += time.Since(started)
+
+		if stats := pipeline.Stats(); stats.Failed > 0 {
+			b.Fatalf("не записано событий: %d", stats.Failed)
+		}
+	}
+	reportLines(b, len(lines), elapsed)
+}
+
+// BenchmarkPipelineBackfill — разовая загрузка файла крупными батчами через ProcessLogFile
+func BenchmarkPipelineBackfill(b *testing.B) {
+	cfg := testConfig()
+	lines := benchmarkLog()
+	path := filepath.Join(b.TempDir(), "2026-04-10-1.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var elapsed time.Duration
+	for b.Loop() {
+		b.StopTimer()
+		parser, pipeline, _ := newTestParser(b, cfg, BackfillPipelineOptions(cfg))
+		b.StartTimer()
+
+		started := time.Now()
+		if err := parser.ProcessLogFile(ctx, path); err != nil {
+			b.Fatal(err)
+		}
+		elapsed += time.Since(started)
+
+		if stats := pipeline.Stats(); stats.Failed > 0 {
+			b.Fatalf("не записано событий: %d", stats.Failed)
+		}
+	}
+	reportLines(b, len(lines), elapsed)
+}
@@ -0,0 +1,228 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// APIPrefix — корень версии REST API
+const APIPrefix = "/api/v1"
+
+// APIOptions — параметры API, читаются при каждом запросе
+type APIOptions struct {
+	Tokens          []string // допустимые токены Bearer; пустой список закрывает API
+	Public          bool     // чтение без токена
+	DefaultPageSize int
+	MaxPageSize     int
+
+	StreamMaxConnections int // одновременных потоков событий
+
+	Servers  []ServerInfo
+	Location *time.Location // часовой пояс, в котором считаются дни истории
+}
+
+// ServerInfo — сервер Minecraft в ответе /servers
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Version string `json:"version"`
+	MapURL  string `json:"map_url,omitempty"`
+	// Вместимость сервера; 0 — не указана
+	MaxPlayers int `json:"max_players,omitempty"`
+}
+
+// API — REST API только для чтения: игроки, сессии, команды и достижения
+type API struct {
+	players      service.PlayerService
+	commands     service.CommandService
+	advancements service.AdvancementService
+	stats        service.StatsService
+	exports      service.ExportService
+	feed         *events.Feed
+	options      func() APIOptions
+
+	streams atomic.Int64    // открытых потоков событий
+	closing <-chan struct{} // закрывается при остановке сервера, чтобы завершить потоки
+}
+
+func NewAPI(
+	players service.PlayerService,
+	commands service.CommandService,
+	advancements service.AdvancementService,
+	stats service.StatsService,
+	exports service.ExportService,
+	feed *events.Feed,
+	options func() APIOptions,
+) *API {
+	return &API{
+		players:      players,
+		commands:     commands,
+		advancements: advancements,
+		stats:        stats,
+		exports:      exports,
+		feed:         feed,
+		options:      options,
+	}
+}
+
+// apiError — ошибка, которую клиент получает в теле ответа
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func badRequest(format string, args ...any) *apiError {
+	return &apiError{Status: http.StatusBadRequest, Code: "bad_request", Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...any) *apiError {
+	return &apiError{Status: http.StatusNotFound, Code: "not_found", Message: fmt.Sprintf(format, args...)}
+}
+
+// apiHandler возвращает тело успешного ответа или ошибку
+type apiHandler func(r *http.Request) (any, error)
+
+// handle регистрирует обработчик с проверкой токена и единым форматом ошибок
+func (a *API) handle(server *Server, pattern string, handler apiHandler) {
+	server.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if err := a.authorize(r, false); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, err)
+			return
+		}
+
+		body, err := handler(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, body)
+	}))
+}
+
+// authorize сравнивает токен из заголовка Authorization с настроенными; в открытом
+// режиме токен не нужен. allowQuery разрешает передать токен в параметре access_token —
+// только для потоков, которые браузер открывает без своих заголовков.
+func (a *API) authorize(r *http.Request, allowQuery bool) *apiError {
+	options := a.options()
+	if options.Public {
+		return nil
+	}
+	unauthorized := &apiError{Status: http.StatusUnauthorized, Code: "unauthorized"}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && allowQuery {
+		token = r.URL.Query().Get("access_token")
+		ok = true
+	}
+	if !ok || token == "" {
+		unauthorized.Message = "нужен заголовок Authorization: Bearer <токен>"
+		return unauthorized
+	}
+	for _, allowed := range options.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			return nil
+		}
+	}
+	unauthorized.Message = "неверный токен"
+	return unauthorized
+}
+
+// writeError отправляет ошибку в виде {"error": {"code": ..., "message": ...}}.
+// Внутренние ошибки пишутся в лог, а клиент получает только общий код.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		logging.Errorf("Ошибка при обработке запроса API: %v", err)
+		apiErr = &apiError{Status: http.StatusInternalServerError, Code: "internal", Message: "внутренняя ошибка"}
+	}
+	writeJSON(w, apiErr.Status, struct {
+		Error *apiError `json:"error"`
+	}{apiErr})
+}
+
+// page — страница списка; next_cursor передаётся в параметре cursor для следующей страницы
+type page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newPage строит страницу из выборки, запрошенной с limit+1 записями:
+// лишняя запись означает, что есть следующая страница
+func newPage[T, V any](rows []T, limit int, key func(T) any, view func(T) V) page {
+	var result page
+	if len(rows) > limit {
+		rows = rows[:limit]
+		result.NextCursor = encodeCursor(key(rows[len(rows)-1]))
+	}
+	items := make([]V, len(rows))
+	for i, row := range rows {
+		items[i] = view(row)
+	}
+	result.Items = items
+	return result
+}
+
+// encodeCursor упаковывает ключ последней записи страницы в непрозрачную строку
+func encodeCursor(key any) string {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor читает курсор из параметра запроса в key; false, если курсора нет
+func decodeCursor(r *http.Request, key any) (bool, error) {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		return false, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || json.Unmarshal(data, key) != nil {
+		return false, badRequest("некорректный курсор")
+	}
+	return true, nil
+}
+
+// pageLimit читает размер страницы; значения больше максимума уменьшаются до него
+func (a *API) pageLimit(r *http.Request) (int, error) {
+	options := a.options()
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return options.DefaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, badRequest("limit должен быть положительным числом")
+	}
+	return min(limit, options.MaxPageSize), nil
+}
+
+// timeParam читает время в формате RFC 3339; нулевое, если параметра нет
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, badRequest("%s: ожидается время в формате RFC 3339, например 2024-05-01T00:00:00Z", name)
+	}
+	return parsed.UTC(), nil
+}
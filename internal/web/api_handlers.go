@@ -0,0 +1,476 @@
+package web
+
+import (
+	"mine-parser/internal/models"
+	"mine-parser/internal/repo"
+	"mine-parser/internal/service"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Register подключает маршруты API к серверу.
+// Игрок в пути и в параметре player задаётся UUID или username.
+func (a *API) Register(server *Server) {
+	a.closing = server.Closing()
+	server.Handle("GET "+APIPrefix+"/events/stream", http.HandlerFunc(a.streamEvents))
+	a.handle(server, "GET "+APIPrefix+"/players", a.listPlayers)
+	a.handle(server, "GET "+APIPrefix+"/players/{player}", a.playerStats)
+	a.handle(server, "GET "+APIPrefix+"/players/{player}/sessions", a.listSessions)
+	a.handle(server, "GET "+APIPrefix+"/players/{player}/commands", a.listCommands)
+	a.handle(server, "GET "+APIPrefix+"/players/{player}/advancements", a.listAdvancements)
+	a.handle(server, "GET "+APIPrefix+"/sessions", a.listSessions)
+	a.handle(server, "GET "+APIPrefix+"/commands", a.listCommands)
+	a.handle(server, "GET "+APIPrefix+"/commands/top", a.topCommands)
+	a.handle(server, "GET "+APIPrefix+"/advancements", a.listAdvancements)
+	a.handle(server, "GET "+APIPrefix+"/online", a.listOnline)
+	a.handle(server, "GET "+APIPrefix+"/servers", a.listServers)
+	a.handle(server, "GET "+APIPrefix+"/leaderboards/{metric}", a.leaderboard)
+	a.handle(server, "GET "+APIPrefix+"/history", a.serverHistory)
+	server.Handle("GET "+APIPrefix+"/export/{table}", http.HandlerFunc(a.exportTable))
+	a.handle(server, APIPrefix+"/", func(r *http.Request) (any, error) {
+		return nil, notFound("нет метода API %s %s", r.Method, r.URL.Path)
+	})
+}
+
+// Представления моделей в ответах API. IP-адреса и служебные поля наружу не отдаются.
+
+type playerView struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Online    bool      `json:"online"`
+}
+
+type playerStatsView struct {
+	playerView
+	PlayTimeSeconds int64             `json:"play_time_seconds"`
+	Sessions        int               `json:"sessions"`
+	Commands        int64             `json:"commands"`
+	Advancements    []advancementView `json:"advancements"`
+}
+
+type sessionView struct {
+	ID              uint       `json:"id"`
+	PlayerID        string     `json:"player_id"`
+	Server          string     `json:"server"`
+	JoinTime        time.Time  `json:"join_time"`
+	LeaveTime       *time.Time `json:"leave_time"` // null, пока сессия открыта
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+type commandView struct {
+	ID        uint      `json:"id"`
+	PlayerID  string    `json:"player_id"`
+	SessionID uint      `json:"session_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Name      string    `json:"name"`
+	Command   string    `json:"command"`
+}
+
+type advancementView struct {
+	ID        uint      `json:"id"`
+	PlayerID  string    `json:"player_id"`
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type onlineView struct {
+	PlayerID  string    `json:"player_id"`
+	Username  string    `json:"username"`
+	Server    string    `json:"server"`
+	SessionID uint      `json:"session_id"`
+	Since     time.Time `json:"since"`
+}
+
+type commandUsageView struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type leaderboardView struct {
+	Metric string                 `json:"metric"`
+	Items  []leaderboardEntryView `json:"items"`
+}
+
+type leaderboardEntryView struct {
+	Rank   int        `json:"rank"`
+	Player playerView `json:"player"`
+	Value  int64      `json:"value"` // секунды для playtime и longest, иначе количество
+}
+
+type historyView struct {
+	Server   string           `json:"server,omitempty"`
+	Timezone string           `json:"timezone"`
+	Days     []historyDayView `json:"days"`
+}
+
+type historyDayView struct {
+	Date            string `json:"date"` // YYYY-MM-DD в часовом поясе timezone
+	Players         int    `json:"players"`
+	Sessions        int    `json:"sessions"`
+	PlayTimeSeconds int64  `json:"play_time_seconds"`
+	PeakOnline      int    `json:"peak_online"`
+}
+
+func (a *API) viewPlayer(player models.Player) playerView {
+	return playerView{
+		ID:        player.ID,
+		Username:  player.Username,
+		FirstSeen: player.FirstSeen.UTC(),
+		LastSeen:  player.LastSeen.UTC(),
+		Online:    a.players.IsPlayerOnline(player.ID),
+	}
+}
+
+func viewSession(session models.Session) sessionView {
+	view := sessionView{
+		ID:       session.ID,
+		PlayerID: session.PlayerID,
+		Server:   session.Server,
+		JoinTime: session.JoinTime.UTC(),
+	}
+	end := time.Now()
+	if session.LeaveTime != nil {
+		leave := session.LeaveTime.UTC()
+		view.LeaveTime = &leave
+		end = leave
+	}
+	view.DurationSeconds = int64(end.Sub(session.JoinTime).Seconds())
+	return view
+}
+
+func viewCommand(command models.Command) commandView {
+	return commandView{
+		ID:        command.ID,
+		PlayerID:  command.Session.PlayerID,
+		SessionID: command.SessionID,
+		Timestamp: command.Timestamp.UTC(),
+		Name:      command.CommandName,
+		Command:   command.Command,
+	}
+}
+
+func viewAdvancement(advancement models.Advancement) advancementView {
+	return advancementView{
+		ID:        advancement.ID,
+		PlayerID:  advancement.PlayerID,
+		Name:      advancement.AdvancementName,
+		Timestamp: advancement.Timestamp.UTC(),
+	}
+}
+
+func sessionKey(session models.Session) any {
+	return repo.TimeKey{Time: session.JoinTime, ID: session.ID}
+}
+
+func commandKey(command models.Command) any {
+	return repo.TimeKey{Time: command.Timestamp, ID: command.ID}
+}
+
+func advancementKey(advancement models.Advancement) any {
+	return repo.TimeKey{Time: advancement.Timestamp, ID: advancement.ID}
+}
+
+func playerKey(player models.Player) any {
+	return repo.PlayerKey{Username: player.Username, ID: player.ID}
+}
+
+// GET /players?q=&limit=&cursor= — игроки по алфавиту, q ищет подстроку в username
+func (a *API) listPlayers(r *http.Request) (any, error) {
+	filter := repo.PlayerFilter{Query: r.URL.Query().Get("q")}
+	var err error
+	if filter.Limit, err = a.pageLimit(r); err != nil {
+		return nil, err
+	}
+	var after repo.PlayerKey
+	if ok, err := decodeCursor(r, &after); err != nil {
+		return nil, err
+	} else if ok {
+		filter.After = &after
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	players, err := a.players.SearchPlayers(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(players, limit, playerKey, a.viewPlayer), nil
+}
+
+// GET /players/{player} — сводная статистика игрока
+func (a *API) playerStats(r *http.Request) (any, error) {
+	playerID, err := a.playerParam(r)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := a.players.GetPlayerStats(r.Context(), playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := playerStatsView{
+		playerView:      a.viewPlayer(stats.Player),
+		PlayTimeSeconds: int64(stats.TotalPlayTime.Seconds()),
+		Sessions:        stats.SessionCount,
+		Commands:        stats.CommandsUsed,
+		Advancements:    make([]advancementView, len(stats.Advancements)),
+	}
+	for i, advancement := range stats.Advancements {
+		view.Advancements[i] = viewAdvancement(advancement)
+	}
+	return view, nil
+}
+
+// GET /sessions и /players/{player}/sessions?from=&to= — сессии, пересекающиеся с интервалом
+func (a *API) listSessions(r *http.Request) (any, error) {
+	var filter repo.SessionFilter
+	var err error
+	if filter.PlayerID, err = a.playerParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Period, err = periodParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = a.pageLimit(r); err != nil {
+		return nil, err
+	}
+	if filter.After, err = timeCursor(r); err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	sessions, err := a.players.ListSessions(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(sessions, limit, sessionKey, viewSession), nil
+}
+
+// GET /commands и /players/{player}/commands?name=&from=&to= — история команд
+func (a *API) listCommands(r *http.Request) (any, error) {
+	var filter repo.CommandFilter
+	// Имена команд хранятся со слешем; принимаем и «tp», и «/tp»
+	if name := r.URL.Query().Get("name"); name != "" {
+		filter.Name = "/" + strings.TrimPrefix(name, "/")
+	}
+	var err error
+	if filter.PlayerID, err = a.playerParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Period, err = periodParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = a.pageLimit(r); err != nil {
+		return nil, err
+	}
+	if filter.After, err = timeCursor(r); err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	commands, err := a.commands.ListCommands(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(commands, limit, commandKey, viewCommand), nil
+}
+
+// GET /advancements и /players/{player}/advancements?from=&to= — полученные достижения
+func (a *API) listAdvancements(r *http.Request) (any, error) {
+	var filter repo.AdvancementFilter
+	var err error
+	if filter.PlayerID, err = a.playerParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Period, err = periodParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = a.pageLimit(r); err != nil {
+		return nil, err
+	}
+	if filter.After, err = timeCursor(r); err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	advancements, err := a.advancements.ListAdvancements(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(advancements, limit, advancementKey, viewAdvancement), nil
+}
+
+// GET /commands/top?limit= — самые используемые команды
+func (a *API) topCommands(r *http.Request) (any, error) {
+	limit, err := a.pageLimit(r)
+	if err != nil {
+		return nil, err
+	}
+	usages, err := a.commands.GetMostUsedCommands(r.Context(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]commandUsageView, len(usages))
+	for i, usage := range usages {
+		items[i] = commandUsageView{Name: usage.CommandName, Count: usage.Count}
+	}
+	return page{Items: items}, nil
+}
+
+// GET /online — игроки на сервере сейчас
+func (a *API) listOnline(*http.Request) (any, error) {
+	online := a.players.ListOnlinePlayers()
+	items := make([]onlineView, len(online))
+	for i, presence := range online {
+		items[i] = viewOnline(presence)
+	}
+	return page{Items: items}, nil
+}
+
+// GET /servers — серверы и адреса для подключения
+func (a *API) listServers(*http.Request) (any, error) {
+	servers := a.options().Servers
+	if servers == nil {
+		servers = []ServerInfo{}
+	}
+	return page{Items: servers}, nil
+}
+
+// GET /leaderboards/{metric}?from=&to=&server=&limit= — лучшие игроки по показателю:
+// playtime (секунды), sessions, commands, advancements или longest (самая долгая сессия, секунды)
+func (a *API) leaderboard(r *http.Request) (any, error) {
+	metric := r.PathValue("metric")
+	if !slices.Contains(service.LeaderboardMetrics, metric) {
+		return nil, notFound("нет рейтинга %q, доступны: %s", metric, strings.Join(service.LeaderboardMetrics, ", "))
+	}
+	filter := repo.RankFilter{Server: r.URL.Query().Get("server")}
+	var err error
+	if filter.Period, err = periodParam(r); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = a.pageLimit(r); err != nil {
+		return nil, err
+	}
+
+	entries, err := a.stats.Leaderboard(r.Context(), metric, filter)
+	if err != nil {
+		return nil, err
+	}
+	view := leaderboardView{Metric: metric, Items: make([]leaderboardEntryView, len(entries))}
+	for i, entry := range entries {
+		view.Items[i] = leaderboardEntryView{Rank: entry.Rank, Player: a.viewPlayer(entry.Player), Value: entry.Value}
+	}
+	return view, nil
+}
+
+// История считается по дням; без from выдаются последние defaultHistoryDays дней
+const (
+	defaultHistoryDays = 30
+	maxHistoryDays     = 366
+)
+
+// GET /history?from=&to=&server= — активность по дням: игроки, сессии, время в игре, пик онлайна
+func (a *API) serverHistory(r *http.Request) (any, error) {
+	period, err := periodParam(r)
+	if err != nil {
+		return nil, err
+	}
+	if period.To.IsZero() {
+		period.To = time.Now().UTC()
+	}
+	if period.From.IsZero() {
+		period.From = period.To.AddDate(0, 0, -defaultHistoryDays)
+	}
+	if !period.From.Before(period.To) {
+		return nil, badRequest("from должен быть раньше to")
+	}
+	if period.To.Sub(period.From) > maxHistoryDays*24*time.Hour {
+		return nil, badRequest("история выдаётся не больше чем за %d дней", maxHistoryDays)
+	}
+
+	location := a.options().Location
+	if location == nil {
+		location = time.UTC
+	}
+	server := r.URL.Query().Get("server")
+	days, err := a.stats.History(r.Context(), server, period, location)
+	if err != nil {
+		return nil, err
+	}
+
+	view := historyView{Server: server, Timezone: location.String(), Days: make([]historyDayView, len(days))}
+	for i, day := range days {
+		view.Days[i] = historyDayView{
+			Date:            day.Date.Format(time.DateOnly),
+			Players:         day.Players,
+			Sessions:        day.Sessions,
+			PlayTimeSeconds: int64(day.PlayTime.Seconds()),
+			PeakOnline:      day.PeakOnline,
+		}
+	}
+	return view, nil
+}
+
+func viewOnline(presence service.Presence) onlineView {
+	return onlineView{
+		PlayerID:  presence.PlayerID,
+		Username:  presence.Username,
+		Server:    presence.Server,
+		SessionID: presence.SessionID,
+		Since:     presence.Since.UTC(),
+	}
+}
+
+// playerParam возвращает UUID игрока из пути или параметра player; пусто, если игрок не задан
+func (a *API) playerParam(r *http.Request) (string, error) {
+	ref := r.PathValue("player")
+	if ref == "" {
+		ref = r.URL.Query().Get("player")
+	}
+	if ref == "" {
+		return "", nil
+	}
+
+	player, err := a.players.FindPlayer(r.Context(), ref)
+	if err != nil {
+		return "", err
+	}
+	if player == nil {
+		return "", notFound("игрок %s не найден", ref)
+	}
+	return player.ID, nil
+}
+
+// periodParam читает интервал из параметров from и to
+func periodParam(r *http.Request) (repo.TimeRange, error) {
+	var period repo.TimeRange
+	var err error
+	if period.From, err = timeParam(r, "from"); err != nil {
+		return period, err
+	}
+	if period.To, err = timeParam(r, "to"); err != nil {
+		return period, err
+	}
+	if !period.From.IsZero() && !period.To.IsZero() && !period.From.Before(period.To) {
+		return period, badRequest("from должен быть раньше to")
+	}
+	return period, nil
+}
+
+// timeCursor читает курсор списка, упорядоченного по времени
+func timeCursor(r *http.Request) (*repo.TimeKey, error) {
+	var after repo.TimeKey
+	ok, err := decodeCursor(r, &after)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &after, nil
+}
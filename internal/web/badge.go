@@ -0,0 +1,209 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mine-parser/internal/badge"
+	"mine-parser/internal/logging"
+	"mine-parser/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BadgeOptions — параметры значка и карточки, читаются при каждом запросе
+type BadgeOptions struct {
+	Enabled     bool
+	Style       badge.Style
+	ShowPlayers bool
+	Hidden      []string // ники или UUID игроков, которых не показывают и не считают
+	MaxAge      time.Duration
+
+	Servers       []ServerInfo
+	DefaultServer string // сервер, если параметр server не указан
+}
+
+// Badges отдаёт значок /badge.svg и карточку /card.png без токена: их встраивают
+// на форумы и в README, где заголовки запроса не задать
+type Badges struct {
+	status  service.ServerStatusService
+	options func() BadgeOptions
+}
+
+func NewBadges(status service.ServerStatusService, options func() BadgeOptions) *Badges {
+	return &Badges{status: status, options: options}
+}
+
+// svgPolicy не даёт выполнить скрипты, если SVG открыт напрямую
+const svgPolicy = "default-src 'none'; style-src 'unsafe-inline'"
+
+// Register добавляет маршруты значка и карточки
+func (b *Badges) Register(server *Server) {
+	server.Handle("GET /badge.svg", b.handler("image/svg+xml; charset=utf-8", badge.SVG))
+	server.Handle("GET /card.png", b.handler("image/png", badge.PNG))
+}
+
+// handler рисует картинку render. Параметры запроса переопределяют оформление:
+// server, label, color, label_color, height, width, bg, text и names=0 (скрыть ники).
+func (b *Badges) handler(contentType string, render func(badge.Status, badge.Style) ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := b.options()
+		if !options.Enabled {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		name := query.Get("server")
+		if name == "" {
+			name = options.DefaultServer
+		}
+		info, ok := findServer(options.Servers, name)
+		if !ok && name == options.DefaultServer {
+			// Сервер, с которого читается лог, не обязан быть в списке servers
+			info, ok = ServerInfo{Name: name}, true
+		}
+		if !ok {
+			http.Error(w, fmt.Sprintf("неизвестный сервер %q", name), http.StatusNotFound)
+			return
+		}
+		style, showPlayers, err := badgeStyle(options, query.Get)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		current, err := b.status.Status(r.Context(), info.Name)
+		if err != nil {
+			logging.Errorf("Не удалось получить состояние сервера %s: %v", info.Name, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		status := badge.Status{
+			Server:     info.Name,
+			Running:    current.Running(),
+			MaxPlayers: info.MaxPlayers,
+			Version:    info.Version,
+			// С точностью до минуты: иначе картинка и ETag менялись бы каждую секунду
+			Uptime: current.Uptime(time.Now()).Truncate(time.Minute),
+		}
+		for _, presence := range current.Online {
+			// После остановки сервера сессии могут остаться открытыми до следующего запуска
+			if !status.Running || isHidden(options.Hidden, presence) {
+				continue
+			}
+			status.Online++
+			if showPlayers {
+				status.Players = append(status.Players, presence.Username)
+			}
+		}
+
+		body, err := render(status, style)
+		if err != nil {
+			logging.Errorf("Не удалось нарисовать значок сервера %s: %v", info.Name, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		header := w.Header()
+		header.Set("ETag", etag)
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(options.MaxAge.Seconds())))
+		header.Set("X-Content-Type-Options", "nosniff")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		if contentType != "image/png" {
+			header.Set("Content-Security-Policy", svgPolicy)
+		}
+		if _, err := w.Write(body); err != nil {
+			logging.Debugf("Значок не отправлен: %v", err)
+		}
+	})
+}
+
+// badgeStyle накладывает параметры запроса на настроенное оформление
+func badgeStyle(options BadgeOptions, param func(string) string) (badge.Style, bool, error) {
+	style := options.Style
+	colors := []struct {
+		name   string
+		target *string
+	}{
+		{"color", &style.OnlineColor},
+		{"label_color", &style.LabelColor},
+		{"bg", &style.CardBackground},
+		{"text", &style.CardText},
+	}
+	for _, c := range colors {
+		value := param(c.name)
+		if value == "" {
+			continue
+		}
+		if !badge.ValidColor(value) {
+			return style, false, fmt.Errorf("параметр %s: цвет %q должен быть в виде rgb или rrggbb", c.name, value)
+		}
+		*c.target = value
+	}
+	if label := param("label"); label != "" {
+		style.Label = label
+	}
+
+	sizes := []struct {
+		name     string
+		target   *int
+		min, max int
+	}{
+		{"height", &style.Height, badge.MinHeight, badge.MaxHeight},
+		{"width", &style.CardWidth, badge.MinCardWidth, badge.MaxCardWidth},
+	}
+	for _, s := range sizes {
+		value := param(s.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < s.min || parsed > s.max {
+			return style, false, fmt.Errorf("параметр %s должен быть числом от %d до %d", s.name, s.min, s.max)
+		}
+		*s.target = parsed
+	}
+
+	showPlayers := options.ShowPlayers && param("names") != "0"
+	return style, showPlayers, nil
+}
+
+func findServer(servers []ServerInfo, name string) (ServerInfo, bool) {
+	for _, server := range servers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return ServerInfo{}, false
+}
+
+// isHidden проверяет, указан ли игрок в списке скрытых по нику (без учёта регистра) или UUID
+func isHidden(hidden []string, presence service.Presence) bool {
+	for _, entry := range hidden {
+		if strings.EqualFold(entry, presence.Username) || strings.EqualFold(entry, presence.PlayerID) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches проверяет заголовок If-None-Match: список тегов через запятую или *
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
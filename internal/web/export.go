@@ -0,0 +1,60 @@
+package web
+
+import (
+	"mine-parser/internal/export"
+	"mine-parser/internal/logging"
+	"net/http"
+)
+
+// GET /export/{table}?format=&from=&to=&player=&server= — выгрузка сессий, команд или
+// достижений файлом. Записи пишутся в ответ по мере чтения из БД.
+func (a *API) exportTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := a.authorize(r, false); err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		writeError(w, err)
+		return
+	}
+
+	table, format, filter, err := a.exportParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+format.FileName(table)+`"`)
+	count, err := a.exports.Export(r.Context(), w, table, format, filter)
+	if err != nil {
+		if r.Context().Err() == nil {
+			logging.Errorf("Выгрузка %s прервана после %d записей: %v", table, count, err)
+		}
+		// Статус уже отправлен: обрываем соединение, чтобы клиент не принял
+		// недописанный файл за целый
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (a *API) exportParams(r *http.Request) (export.Table, export.Format, export.Filter, error) {
+	var filter export.Filter
+	table, err := export.ParseTable(r.PathValue("table"))
+	if err != nil {
+		return "", "", filter, notFound("%v", err)
+	}
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = string(export.FormatJSONL)
+	}
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		return "", "", filter, badRequest("%v", err)
+	}
+	if filter.PlayerID, err = a.playerParam(r); err != nil {
+		return "", "", filter, err
+	}
+	if filter.Period, err = periodParam(r); err != nil {
+		return "", "", filter, err
+	}
+	filter.Server = r.URL.Query().Get("server")
+	return table, format, filter, nil
+}
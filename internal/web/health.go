@@ -0,0 +1,30 @@
+package web
+
+import (
+	"mine-parser/internal/health"
+	"net/http"
+)
+
+// StatusCodes — HTTP-коды для состояний проверок
+type StatusCodes struct {
+	Degraded int
+	Failed   int
+}
+
+// HealthHandler отвечает сводкой проверок: живости (/healthz), если livenessOnly,
+// иначе готовности (/readyz). Код ответа зависит от общего состояния.
+func HealthHandler(registry *health.Registry, livenessOnly bool, codes func() StatusCodes) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := registry.Run(r.Context(), livenessOnly)
+
+		status := http.StatusOK
+		switch report.Status {
+		case health.StatusDegraded:
+			status = codes().Degraded
+		case health.StatusFailed:
+			status = codes().Failed
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, status, report)
+	})
+}
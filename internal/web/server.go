@@ -0,0 +1,93 @@
+// Package web — HTTP-сервер приложения: проверки состояния, REST API и служебные эндпоинты
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mine-parser/internal/logging"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Server — HTTP-сервер с общим маршрутизатором
+type Server struct {
+	mux     *http.ServeMux
+	server  *http.Server
+	done    chan struct{}
+	closing chan struct{}
+}
+
+func NewServer(addr string) *Server {
+	mux := http.NewServeMux()
+	s := &Server{
+		mux: mux,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+		done:    make(chan struct{}),
+		closing: make(chan struct{}),
+	}
+	// Shutdown ждёт завершения запросов, поэтому долгие соединения должны закрыться сами
+	s.server.RegisterOnShutdown(func() { close(s.closing) })
+	return s
+}
+
+// Closing закрывается в начале остановки сервера; по нему завершаются потоковые ответы
+func (s *Server) Closing() <-chan struct{} {
+	return s.closing
+}
+
+// Handle регистрирует обработчик; шаблоны — как у http.ServeMux ("GET /healthz")
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start занимает порт и обслуживает запросы в фоне.
+// Ошибка занятого порта возвращается сразу, а не теряется в горутине.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("HTTP-сервер слушает %s", listener.Addr())
+
+	go func() {
+		defer close(s.done)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Errorf("Ошибка HTTP-сервера: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown перестаёт принимать соединения и дожидается текущих запросов
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		logging.Errorf("Ошибка при остановке HTTP-сервера: %v", err)
+	}
+	<-s.done
+}
+
+// writeJSON отправляет value в формате JSON с кодом status
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		logging.Errorf("Ошибка при отправке ответа: %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mine-parser/internal/events"
+	"mine-parser/internal/logging"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamHeartbeat    = 25 * time.Second // комментарий SSE или ping WebSocket, чтобы прокси не закрывали соединение
+	streamWriteTimeout = 10 * time.Second
+	streamBuffer       = 256             // событий в очереди клиента; при переполнении клиент отключается
+	sseRetry           = 3 * time.Second // через сколько браузер переподключается
+)
+
+var upgrader = websocket.Upgrader{
+	// Доступ проверяется по токену, а не по cookie, поэтому страницы с других доменов не опасны
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// streamMessage — событие в потоке; поток может быть публичным, поэтому событие
+// отдаётся в публичном представлении, как модели в ответах API
+type streamMessage struct {
+	ID      string        `json:"id"`
+	Type    events.Type   `json:"type"`
+	Payload events.Public `json:"payload"`
+}
+
+// streamEvents отдаёт доменные события по мере их появления: через WebSocket, если клиент
+// запросил Upgrade, иначе как Server-Sent Events.
+//
+// Параметры: types (через запятую), server, player (UUID или ник), last_event_id.
+// Браузерные EventSource и WebSocket не умеют передавать заголовки, поэтому токен можно
+// указать в параметре access_token, а SSE при переподключении сам отправляет Last-Event-ID.
+func (a *API) streamEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := a.authorize(r, true); err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		writeError(w, err)
+		return
+	}
+
+	filter, err := streamFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Счётчик увеличивается до проверки, чтобы одновременные подключения не проскочили лимит
+	if limit := a.options().StreamMaxConnections; int(a.streams.Add(1)) > limit {
+		a.streams.Add(-1)
+		w.Header().Set("Retry-After", "5")
+		writeError(w, &apiError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "too_many_connections",
+			Message: fmt.Sprintf("открыто максимальное число потоков (%d)", limit),
+		})
+		return
+	}
+	defer a.streams.Add(-1)
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	listener, replay, err := a.feed.Listen(lastID, filter, streamBuffer)
+	if err != nil {
+		writeError(w, badRequest("%v", err))
+		return
+	}
+	defer listener.Close()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		a.serveWebSocket(w, r, listener, replay)
+	} else {
+		a.serveSSE(w, r, listener, replay)
+	}
+}
+
+func (a *API) serveSSE(w http.ResponseWriter, r *http.Request, listener *events.Listener, replay []events.Record) {
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не должен копить ответ
+	w.WriteHeader(http.StatusOK)
+
+	send := func(record events.Record) error {
+		data, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", record.ID, record.Event.EventType(), data)
+		return err
+	}
+	flush := func() bool {
+		_ = controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return controller.Flush() == nil
+	}
+
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	for _, record := range replay {
+		if send(record) != nil {
+			return
+		}
+	}
+	if !flush() {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.closing:
+			return
+		case <-listener.Done():
+			return
+		case record := <-listener.C():
+			if send(record) != nil || !flush() {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || !flush() {
+				return
+			}
+		}
+	}
+}
+
+func (a *API) serveWebSocket(w http.ResponseWriter, r *http.Request, listener *events.Listener, replay []events.Record) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader уже ответил клиенту ошибкой
+		return
+	}
+	defer conn.Close()
+
+	// Клиент ничего не отправляет; чтение нужно, чтобы обрабатывать ping/close и заметить обрыв
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(record events.Record) error {
+		data, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+	for _, record := range replay {
+		if send(record) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-a.closing:
+			closeWebSocket(conn, websocket.CloseGoingAway, "сервер останавливается")
+			return
+		case <-listener.Done():
+			closeWebSocket(conn, websocket.CloseTryAgainLater, "клиент не успевает получать события")
+			return
+		case record := <-listener.C():
+			if err := send(record); err != nil {
+				logging.Debugf("Поток событий WebSocket закрыт: %v", err)
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// encodeRecord сериализует событие в одну строку JSON (SSE не допускает переводов строк в data)
+func encodeRecord(record events.Record) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(streamMessage{ID: record.ID, Type: record.Event.EventType(), Payload: events.PublicOf(record.Event)}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteTimeout))
+}
+
+// streamFilter строит отбор событий по параметрам types, server и player
+func streamFilter(r *http.Request) (func(events.Event) bool, error) {
+	query := r.URL.Query()
+
+	var types []events.Type
+	for _, name := range strings.Split(query.Get("types"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !slices.Contains(events.AllTypes, events.Type(name)) {
+			return nil, badRequest("неизвестный тип события %q", name)
+		}
+		types = append(types, events.Type(name))
+	}
+	server := query.Get("server")
+	player := query.Get("player")
+
+	return func(event events.Event) bool {
+		if len(types) > 0 && !slices.Contains(types, event.EventType()) {
+			return false
+		}
+		subject := events.SubjectOf(event)
+		if server != "" && subject.Server != server {
+			return false
+		}
+		if player != "" && subject.PlayerID != player && !strings.EqualFold(subject.Username, player) {
+			return false
+		}
+		return true
+	}, nil
+}
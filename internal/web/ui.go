@@ -0,0 +1,45 @@
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Веб-интерфейс — статические файлы без сборки и внешних зависимостей.
+// Все данные он получает из REST API, поэтому доступ к ним определяют настройки API.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiPolicy запрещает загрузку чего-либо, кроме файлов самого интерфейса и запросов к API
+const uiPolicy = "default-src 'self'; img-src 'self' data:; style-src 'self'; script-src 'self'; " +
+	"connect-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+
+// UIHandler отдаёт встроенный веб-интерфейс; enabled проверяется при каждом запросе.
+// Регистрируется на "/" без метода: шаблон "GET /" конфликтует с "/api/v1/".
+func UIHandler(enabled func() bool) http.Handler {
+	root, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // каталог встроен при сборке и есть всегда
+	}
+	files := http.FileServerFS(root)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !enabled() {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		// Файлы меняются вместе с бинарником, поэтому браузер перепроверяет их при каждой загрузке
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Content-Security-Policy", uiPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		files.ServeHTTP(w, r)
+	})
+}
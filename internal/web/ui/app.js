@@ -0,0 +1,397 @@
+'use strict';
+
+// Веб-интерфейс сервера: одна страница, маршруты в hash, данные из /api/v1.
+// Разметка строится через DOM, без innerHTML: ники и сообщения приходят от игроков.
+
+const API = '/api/v1';
+const TOKEN_KEY = 'mclog.token';
+const PAGE_SIZE = 20;
+
+const view = document.getElementById('view');
+let renderSeq = 0; // номер текущей отрисовки: ответы устаревших запросов отбрасываются
+let live = null;   // поток событий открытой страницы
+
+class Unauthorized extends Error {}
+
+// --- API ---
+
+async function api(path, params = {}) {
+  const url = new URL(API + path, location.origin);
+  for (const [key, value] of Object.entries(params)) {
+    if (value !== undefined && value !== null && value !== '') url.searchParams.set(key, value);
+  }
+  const headers = {};
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) headers.Authorization = 'Bearer ' + token;
+
+  const response = await fetch(url, { headers });
+  if (response.status === 401) throw new Unauthorized();
+  const body = await response.json().catch(() => null);
+  if (!response.ok) {
+    throw new Error(body && body.error ? body.error.message : 'Ошибка ' + response.status);
+  }
+  return body;
+}
+
+function openStream(types, onEvent) {
+  const url = new URL(API + '/events/stream', location.origin);
+  url.searchParams.set('types', types.join(','));
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) url.searchParams.set('access_token', token);
+
+  const source = new EventSource(url);
+  for (const type of types) {
+    source.addEventListener(type, (e) => onEvent(JSON.parse(e.data)));
+  }
+  return source;
+}
+
+// --- Разметка ---
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs)) {
+    if (value === undefined || value === null || value === false) continue;
+    if (key.startsWith('on')) node.addEventListener(key.slice(2), value);
+    else if (key === 'class') node.className = value;
+    else node.setAttribute(key, value === true ? '' : value);
+  }
+  for (const child of children.flat()) {
+    if (child === undefined || child === null || child === false) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+function svg(tag, attrs = {}, ...children) {
+  const node = document.createElementNS('http://www.w3.org/2000/svg', tag);
+  for (const [key, value] of Object.entries(attrs)) node.setAttribute(key, value);
+  for (const child of children.flat()) node.append(child instanceof Node ? child : String(child));
+  return node;
+}
+
+function card(title, ...children) {
+  return el('section', { class: 'card' }, title ? el('h2', {}, title) : null, ...children);
+}
+
+function table(headers, rows, empty = 'Нет данных') {
+  if (rows.length === 0) return el('p', { class: 'muted' }, empty);
+  return el('table', {}, thead(headers), el('tbody', {}, rows));
+}
+
+// Заголовок столбца — строка или { title, num } для числовых столбцов
+function thead(headers) {
+  return el('thead', {}, el('tr', {}, headers.map((h) => el('th', { class: h.num ? 'num' : null }, h.title || h))));
+}
+
+function playerLink(player) {
+  return el('a', { href: '#/player/' + encodeURIComponent(player.id) }, player.username);
+}
+
+function statusDot(online) {
+  return el('span', { class: online ? 'dot on' : 'dot', title: online ? 'Онлайн' : 'Офлайн' });
+}
+
+function stat(value, label) {
+  return el('div', { class: 'stat' }, el('b', {}, value), el('span', {}, label));
+}
+
+// --- Форматирование ---
+
+const dateTime = new Intl.DateTimeFormat('ru-RU', { dateStyle: 'short', timeStyle: 'short' });
+const dateOnly = new Intl.DateTimeFormat('ru-RU', { day: '2-digit', month: '2-digit' });
+
+function formatTime(iso) {
+  return iso ? dateTime.format(new Date(iso)) : '—';
+}
+
+function formatDuration(seconds) {
+  const hours = Math.floor(seconds / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  if (hours === 0) return minutes + ' мин';
+  return hours + ' ч ' + minutes + ' мин';
+}
+
+function daysAgo(days) {
+  return days ? new Date(Date.now() - days * 86400e3).toISOString().replace(/\.\d+Z$/, 'Z') : '';
+}
+
+// --- Маршруты ---
+
+const routes = [
+  [/^$/, 'online', renderOnline],
+  [/^players$/, 'players', renderPlayers],
+  [/^player\/(.+)$/, 'players', renderPlayer],
+  [/^leaderboards$/, 'leaderboards', renderLeaderboards],
+  [/^history$/, 'history', renderHistory],
+];
+
+function currentRoute() {
+  const raw = location.hash.replace(/^#\/?/, '');
+  const [path, query] = raw.split('?');
+  return { path: decodeURIComponent(path || ''), params: new URLSearchParams(query || '') };
+}
+
+function navigate(path, params) {
+  const query = new URLSearchParams(Object.entries(params).filter(([, v]) => v !== '' && v !== undefined)).toString();
+  location.hash = '#/' + path + (query ? '?' + query : '');
+}
+
+async function route() {
+  const seq = ++renderSeq;
+  if (live) {
+    live.close();
+    live = null;
+  }
+  const { path, params } = currentRoute();
+  const match = routes.find(([re]) => re.test(path));
+  const [re, nav, render] = match || routes[0];
+
+  for (const link of document.querySelectorAll('[data-nav]')) {
+    link.classList.toggle('active', link.dataset.nav === nav);
+  }
+
+  // show заменяет содержимое, только если пользователь не ушёл на другую страницу
+  const show = (...nodes) => {
+    if (seq === renderSeq) view.replaceChildren(...nodes);
+  };
+  try {
+    await render(show, params, ...(path.match(re) || []).slice(1));
+  } catch (err) {
+    if (err instanceof Unauthorized) showLogin(show);
+    else show(card('Ошибка', el('p', { class: 'error' }, err.message)));
+  }
+}
+
+function showLogin(show) {
+  const form = document.getElementById('login-template').content.cloneNode(true);
+  show(form);
+  document.getElementById('login-form').addEventListener('submit', (e) => {
+    e.preventDefault();
+    localStorage.setItem(TOKEN_KEY, e.target.token.value.trim());
+    route();
+  });
+}
+
+// --- Страницы ---
+
+async function renderOnline(show) {
+  const [online, servers] = await Promise.all([api('/online'), api('/servers')]);
+
+  const list = card(null);
+  const draw = (items) => {
+    list.replaceChildren(
+      el('h2', {}, 'Сейчас онлайн: ' + items.length),
+      table(['Игрок', 'Сервер', 'В игре с'], items.map((p) => el('tr', {},
+        el('td', {}, statusDot(true), playerLink({ id: p.player_id, username: p.username })),
+        el('td', {}, p.server),
+        el('td', {}, formatTime(p.since)))), 'Никого нет на сервере'));
+  };
+  draw(online.items);
+
+  show(list, card('Как подключиться', table(['Сервер', 'Адрес', 'Версия', 'Карта'],
+    servers.items.map((s) => el('tr', {},
+      el('td', {}, s.name),
+      el('td', {}, el('code', {}, s.address)),
+      el('td', {}, s.version),
+      el('td', {}, s.map_url ? el('a', { href: s.map_url, target: '_blank', rel: 'noopener' }, 'открыть') : '—'))))));
+
+  // Входы и выходы приходят из потока событий; список перечитывается целиком
+  live = openStream(['player_login', 'player_logout'], async () => {
+    try {
+      draw((await api('/online')).items);
+    } catch (err) {
+      // Следующее событие попробует ещё раз
+    }
+  });
+}
+
+async function renderPlayers(show, params) {
+  const q = params.get('q') || '';
+  const search = el('input', { type: 'search', placeholder: 'Поиск по нику', value: q, autofocus: true });
+  let timer;
+  search.addEventListener('input', () => {
+    clearTimeout(timer);
+    timer = setTimeout(() => navigate('players', { q: search.value.trim() }), 300);
+  });
+
+  const body = el('tbody');
+  const more = el('button', { class: 'secondary', hidden: true }, 'Показать ещё');
+  let cursor = '';
+  const load = async () => {
+    const result = await api('/players', { q, limit: PAGE_SIZE, cursor });
+    for (const p of result.items) {
+      body.append(el('tr', {},
+        el('td', {}, statusDot(p.online), playerLink(p)),
+        el('td', {}, formatTime(p.first_seen)),
+        el('td', {}, formatTime(p.last_seen))));
+    }
+    cursor = result.next_cursor || '';
+    more.hidden = !cursor;
+    return result.items.length;
+  };
+  more.addEventListener('click', () => load().catch((err) => more.replaceWith(el('p', { class: 'error' }, err.message))));
+
+  const found = await load();
+  show(card('Игроки',
+    el('div', { class: 'toolbar' }, search),
+    found === 0
+      ? el('p', { class: 'muted' }, q ? 'Никого не нашлось' : 'Игроков пока нет')
+      : el('table', {}, thead(['Игрок', 'Первый вход', 'Последний раз']), body),
+    more));
+  search.focus();
+  search.setSelectionRange(q.length, q.length);
+}
+
+async function renderPlayer(show, params, ref) {
+  const stats = await api('/players/' + encodeURIComponent(ref));
+  const id = encodeURIComponent(stats.id);
+
+  const sessions = pagedTable('/players/' + id + '/sessions', ['Сервер', 'Вход', 'Выход', { title: 'Длительность', num: true }],
+    (s) => el('tr', {},
+      el('td', {}, s.server),
+      el('td', {}, formatTime(s.join_time)),
+      el('td', {}, s.leave_time ? formatTime(s.leave_time) : 'в игре'),
+      el('td', { class: 'num' }, formatDuration(s.duration_seconds))),
+    'Сессий нет');
+  const commands = pagedTable('/players/' + id + '/commands', ['Команда', 'Время'],
+    (c) => el('tr', {}, el('td', {}, el('code', {}, c.command)), el('td', {}, formatTime(c.timestamp))),
+    'Нет команд');
+
+  const advancements = [...stats.advancements].sort((a, b) => b.timestamp.localeCompare(a.timestamp));
+
+  await Promise.all([sessions.load(), commands.load()]);
+  show(
+    card(null,
+      el('h1', {}, statusDot(stats.online), stats.username),
+      el('div', { class: 'stats' },
+        stat(stats.online ? 'Онлайн' : 'Офлайн', 'Статус'),
+        stat(formatDuration(stats.play_time_seconds), 'Время на сервере'),
+        stat(stats.sessions, 'Сессий'),
+        stat(stats.commands, 'Команд'),
+        stat(advancements.length, 'Достижений')),
+      el('p', { class: 'muted' }, 'Первый вход: ' + formatTime(stats.first_seen) + ' · последний раз: ' + formatTime(stats.last_seen))),
+    card('Сессии', sessions.node),
+    card('Достижения', table(['Достижение', 'Получено'],
+      advancements.map((a) => el('tr', {}, el('td', {}, a.name), el('td', {}, formatTime(a.timestamp)))), 'Нет достижений')),
+    card('Команды', commands.node));
+}
+
+// pagedTable — таблица списка API с кнопкой «Показать ещё»
+function pagedTable(path, headers, row, empty) {
+  const body = el('tbody');
+  const list = el('table', {}, thead(headers), body);
+  const more = el('button', { class: 'secondary', hidden: true }, 'Показать ещё');
+  const node = el('div', {});
+  let cursor = '';
+
+  const load = async () => {
+    const result = await api(path, { limit: PAGE_SIZE, cursor });
+    body.append(...result.items.map(row));
+    cursor = result.next_cursor || '';
+    more.hidden = !cursor;
+    node.replaceChildren(body.children.length === 0 ? el('p', { class: 'muted' }, empty) : list, more);
+  };
+  more.addEventListener('click', () => load().catch((err) => more.replaceWith(el('p', { class: 'error' }, err.message))));
+  return { node, load };
+}
+
+const metrics = [
+  ['playtime', 'Время в игре', (v) => formatDuration(v)],
+  ['sessions', 'Сессии', (v) => v],
+  ['commands', 'Команды', (v) => v],
+  ['advancements', 'Достижения', (v) => v],
+  ['longest', 'Самая долгая сессия', (v) => formatDuration(v)],
+];
+
+const periods = [['7', 'Неделя'], ['30', 'Месяц'], ['', 'Всё время']];
+
+async function renderLeaderboards(show, params) {
+  const metric = params.get('metric') || 'playtime';
+  const days = params.has('days') ? params.get('days') : '30';
+  const server = params.get('server') || '';
+  const [, title, format] = metrics.find(([m]) => m === metric) || metrics[0];
+
+  const [board, servers] = await Promise.all([
+    api('/leaderboards/' + encodeURIComponent(metric), { from: daysAgo(Number(days)), server, limit: 25 }),
+    api('/servers'),
+  ]);
+  const go = (change) => navigate('leaderboards', { metric, days, server, ...change });
+
+  show(card('Рейтинг: ' + title,
+    el('div', { class: 'toolbar' },
+      el('div', { class: 'tabs' }, metrics.map(([m, label]) =>
+        el('button', { class: m === metric ? 'active' : null, onclick: () => go({ metric: m }) }, label))),
+      select(periods, days, (value) => go({ days: value })),
+      serverSelect(servers.items, server, (value) => go({ server: value }), metric === 'advancements')),
+    table(['#', 'Игрок', { title: title, num: true }], board.items.map((entry) => el('tr', {},
+      el('td', {}, entry.rank),
+      el('td', {}, statusDot(entry.player.online), playerLink(entry.player)),
+      el('td', { class: 'num' }, format(entry.value)))), 'За этот период никого нет')));
+}
+
+async function renderHistory(show, params) {
+  const days = params.get('days') || '30';
+  const server = params.get('server') || '';
+  const [history, servers] = await Promise.all([
+    api('/history', { from: daysAgo(Number(days)), server }),
+    api('/servers'),
+  ]);
+  const go = (change) => navigate('history', { days, server, ...change });
+  const rows = [...history.days].reverse();
+
+  show(
+    card('История сервера',
+      el('div', { class: 'toolbar' },
+        select([['7', 'Неделя'], ['30', 'Месяц'], ['90', '3 месяца'], ['365', 'Год']], days, (value) => go({ days: value })),
+        serverSelect(servers.items, server, (value) => go({ server: value }))),
+      el('p', { class: 'muted' }, 'Игроков в день; дни по часовому поясу ' + history.timezone),
+      barChart(history.days.map((d) => [d.date, d.players]))),
+    card(null, table(
+      ['Дата', { title: 'Игроков', num: true }, { title: 'Пик онлайна', num: true }, { title: 'Сессий', num: true }, { title: 'Время в игре', num: true }],
+      rows.map((d) => el('tr', {},
+        el('td', {}, d.date),
+        el('td', { class: 'num' }, d.players),
+        el('td', { class: 'num' }, d.peak_online),
+        el('td', { class: 'num' }, d.sessions),
+        el('td', { class: 'num' }, formatDuration(d.play_time_seconds)))))));
+}
+
+function select(options, current, onChange) {
+  return el('select', { onchange: (e) => onChange(e.target.value) },
+    options.map(([value, label]) => el('option', { value, selected: value === current }, label)));
+}
+
+function serverSelect(servers, current, onChange, disabled = false) {
+  if (servers.length < 2) return null;
+  const node = select([['', 'Все серверы'], ...servers.map((s) => [s.name, s.name])], current, onChange);
+  node.disabled = disabled;
+  return node;
+}
+
+// barChart рисует столбцы [подпись, значение] в SVG без внешних библиотек
+function barChart(points) {
+  const width = 600;
+  const height = 160;
+  const bottom = 16;
+  const peak = Math.max(1, ...points.map(([, v]) => v));
+  const step = width / Math.max(points.length, 1);
+  const labelEvery = Math.ceil(points.length / 10);
+
+  return svg('svg', { class: 'chart', viewBox: `0 0 ${width} ${height}`, preserveAspectRatio: 'none', role: 'img' },
+    points.map(([label, value], i) => {
+      const h = ((height - bottom - 12) * value) / peak;
+      const x = i * step;
+      return [
+        svg('rect', { x: x + step * 0.1, y: height - bottom - h, width: step * 0.8, height: h },
+          svg('title', {}, `${label}: ${value}`)),
+        i % labelEvery === 0
+          ? svg('text', { x: x + step / 2, y: height - 4, 'text-anchor': 'middle' }, dateOnly.format(new Date(label + 'T12:00:00')))
+          : null,
+      ].filter(Boolean);
+    }),
+    svg('text', { x: 2, y: 10 }, 'макс. ' + peak));
+}
+
+window.addEventListener('hashchange', route);
+route();
@@ -0,0 +1,197 @@
+// Package webhook отправляет события во внешние сервисы: POST-запрос с JSON,
+// подписанный HMAC-SHA256 секретом получателя
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mine-parser/internal/events"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса. Подпись — "sha256=<hex>" от строки "<X-Mclog-Timestamp>.<тело>":
+// метка времени входит в подпись, поэтому перехваченный запрос нельзя повторить позже.
+const (
+	HeaderWebhook   = "X-Mclog-Webhook"
+	HeaderEvent     = "X-Mclog-Event"
+	HeaderDelivery  = "X-Mclog-Delivery" // номер доставки, одинаковый во всех повторах
+	HeaderTimestamp = "X-Mclog-Timestamp"
+	HeaderSignature = "X-Mclog-Signature"
+)
+
+const (
+	signaturePrefix = "sha256="
+	userAgent       = "mclog-webhook/1"
+	maxErrorBody    = 512 // байт тела ответа в тексте ошибки
+)
+
+// Payload — тело запроса:
+//
+//	{
+//	  "type": "player_login",             // тип события, как в заголовке X-Mclog-Event
+//	  "occurred_at": "2026-04-10T10:00:00Z",
+//	  "data": {
+//	    "server": "main",
+//	    "player_id": "<UUID>",            // нет у server_started и server_stopping
+//	    "username": "Steve",              // нет у server_started и server_stopping
+//	    "session_id": 42,                 // player_login
+//	    "command": "/home",               // command_issued
+//	    "advancement": "Stone Age",       // advancement_earned
+//	    "message": "...",                 // chat_message, player_died
+//	    "timestamp": "2026-04-10T10:00:00Z"
+//	  }
+//	}
+//
+// data — публичное представление события (events.Public), как в потоке API:
+// IP-адреса и служебные поля получателям не отправляются.
+type Payload struct {
+	Type       events.Type   `json:"type"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Data       events.Public `json:"data"`
+}
+
+// Marshal готовит тело запроса для события
+func Marshal(event events.Event) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	payload := Payload{Type: event.EventType(), OccurredAt: event.OccurredAt(), Data: events.PublicOf(event)}
+	if err := encoder.Encode(payload); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Sign возвращает значение заголовка X-Mclog-Signature
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись входящего запроса так, как это должен делать получатель:
+// подпись совпадает, а метка времени отличается от now не больше чем на tolerance
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("некорректный заголовок %s", HeaderTimestamp)
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)).Abs(); skew > tolerance {
+		return fmt.Errorf("метка времени отличается на %s", skew.Truncate(time.Second))
+	}
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(HeaderSignature))) {
+		return errors.New("подпись не совпадает")
+	}
+	return nil
+}
+
+// Request — одна попытка доставки
+type Request struct {
+	Webhook    string
+	URL        string
+	Secret     string
+	DeliveryID uint
+	Event      string
+	Body       []byte
+}
+
+// Result — итог попытки доставки
+type Result struct {
+	StatusCode int // 0 — ответа не было
+	Duration   time.Duration
+	RetryAfter time.Duration // задержка из заголовка Retry-After ответа 429 или 503
+	Err        error         // nil при ответе 2xx
+}
+
+// Retryable сообщает, есть ли смысл повторять: сеть, таймаут, 408, 429 и 5xx.
+// Остальные ответы 3xx и 4xx означают, что получатель не примет запрос и при повторе.
+func (r Result) Retryable() bool {
+	switch {
+	case r.Err == nil:
+		return false
+	case r.StatusCode == 0, r.StatusCode >= 500:
+		return true
+	default:
+		return r.StatusCode == http.StatusRequestTimeout || r.StatusCode == http.StatusTooManyRequests
+	}
+}
+
+// Client отправляет запросы вебхуков
+type Client struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+// NewClient создаёт клиента; время ожидания ответа задаёт контекст Send.
+// Перенаправления не выполняются: POST после 301/302 превратился бы в GET,
+// поэтому ответ 3xx считается ошибкой.
+func NewClient() *Client {
+	return &Client{
+		http: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send выполняет одну попытку доставки
+func (c *Client) Send(ctx context.Context, request Request) Result {
+	started := c.now()
+	result := c.send(ctx, request, started)
+	result.Duration = c.now().Sub(started)
+	return result
+}
+
+func (c *Client) send(ctx context.Context, request Request, now time.Time) Result {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	timestamp := now.Unix()
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("User-Agent", userAgent)
+	httpRequest.Header.Set(HeaderWebhook, request.Webhook)
+	httpRequest.Header.Set(HeaderEvent, request.Event)
+	httpRequest.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(request.DeliveryID), 10))
+	httpRequest.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpRequest.Header.Set(HeaderSignature, Sign(request.Secret, timestamp, request.Body))
+
+	response, err := c.http.Do(httpRequest)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer response.Body.Close()
+
+	result := Result{StatusCode: response.StatusCode}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		// Тело дочитывается, чтобы соединение вернулось в пул
+		_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+		return result
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+	message := fmt.Sprintf("HTTP %d", response.StatusCode)
+	if text := strings.TrimSpace(string(snippet)); text != "" {
+		message += ": " + text
+	}
+	result.Err = errors.New(message)
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		result.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return result
+}